@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/curator4/mcp-server-diary/query"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+var (
+	wikiEmbedPattern     = regexp.MustCompile(`(!?)\[\[([^\]|#^]+)(?:[#^|][^\]]*)?\]\]`)
+	markdownImagePattern = regexp.MustCompile(`(!?)\[[^\]]*\]\(\s*(<[^>]+>|[^)\s]+)(?:\s+"[^"]*")?\s*\)`)
+)
+
+var mediaExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".bmp": true, ".svg": true, ".webp": true, ".avif": true, ".heic": true,
+	".mp3": true, ".wav": true, ".m4a": true, ".ogg": true, ".flac": true, ".webm": true,
+	".mp4": true, ".mov": true, ".mkv": true, ".ogv": true,
+	".pdf": true,
+}
+
+// THEMIS_ATTACHMENT_DIRS lists the vault relative folders attachments live in, e.g. "attachments,media".
+// unset means media files anywhere in the vault.
+var attachmentDirs = getAttachmentDirs()
+
+func getAttachmentDirs() []string {
+	raw := strings.TrimSpace(os.Getenv("THEMIS_ATTACHMENT_DIRS"))
+	if raw == "" {
+		return nil
+	}
+
+	var dirs []string
+	for _, name := range strings.Split(raw, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		dir, err := resolveInVault(filepath.FromSlash(name))
+		if err != nil {
+			log.Fatalf("invalid THEMIS_ATTACHMENT_DIRS: %v", err)
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+type GetEntryAttachmentsInput struct {
+	Date string `json:"date" jsonschema:"Entry date in YYYY-MM-DD format"`
+}
+
+type Attachment struct {
+	Ref    string `json:"ref" jsonschema:"Reference as written in the entry"`
+	Syntax string `json:"syntax" jsonschema:"Either wikilink for obsidian embeds or markdown for standard images and links"`
+	Path   string `json:"path" jsonschema:"Resolved path relative to the vault"`
+	Exists bool   `json:"exists" jsonschema:"Whether the resolved file exists on disk"`
+}
+
+type AttachmentsOutput struct {
+	Date        string       `json:"date" jsonschema:"Entry date in YYYY-MM-DD format"`
+	Attachments []Attachment `json:"attachments" jsonschema:"Media referenced by the entry in document order"`
+	Missing     int          `json:"missing" jsonschema:"Number of references pointing to files that do not exist"`
+}
+
+type AuditAttachmentsInput struct{}
+
+type BrokenReference struct {
+	Date string `json:"date" jsonschema:"Date of the entry holding the reference in YYYY-MM-DD format"`
+	Ref  string `json:"ref" jsonschema:"Reference as written in the entry"`
+	Path string `json:"path" jsonschema:"Vault relative path the reference was expected at"`
+}
+
+type AttachmentAuditOutput struct {
+	OrphanedAttachments []string          `json:"orphanedAttachments" jsonschema:"Vault relative paths of attachment files no entry references, sorted"`
+	BrokenReferences    []BrokenReference `json:"brokenReferences" jsonschema:"References to attachments that do not exist, oldest entry first"`
+	AttachmentDirs      []string          `json:"attachmentDirs,omitempty" jsonschema:"Folders scanned for attachments, absent when the whole vault was scanned for media files"`
+	Unscanned           []string          `json:"unscanned,omitempty" jsonschema:"Dates of encrypted entries that could not be decrypted, attachments only they reference show up as orphaned"`
+}
+
+// handlers
+func handleGetEntryAttachments(ctx context.Context, req *mcp.CallToolRequest, input GetEntryAttachmentsInput) (
+	*mcp.CallToolResult,
+	AttachmentsOutput,
+	error,
+) {
+	entry, err := getEntryByDate(input.Date)
+	if err != nil {
+		return nil, AttachmentsOutput{}, err
+	}
+
+	resolver := newAttachmentResolver()
+	attachments := resolver.resolveAll(entry)
+
+	output := AttachmentsOutput{Date: entry.Date, Attachments: attachments}
+	for _, attachment := range attachments {
+		if !attachment.Exists {
+			output.Missing++
+		}
+	}
+
+	return nil, output, nil
+}
+
+func handleAuditAttachments(ctx context.Context, req *mcp.CallToolRequest, input AuditAttachmentsInput) (
+	*mcp.CallToolResult,
+	AttachmentAuditOutput,
+	error,
+) {
+	output := AttachmentAuditOutput{OrphanedAttachments: []string{}, BrokenReferences: []BrokenReference{}}
+
+	// one resolver for the whole walk so bare embed names index the vault once
+	resolver := newAttachmentResolver()
+	referenced := map[string]bool{}
+	err := eachEntry(ctx, query.Filter[Entry]{}, func(entry Entry) error {
+		if entry.Locked {
+			output.Unscanned = append(output.Unscanned, entry.Date)
+			return nil
+		}
+		for _, attachment := range resolver.resolveAll(entry) {
+			if attachment.Exists {
+				referenced[attachment.Path] = true
+			} else {
+				output.BrokenReferences = append(output.BrokenReferences, BrokenReference{Date: entry.Date, Ref: attachment.Ref, Path: attachment.Path})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, AttachmentAuditOutput{}, fmt.Errorf("failed to get entries: %w", err)
+	}
+	sort.SliceStable(output.BrokenReferences, func(i, j int) bool {
+		return output.BrokenReferences[i].Date < output.BrokenReferences[j].Date
+	})
+	sort.Strings(output.Unscanned)
+
+	for _, file := range attachmentFiles() {
+		if !referenced[file] {
+			output.OrphanedAttachments = append(output.OrphanedAttachments, file)
+		}
+	}
+	for _, dir := range attachmentDirs {
+		output.AttachmentDirs = append(output.AttachmentDirs, vaultRelative(dir))
+	}
+
+	return nil, output, nil
+}
+
+// helpers
+
+// attachmentFiles lists vault relative attachment paths, sorted. configured folders count every file in them,
+// otherwise it is every media file in the vault outside hidden folders and exports.
+func attachmentFiles() []string {
+	roots, mediaOnly := attachmentDirs, false
+	if len(roots) == 0 {
+		roots, mediaOnly = []string{themisPath}, true
+	}
+
+	seen := map[string]bool{}
+	var files []string
+	for _, root := range roots {
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				if path != root && strings.HasPrefix(d.Name(), ".") || isExportsDir(path) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if strings.HasPrefix(d.Name(), ".") || mediaOnly && !mediaExtensions[strings.ToLower(filepath.Ext(d.Name()))] {
+				return nil
+			}
+			if rel := vaultRelative(path); !seen[rel] {
+				seen[rel] = true
+				files = append(files, rel)
+			}
+			return nil
+		})
+	}
+	sort.Strings(files)
+	return files
+}
+
+// attachmentResolver resolves media references the way obsidian does, indexing vault files by name on first use
+type attachmentResolver struct {
+	byName map[string]string
+}
+
+func newAttachmentResolver() *attachmentResolver {
+	return &attachmentResolver{}
+}
+
+func (r *attachmentResolver) resolveAll(entry Entry) []Attachment {
+	attachments := []Attachment{}
+	for _, ref := range parseMediaRefs(entry.Content) {
+		resolved, exists := r.resolve(entry.FilePath, ref.Ref, ref.Syntax)
+		ref.Path, ref.Exists = resolved, exists
+		attachments = append(attachments, ref)
+	}
+	return attachments
+}
+
+// parseMediaRefs extracts embedded and linked media, skipping fenced code, urls and links to notes
+func parseMediaRefs(content string) []Attachment {
+	var refs []Attachment
+	inFence := false
+	for _, line := range strings.Split(content, "\n") {
+		if isFenceLine(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		for _, match := range wikiEmbedPattern.FindAllStringSubmatch(line, -1) {
+			ref := strings.TrimSpace(match[2])
+			if isMediaRef(ref, match[1] == "!") {
+				refs = append(refs, Attachment{Ref: ref, Syntax: "wikilink"})
+			}
+		}
+
+		for _, match := range markdownImagePattern.FindAllStringSubmatch(line, -1) {
+			ref := strings.TrimSuffix(strings.TrimPrefix(match[2], "<"), ">")
+			if strings.Contains(ref, "://") || strings.HasPrefix(ref, "data:") || strings.HasPrefix(ref, "mailto:") || strings.HasPrefix(ref, "#") {
+				continue
+			}
+			if unescaped, err := url.PathUnescape(ref); err == nil {
+				ref = unescaped
+			}
+			if isMediaRef(ref, match[1] == "!") {
+				refs = append(refs, Attachment{Ref: ref, Syntax: "markdown"})
+			}
+		}
+	}
+
+	return refs
+}
+
+// isMediaRef reports whether a reference points at a non-note file; plain links only count when they look like media
+func isMediaRef(ref string, embedded bool) bool {
+	ext := strings.ToLower(path.Ext(ref))
+	if ext == "" || ext == ".md" {
+		return false
+	}
+	return embedded || mediaExtensions[ext]
+}
+
+// resolve returns the vault relative path for a reference and whether that file exists
+func (r *attachmentResolver) resolve(entryPath, ref, syntax string) (string, bool) {
+	ref = filepath.FromSlash(ref)
+	var candidates []string
+	if strings.HasPrefix(ref, string(filepath.Separator)) {
+		candidates = append(candidates, filepath.Join(themisPath, ref))
+	} else {
+		candidates = append(candidates, filepath.Join(filepath.Dir(entryPath), ref), filepath.Join(themisPath, ref))
+	}
+
+	for _, candidate := range candidates {
+		// references escaping the vault are reported as missing rather than probed
+		if _, err := resolveInVault(candidate); err == nil && fileExists(candidate) {
+			return vaultRelative(candidate), true
+		}
+	}
+
+	// obsidian embeds resolve bare file names anywhere in the vault
+	if syntax == "wikilink" && !strings.ContainsRune(ref, filepath.Separator) {
+		if found, ok := r.lookupName(ref); ok {
+			return vaultRelative(found), true
+		}
+	}
+
+	return vaultRelative(candidates[0]), false
+}
+
+func (r *attachmentResolver) lookupName(name string) (string, bool) {
+	if r.byName == nil {
+		r.byName = map[string]string{}
+		filepath.WalkDir(themisPath, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				if path != themisPath && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if _, seen := r.byName[d.Name()]; !seen {
+				r.byName[d.Name()] = path
+			}
+			return nil
+		})
+	}
+
+	found, ok := r.byName[name]
+	return found, ok
+}
+
+func vaultRelative(path string) string {
+	rel, err := filepath.Rel(themisPath, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
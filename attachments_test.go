@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGetEntryAttachments(t *testing.T) {
+	newTestVault(t, map[string]string{
+		"2024-03-15.md":         "# Friday\n![[beach.jpg]] and ![sunset](media/sunset%20pier.png)\n[notes](notes.md)\n```\n![[skipped.png]]\n```\n![[lost.mp3]]\n",
+		"2024-03-16.md":         "# Saturday\nnothing attached, see [[Friday]]\n",
+		"photos/beach.jpg":      "jpg",
+		"media/sunset pier.png": "png",
+	})
+	ctx := context.Background()
+
+	_, output, err := handleGetEntryAttachments(ctx, nil, GetEntryAttachmentsInput{Date: "2024-03-15"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Attachment{
+		{Ref: "beach.jpg", Syntax: "wikilink", Path: "photos/beach.jpg", Exists: true},
+		{Ref: "media/sunset pier.png", Syntax: "markdown", Path: "media/sunset pier.png", Exists: true},
+		{Ref: "lost.mp3", Syntax: "wikilink", Path: "lost.mp3", Exists: false},
+	}
+	if !reflect.DeepEqual(output.Attachments, want) || output.Missing != 1 {
+		t.Errorf("attachments = %+v with %d missing, want %+v with 1 missing", output.Attachments, output.Missing, want)
+	}
+
+	// an entry without attachments returns an empty list, not null
+	_, output, err = handleGetEntryAttachments(ctx, nil, GetEntryAttachmentsInput{Date: "2024-03-16"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"attachments":[]`) {
+		t.Errorf("output = %s, want an empty attachments list", data)
+	}
+}
+
+func TestParseMediaRefs(t *testing.T) {
+	content := strings.Join([]string{
+		"![[scan.pdf#page=2]] ![[Note]] [[trip.mov|the video]]",
+		"![remote](https://example.com/a.png) ![inline](data:image/png;base64,AA) [mail](mailto:a@b.c) [top](#heading)",
+		"![spaced](<media/my photo.jpg> \"caption\") [report](docs/report.pdf) [draft](draft.md) [page](about.html)",
+		"~~~",
+		"![[fenced.png]]",
+		"~~~",
+	}, "\n")
+	want := []Attachment{
+		{Ref: "scan.pdf", Syntax: "wikilink"},
+		{Ref: "trip.mov", Syntax: "wikilink"},
+		{Ref: "media/my photo.jpg", Syntax: "markdown"},
+		{Ref: "docs/report.pdf", Syntax: "markdown"},
+	}
+	if got := parseMediaRefs(content); !reflect.DeepEqual(got, want) {
+		t.Errorf("refs = %+v, want %+v", got, want)
+	}
+}
+
+func TestAttachmentsResolveNextToEntry(t *testing.T) {
+	newTestVault(t, map[string]string{
+		"2024/03/2024-03-15.md": "![](img/pier.png) ![](/img/pier.png) ![](../../../outside.png)\n",
+		"2024/03/img/pier.png":  "png",
+		"img/pier.png":          "png",
+	})
+
+	_, output, err := handleGetEntryAttachments(context.Background(), nil, GetEntryAttachmentsInput{Date: "2024-03-15"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, attachment := range output.Attachments {
+		paths = append(paths, attachment.Path)
+	}
+	// relative refs try the entry's folder first, a leading slash is the vault root, escapes are never probed
+	if want := []string{"2024/03/img/pier.png", "img/pier.png", "../outside.png"}; !reflect.DeepEqual(paths, want) || output.Missing != 1 {
+		t.Errorf("paths = %v with %d missing, want %v with 1 missing", paths, output.Missing, want)
+	}
+}
+
+func TestAuditAttachments(t *testing.T) {
+	dir := newTestVault(t, map[string]string{
+		"2024-03-14.md":          "# Thursday\n![[beach.jpg]] ![[gone.png]]\n",
+		"2024-03-15.md":          "# Friday\n![pier](media/pier.png) ![[lost.mp3]]\n",
+		"photos/beach.jpg":       "jpg",
+		"media/pier.png":         "png",
+		"media/unused.png":       "png",
+		"media/notes.txt":        "not media",
+		".obsidian/icon.png":     "hidden",
+		"exports/html/cover.png": "exported",
+	})
+	ctx := context.Background()
+
+	// without attachment folders every media file outside hidden folders and exports counts
+	_, output, err := handleAuditAttachments(ctx, nil, AuditAttachmentsInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(output.OrphanedAttachments, []string{"media/unused.png"}) {
+		t.Errorf("orphaned = %v", output.OrphanedAttachments)
+	}
+	wantBroken := []BrokenReference{
+		{Date: "2024-03-14", Ref: "gone.png", Path: "gone.png"},
+		{Date: "2024-03-15", Ref: "lost.mp3", Path: "lost.mp3"},
+	}
+	if !reflect.DeepEqual(output.BrokenReferences, wantBroken) || output.AttachmentDirs != nil {
+		t.Errorf("broken = %+v in %v, want %+v", output.BrokenReferences, output.AttachmentDirs, wantBroken)
+	}
+
+	// a configured folder counts every file in it, whatever its type
+	old := attachmentDirs
+	attachmentDirs = []string{filepath.Join(dir, "media")}
+	defer func() { attachmentDirs = old }()
+	_, output, err = handleAuditAttachments(ctx, nil, AuditAttachmentsInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(output.OrphanedAttachments, []string{"media/notes.txt", "media/unused.png"}) || !reflect.DeepEqual(output.AttachmentDirs, []string{"media"}) {
+		t.Errorf("with media configured: orphaned %v in %v", output.OrphanedAttachments, output.AttachmentDirs)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// entryBlocks renders entries as one text block each under a date heading, for clients that display content
+// blocks rather than structured output. the structured result is returned alongside unchanged. nil leaves the
+// sdk's default of the output as json.
+func entryBlocks(enabled bool, entries []Entry) *mcp.CallToolResult {
+	if !enabled {
+		return nil
+	}
+	if len(entries) == 0 {
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "No entries found."}}}
+	}
+
+	result := &mcp.CallToolResult{Content: make([]mcp.Content, 0, len(entries))}
+	for _, entry := range entries {
+		result.Content = append(result.Content, &mcp.TextContent{Text: formatEntryBlock(entry)})
+	}
+	return result
+}
+
+func formatEntryBlock(entry Entry) string {
+	// the date may have been left out by field selection, the block is then just the text
+	var b strings.Builder
+	if entry.Date != "" {
+		b.WriteString("## " + entry.Date)
+		if entry.RelativeLabel != "" {
+			b.WriteString(" (" + entry.RelativeLabel + ")")
+		}
+		b.WriteString("\n\n")
+	}
+
+	switch {
+	case entry.Locked:
+		b.WriteString("This entry is encrypted and could not be decrypted.")
+	case entry.Content != "" && entry.ContentEncoding == "":
+		b.WriteString(strings.TrimSpace(entry.Content))
+		if entry.Truncated {
+			b.WriteString("\n\n(truncated)")
+		}
+	case entry.Preview != "":
+		b.WriteString(entry.Preview)
+	default:
+		fmt.Fprintf(&b, "%d words", entry.WordCount)
+	}
+	return b.String()
+}
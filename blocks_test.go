@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func blockTexts(t *testing.T, result *mcp.CallToolResult) []string {
+	t.Helper()
+	if result == nil {
+		t.Fatal("no content blocks")
+	}
+	texts := make([]string, len(result.Content))
+	for i, content := range result.Content {
+		text, ok := content.(*mcp.TextContent)
+		if !ok {
+			t.Fatalf("block %d is %T, want text", i, content)
+		}
+		texts[i] = text.Text
+	}
+	return texts
+}
+
+func TestContentBlocks(t *testing.T) {
+	newTestVault(t, map[string]string{
+		"2024-03-14.md": "---\nmood: 4\n---\n# Thursday\nlong walk\n",
+		"2024-03-15.md": "# Friday\n\nquiet day\n\n",
+	})
+	setTestNow(t, "2024-03-15")
+	ctx := context.Background()
+
+	// one block per entry in the order of the structured result, which is returned unchanged
+	result, output, err := handleGetEntriesByYear(ctx, nil, GetEntriesByYearInput{Year: 2024, ContentBlocks: true, IncludeRelativeLabels: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"## 2024-03-15 (today)\n\n# Friday\n\nquiet day",
+		"## 2024-03-14 (yesterday)\n\n---\nmood: 4\n---\n# Thursday\nlong walk",
+	}
+	if got := blockTexts(t, result); !reflect.DeepEqual(got, want) {
+		t.Errorf("blocks = %q, want %q", got, want)
+	}
+	if output.Count != 2 || output.Entries[0].Content != "# Friday\n\nquiet day\n\n" {
+		t.Errorf("structured output changed: %+v", output)
+	}
+
+	tests := []struct {
+		name  string
+		input GetEntriesByYearInput
+		want  []string
+	}{
+		{"preview without content", GetEntriesByYearInput{Year: 2024, ContentBlocks: true, OmitContent: true, PreviewChars: 10}, []string{"## 2024-03-15\n\nFriday qui…", "## 2024-03-14\n\nThursday l…"}},
+		{"word count for base64", GetEntriesByYearInput{Year: 2024, ContentBlocks: true, ContentEncoding: "base64"}, []string{"## 2024-03-15\n\n3 words", "## 2024-03-14\n\n3 words"}},
+		{"no entries", GetEntriesByYearInput{Year: 2023, ContentBlocks: true}, []string{"No entries found."}},
+	}
+	for _, tt := range tests {
+		result, _, err := handleGetEntriesByYear(ctx, nil, tt.input)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := blockTexts(t, result); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: blocks = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if result, _, err := handleGetEntriesByYear(ctx, nil, GetEntriesByYearInput{Year: 2024}); err != nil || result != nil {
+		t.Errorf("without contentBlocks = %+v, %v, want the sdk default", result, err)
+	}
+}
+
+func TestContentBlocksOverTheProtocol(t *testing.T) {
+	newTestVault(t, map[string]string{"2024-03-15.md": "# Friday\n"})
+	session := connectTestServer(t, newServer(false, false))
+
+	result, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: "getEntriesByYear", Arguments: map[string]any{"year": 2024, "contentBlocks": true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := blockTexts(t, result); !reflect.DeepEqual(got, []string{"## 2024-03-15\n\n# Friday"}) {
+		t.Errorf("blocks = %q", got)
+	}
+	structured, ok := result.StructuredContent.(map[string]any)
+	if !ok || structured["count"] != float64(1) {
+		t.Errorf("structured content = %v, want the entries alongside the blocks", result.StructuredContent)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/curator4/mcp-server-diary/query"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	defaultBriefingDays = 7
+	briefingTagDays     = 7
+)
+
+type GetMorningBriefingInput struct {
+	Days int `json:"days,omitempty" jsonschema:"Days of recent entries and open todos to include (default 7)"`
+}
+
+type OpenTodo struct {
+	Date string `json:"date" jsonschema:"Date of the entry holding the todo in YYYY-MM-DD format"`
+	Text string `json:"text" jsonschema:"Text of the - [ ] item"`
+}
+
+type MorningBriefingOutput struct {
+	Recent    EntriesOutput `json:"recent" jsonschema:"What getRecentEntries returns for the same number of days"`
+	OpenTodos []OpenTodo    `json:"openTodos" jsonschema:"Unchecked - [ ] items from those entries, newest entry first"`
+	TopTags   []TagCount    `json:"topTags" jsonschema:"Most used tags of the last 7 days, most frequent first"`
+}
+
+// handlers
+func handleGetMorningBriefing(ctx context.Context, req *mcp.CallToolRequest, input GetMorningBriefingInput) (
+	*mcp.CallToolResult,
+	MorningBriefingOutput,
+	error,
+) {
+	days := input.Days
+	if days < 0 {
+		return nil, MorningBriefingOutput{}, fmt.Errorf("days must not be negative, got %d", days)
+	}
+	if days == 0 {
+		days = defaultBriefingDays
+	}
+
+	_, recent, err := handleGetRecentEntries(ctx, req, GetRecentEntriesInput{Days: days})
+	if err != nil {
+		return nil, MorningBriefingOutput{}, err
+	}
+	output := MorningBriefingOutput{Recent: recent, OpenTodos: []OpenTodo{}}
+
+	// todos cover the same days as the recent entries, tags always the last week
+	todoCutoff := firstDayFrom(now().AddDate(0, 0, -days))
+	tagCutoff := firstDayFrom(now().AddDate(0, 0, -briefingTagDays))
+	var todoEntries []Entry
+	tagCounts := map[string]int{}
+	from := todoCutoff
+	if tagCutoff.Before(from) {
+		from = tagCutoff
+	}
+	err = eachEntry(ctx, query.Filter[Entry]{Start: from}, func(entry Entry) error {
+		date, _ := time.Parse("2006-01-02", entry.Date)
+		fm, body, _ := parseFrontmatter(entry.Content)
+		if !date.Before(todoCutoff) {
+			todoEntries = append(todoEntries, Entry{Date: entry.Date, FilePath: entry.FilePath, Content: body})
+		}
+		if !date.Before(tagCutoff) {
+			for _, tag := range extractTags(fm, body) {
+				tagCounts[tag]++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, MorningBriefingOutput{}, fmt.Errorf("failed to get entries: %w", err)
+	}
+
+	sortEntries(todoEntries, true)
+	for _, entry := range todoEntries {
+		for _, task := range entryTasks(entry.Content) {
+			if !task.done && task.text != "" {
+				output.OpenTodos = append(output.OpenTodos, OpenTodo{Date: entry.Date, Text: task.text})
+			}
+		}
+	}
+	output.TopTags = topTags(tagCounts, topTagsLimit)
+
+	return nil, output, nil
+}
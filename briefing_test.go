@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetMorningBriefing(t *testing.T) {
+	newTestVault(t, map[string]string{
+		"2024-03-05.md": "# Tuesday #old\n- [ ] ancient todo\n",
+		"2024-03-10.md": "# Sunday #gym #work\n- [ ] renew passport\n",
+		"2024-03-13.md": "---\ntags: [work]\n---\n# Wednesday\n- [ ] file taxes\n- [x] booked the train\n```\n- [ ] fenced\n```\n",
+		"2024-03-15.md": "# Friday #work\n- [ ] call mum\n- [ ]   \n* [ ] water plants\n",
+	})
+	oldLocation := location
+	location = time.UTC
+	defer func() { location = oldLocation }()
+	setTestNow(t, "2024-03-15T12:00:00Z")
+
+	// entries and todos cover the last 3 days, tags the whole week
+	_, output, err := handleGetMorningBriefing(context.Background(), nil, GetMorningBriefingInput{Days: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := entryDates(output.Recent.Entries); !reflect.DeepEqual(got, []string{"2024-03-15", "2024-03-13"}) || output.Recent.Cutoff != "2024-03-13" {
+		t.Errorf("recent = %v from %s", got, output.Recent.Cutoff)
+	}
+	wantTodos := []OpenTodo{
+		{Date: "2024-03-15", Text: "call mum"},
+		{Date: "2024-03-15", Text: "water plants"},
+		{Date: "2024-03-13", Text: "file taxes"},
+	}
+	if !reflect.DeepEqual(output.OpenTodos, wantTodos) {
+		t.Errorf("open todos = %+v, want %+v", output.OpenTodos, wantTodos)
+	}
+	if want := []TagCount{{Tag: "work", Count: 3}, {Tag: "gym", Count: 1}}; !reflect.DeepEqual(output.TopTags, want) {
+		t.Errorf("top tags = %+v, want %+v", output.TopTags, want)
+	}
+
+	// the default is a week of everything
+	_, output, err = handleGetMorningBriefing(context.Background(), nil, GetMorningBriefingInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Recent.Count != 3 || len(output.OpenTodos) != 4 || output.OpenTodos[3].Text != "renew passport" {
+		t.Errorf("default briefing = %d entries with todos %+v", output.Recent.Count, output.OpenTodos)
+	}
+
+	if _, _, err := handleGetMorningBriefing(context.Background(), nil, GetMorningBriefingInput{Days: -1}); err == nil {
+		t.Error("negative days accepted")
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"container/list"
+	"os"
+	"sync"
+	"time"
+)
+
+const defaultCacheBytes = 64 << 20
+
+// cache shared by every tool reading entry files, THEMIS_CACHE_BYTES=0 disables it
+var cache = newContentCache(int64(getEnvInt("THEMIS_CACHE_BYTES", defaultCacheBytes)))
+
+// contentCache is an lru of file contents bounded by total bytes. an item is only served while the file's
+// mtime and size still match what was read, so external edits are picked up on the next read.
+type contentCache struct {
+	mu        sync.Mutex
+	budget    int64
+	used      int64
+	order     *list.List // front is most recently used
+	items     map[string]*list.Element
+	hits      int
+	misses    int
+	evictions int
+}
+
+type cacheItem struct {
+	path    string
+	modTime time.Time
+	size    int64
+	data    []byte
+}
+
+type CacheState struct {
+	Hits        int   `json:"hits" jsonschema:"Reads served from the cache"`
+	Misses      int   `json:"misses" jsonschema:"Reads that went to disk"`
+	Evictions   int   `json:"evictions" jsonschema:"Items dropped to stay within the byte budget"`
+	Entries     int   `json:"entries" jsonschema:"Files currently cached"`
+	Bytes       int64 `json:"bytes" jsonschema:"Bytes currently cached"`
+	BudgetBytes int64 `json:"budgetBytes" jsonschema:"Configured byte budget, 0 when disabled"`
+}
+
+func newContentCache(budget int64) *contentCache {
+	return &contentCache{budget: budget, order: list.New(), items: map[string]*list.Element{}}
+}
+
+// read returns the file's contents, from memory when the cached copy is still current
+func (c *contentCache) read(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if el, ok := c.items[path]; ok {
+		item := el.Value.(*cacheItem)
+		if item.modTime.Equal(info.ModTime()) && item.size == info.Size() {
+			c.order.MoveToFront(el)
+			c.hits++
+			c.mu.Unlock()
+			return item.data, nil
+		}
+		c.remove(el)
+	}
+	c.misses++
+	c.mu.Unlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// only cache when the file didn't change while it was being read
+	if after, err := os.Stat(path); err == nil && after.ModTime().Equal(info.ModTime()) && after.Size() == int64(len(data)) {
+		c.store(&cacheItem{path: path, modTime: info.ModTime(), size: info.Size(), data: data})
+	}
+	return data, nil
+}
+
+func (c *contentCache) store(item *cacheItem) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if item.size > c.budget {
+		return
+	}
+	if el, ok := c.items[item.path]; ok {
+		c.remove(el)
+	}
+
+	c.items[item.path] = c.order.PushFront(item)
+	c.used += item.size
+	for c.used > c.budget {
+		c.remove(c.order.Back())
+		c.evictions++
+	}
+}
+
+// invalidate drops path from the cache, write tools call it before returning
+func (c *contentCache) invalidate(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[path]; ok {
+		c.remove(el)
+	}
+}
+
+// remove must be called with mu held
+func (c *contentCache) remove(el *list.Element) {
+	item := c.order.Remove(el).(*cacheItem)
+	delete(c.items, item.path)
+	c.used -= item.size
+}
+
+func (c *contentCache) state() CacheState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return CacheState{
+		Hits:        c.hits,
+		Misses:      c.misses,
+		Evictions:   c.evictions,
+		Entries:     len(c.items),
+		Bytes:       c.used,
+		BudgetBytes: c.budget,
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestContentCacheStaleness(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "2024-03-15.md")
+	writeTestFile(t, path, "first\n")
+	c := newContentCache(1 << 20)
+
+	read := func(want string) {
+		t.Helper()
+		data, err := c.read(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("read %q, want %q", data, want)
+		}
+	}
+
+	read("first\n")
+	read("first\n")
+	if state := c.state(); state.Misses != 1 || state.Hits != 1 {
+		t.Errorf("after two reads: %+v, want a miss then a hit", state)
+	}
+
+	// an edit of the same size is caught by the mtime
+	writeTestFile(t, path, "fresh\n")
+	later := time.Now().Add(time.Minute)
+	touch(t, path, later)
+	read("fresh\n")
+
+	// and one keeping the mtime by the size
+	writeTestFile(t, path, "fresher\n")
+	touch(t, path, later)
+	read("fresher\n")
+	if state := c.state(); state.Misses != 3 || state.Entries != 1 {
+		t.Errorf("after edits: %+v, want every edit read from disk and one cached file", state)
+	}
+
+	c.invalidate(path)
+	read("fresher\n")
+	if state := c.state(); state.Misses != 4 {
+		t.Errorf("after invalidate: %+v, want another miss", state)
+	}
+}
+
+func TestContentCacheBudget(t *testing.T) {
+	dir := t.TempDir()
+	paths := map[string]string{}
+	for _, name := range []string{"a", "b", "c", "big"} {
+		paths[name] = filepath.Join(dir, name+".md")
+	}
+	writeTestFile(t, paths["a"], "aaaa")
+	writeTestFile(t, paths["b"], "bbbb")
+	writeTestFile(t, paths["c"], "cccc")
+	writeTestFile(t, paths["big"], "too big for the budget")
+	c := newContentCache(10)
+
+	for _, name := range []string{"a", "b", "a", "c", "big"} {
+		if _, err := c.read(paths[name]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// c pushed out b, the least recently used, and big never fit
+	state := c.state()
+	if state.Entries != 2 || state.Bytes != 8 || state.Evictions != 1 {
+		t.Errorf("state = %+v, want a and c cached after one eviction", state)
+	}
+	if _, ok := c.items[paths["b"]]; ok {
+		t.Error("b is still cached")
+	}
+
+	disabled := newContentCache(0)
+	disabled.read(paths["a"])
+	disabled.read(paths["a"])
+	if state := disabled.state(); state.Hits != 0 || state.Entries != 0 {
+		t.Errorf("disabled cache state = %+v", state)
+	}
+}
+
+func TestEntryReadAfterExternalEdit(t *testing.T) {
+	dir := newTestVault(t, map[string]string{"2024-03-15.md": "# Friday\n"})
+	if entry, err := getEntryByDate("2024-03-15"); err != nil || entry.Content != "# Friday\n" {
+		t.Fatalf("entry = %q, %v", entry.Content, err)
+	}
+
+	path := filepath.Join(dir, "2024-03-15.md")
+	writeTestFile(t, path, "# Friday, edited in another app\n")
+	touch(t, path, time.Now().Add(time.Minute))
+	if entry, err := getEntryByDate("2024-03-15"); err != nil || entry.Content != "# Friday, edited in another app\n" {
+		t.Errorf("entry after the edit = %q, %v", entry.Content, err)
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	defaultMaxResponseBytes = 256 << 10
+	continuationTTL         = 10 * time.Minute
+	minSplitBytes           = 1 << 10
+	chunkOverheadBytes      = 96 // continuation token field, separators and token estimate digits
+
+	// parked continuations are capped by count and by the content they hold, the oldest go first
+	maxContinuations     = 32
+	maxContinuationBytes = 64 << 20
+)
+
+// responses larger than THEMIS_MAX_RESPONSE_BYTES are split into chunks, 0 disables chunking
+var maxResponseBytes = getEnvInt("THEMIS_MAX_RESPONSE_BYTES", defaultMaxResponseBytes)
+
+var continuations = &continuationStore{pending: map[string]*continuation{}}
+
+type continuationStore struct {
+	mu      sync.Mutex
+	pending map[string]*continuation
+	bytes   int // content held by all pending continuations
+}
+
+// continuation holds the entries not yet sent, the first of which may be the rest of a split entry
+type continuation struct {
+	remaining []Entry
+	files     map[string]fileStamp
+	expires   time.Time
+	bytes     int
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+type ContinueResultInput struct {
+	ContinuationToken string `json:"continuationToken" jsonschema:"Token from a previous chunked response"`
+}
+
+// handlers
+func handleContinueResult(ctx context.Context, req *mcp.CallToolRequest, input ContinueResultInput) (
+	*mcp.CallToolResult,
+	EntriesOutput,
+	error,
+) {
+	pending, err := continuations.take(input.ContinuationToken)
+	if err != nil {
+		return nil, EntriesOutput{}, err
+	}
+
+	output := EntriesOutput{}
+	for _, entry := range pending.remaining {
+		output.TotalEstimatedTokens += entry.EstimatedTokens
+	}
+	return nil, chunkEntriesOutput(output, pending.remaining, pending.files), nil
+}
+
+// helpers
+
+// chunkEntriesOutput fills output with as many entries as fit the response limit, splitting the content of an
+// entry that is too large on its own, and parks the rest behind a continuation token
+func chunkEntriesOutput(output EntriesOutput, entries []Entry, files map[string]fileStamp) EntriesOutput {
+	output.Entries = []Entry{}
+	if maxResponseBytes <= 0 {
+		output.Entries = append(output.Entries, entries...)
+		output.Count = len(output.Entries)
+		return output
+	}
+
+	used := jsonSize(output)
+	for i, entry := range entries {
+		size := jsonSize(entry)
+		if used+size <= maxResponseBytes {
+			output.Entries = append(output.Entries, entry)
+			used += size
+			continue
+		}
+
+		shell := entry
+		shell.Content, shell.Partial, shell.ContentOffset = "", true, entry.ContentOffset+len(entry.Content)
+		available := maxResponseBytes - used - jsonSize(shell) - chunkOverheadBytes
+		if available < minSplitBytes && len(output.Entries) > 0 {
+			output.ContinuationToken = continuations.park(entries[i:], files)
+			break
+		}
+
+		head, tail := splitEntryContent(entry, max(available, minSplitBytes))
+		output.Entries = append(output.Entries, head)
+		output.ContinuationToken = continuations.park(append([]Entry{tail}, entries[i+1:]...), files)
+		break
+	}
+
+	output.Count = len(output.Entries)
+	return output
+}
+
+// splitEntryContent cuts an entry's content so the first piece marshals to roughly budget bytes, preferring a line break
+func splitEntryContent(entry Entry, budget int) (Entry, Entry) {
+	content := entry.Content
+	cut := min(budget, len(content))
+	for cut > 0 {
+		for cut > 0 && cut < len(content) && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		size := jsonSize(content[:cut])
+		if size <= budget {
+			break
+		}
+		// shrink in proportion to the json escaping overhead
+		cut = cut * budget / size
+	}
+	if newline := strings.LastIndexByte(content[:cut], '\n'); newline > cut/2 {
+		cut = newline + 1
+	}
+	if cut == 0 {
+		_, width := utf8.DecodeRuneInString(content)
+		cut = width
+	}
+
+	head, tail := entry, entry
+	head.Content, head.Partial = content[:cut], true
+	tail.Content, tail.Partial = content[cut:], true
+	tail.ContentOffset = entry.ContentOffset + cut
+	tail.Frontmatter, tail.Warnings = nil, nil
+	if entry.EstimatedTokens > 0 {
+		head.EstimatedTokens, tail.EstimatedTokens = estimateTokens(head.Content), estimateTokens(tail.Content)
+	}
+	return head, tail
+}
+
+func jsonSize(v any) int {
+	data, _ := json.Marshal(v)
+	return len(data)
+}
+
+// stampFiles records the current mod time and size of every entry file so continuations notice external edits
+func stampFiles(entries []Entry) map[string]fileStamp {
+	files := make(map[string]fileStamp, len(entries))
+	for _, entry := range entries {
+		if info, err := os.Stat(entry.FilePath); err == nil {
+			files[entry.FilePath] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return files
+}
+
+func (s *continuationStore) park(remaining []Entry, files map[string]fileStamp) string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	token := hex.EncodeToString(buf)
+
+	size := 0
+	for _, entry := range remaining {
+		size += len(entry.Content)
+	}
+
+	// the ttl is real time, a pinned or jumped clock must neither keep tokens forever nor expire them all at once
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune()
+	s.pending[token] = &continuation{remaining: remaining, files: files, expires: wallNow().Add(continuationTTL), bytes: size}
+	s.bytes += size
+	s.evict(token)
+	return token
+}
+
+// take hands out a continuation once, refusing it when it expired or the files behind it changed
+func (s *continuationStore) take(token string) (*continuation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune()
+
+	pending, ok := s.pending[token]
+	if !ok {
+		return nil, fmt.Errorf("unknown or expired continuation token, rerun the original query")
+	}
+	s.drop(token)
+
+	for path, stamp := range pending.files {
+		info, err := os.Stat(path)
+		if err != nil || !info.ModTime().Equal(stamp.modTime) || info.Size() != stamp.size {
+			return nil, fmt.Errorf("entries changed since the first chunk (%s), rerun the original query", vaultRelative(path))
+		}
+	}
+	return pending, nil
+}
+
+// prune must be called with mu held
+func (s *continuationStore) prune() {
+	for token, pending := range s.pending {
+		if wallNow().After(pending.expires) {
+			s.drop(token)
+		}
+	}
+}
+
+// evict drops the oldest continuations other than keep until the store is within its caps. must be called with mu held.
+func (s *continuationStore) evict(keep string) {
+	for len(s.pending) > maxContinuations || s.bytes > maxContinuationBytes {
+		oldest := ""
+		for token, pending := range s.pending {
+			if token != keep && (oldest == "" || pending.expires.Before(s.pending[oldest].expires)) {
+				oldest = token
+			}
+		}
+		if oldest == "" {
+			return
+		}
+		s.drop(oldest)
+	}
+}
+
+// drop must be called with mu held
+func (s *continuationStore) drop(token string) {
+	if pending, ok := s.pending[token]; ok {
+		s.bytes -= pending.bytes
+		delete(s.pending, token)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useTestContinuations gives the test its own continuation store and response limit
+func useTestContinuations(t *testing.T, limit int) {
+	t.Helper()
+	oldStore, oldLimit := continuations, maxResponseBytes
+	continuations, maxResponseBytes = &continuationStore{pending: map[string]*continuation{}}, limit
+	t.Cleanup(func() { continuations, maxResponseBytes = oldStore, oldLimit })
+}
+
+func TestContinueResult(t *testing.T) {
+	long := strings.Repeat("a line of diary text\n", 150)
+	dir := newTestVault(t, map[string]string{
+		"2024-03-13.md": long,
+		"2024-03-14.md": long,
+		"2024-03-15.md": long,
+	})
+	useTestContinuations(t, 4096)
+	ctx := context.Background()
+
+	_, output, err := handleGetEntriesByYear(ctx, nil, GetEntriesByYearInput{Year: 2024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := map[string]string{}
+	collect := func(output EntriesOutput) {
+		for _, entry := range output.Entries {
+			content[entry.Date] += entry.Content
+		}
+	}
+	collect(output)
+
+	chunks := 1
+	for token := output.ContinuationToken; token != ""; token = output.ContinuationToken {
+		if _, output, err = handleContinueResult(ctx, nil, ContinueResultInput{ContinuationToken: token}); err != nil {
+			t.Fatal(err)
+		}
+		collect(output)
+		chunks++
+
+		// a token is handed out once
+		if _, _, err := handleContinueResult(ctx, nil, ContinueResultInput{ContinuationToken: token}); err == nil {
+			t.Fatal("a used token was accepted again")
+		}
+	}
+	if chunks < 2 {
+		t.Errorf("got %d chunks, want the response split", chunks)
+	}
+	if want := map[string]string{"2024-03-13": long, "2024-03-14": long, "2024-03-15": long}; !reflect.DeepEqual(content, want) {
+		t.Errorf("reassembled entries differ from the files")
+	}
+
+	// an edit between chunks invalidates the token
+	_, output, err = handleGetEntriesByYear(ctx, nil, GetEntriesByYearInput{Year: 2024})
+	if err != nil || output.ContinuationToken == "" {
+		t.Fatalf("output = %+v, %v", output.ContinuationToken, err)
+	}
+	path := filepath.Join(dir, "2024-03-13.md")
+	writeTestFile(t, path, long+"edited\n")
+	if _, _, err := handleContinueResult(ctx, nil, ContinueResultInput{ContinuationToken: output.ContinuationToken}); err == nil || !strings.Contains(err.Error(), "changed since the first chunk") {
+		t.Errorf("error = %v, want the edit reported", err)
+	}
+}
+
+func TestContinuationsExpireOnTheWallClock(t *testing.T) {
+	newTestVault(t, nil)
+	useTestContinuations(t, 4096)
+
+	// pinning or jumping the clock doesn't touch the ttl
+	setTestNow(t, "2001-01-01")
+	token := continuations.park([]Entry{{Date: "2024-03-15"}}, nil)
+	setTestNow(t, "2099-01-01")
+	if _, err := continuations.take(token); err != nil {
+		t.Errorf("token expired after a clock jump: %v", err)
+	}
+
+	token = continuations.park([]Entry{{Date: "2024-03-15"}}, nil)
+	continuations.pending[token].expires = time.Now().Add(-time.Second)
+	if _, err := continuations.take(token); err == nil {
+		t.Error("an expired token was accepted")
+	}
+}
+
+func TestContinuationStoreCap(t *testing.T) {
+	useTestContinuations(t, 4096)
+
+	var tokens []string
+	for range maxContinuations + 1 {
+		tokens = append(tokens, continuations.park([]Entry{{Content: "x"}}, nil))
+		time.Sleep(time.Microsecond)
+	}
+	if len(continuations.pending) != maxContinuations {
+		t.Errorf("%d continuations held, want %d", len(continuations.pending), maxContinuations)
+	}
+	if _, err := continuations.take(tokens[0]); err == nil {
+		t.Error("the oldest continuation was not evicted")
+	}
+
+	// one continuation over the byte cap pushes out everything else but is kept itself
+	big := continuations.park([]Entry{{Content: strings.Repeat("x", maxContinuationBytes+1)}}, nil)
+	if len(continuations.pending) != 1 || continuations.bytes != maxContinuationBytes+1 {
+		t.Errorf("%d continuations holding %d bytes, want only the big one", len(continuations.pending), continuations.bytes)
+	}
+	if _, err := continuations.take(big); err != nil || continuations.bytes != 0 {
+		t.Errorf("take = %v with %d bytes left", err, continuations.bytes)
+	}
+}
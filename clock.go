@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// clock is the source of the current time behind now()
+type clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// fixedClock always reports the same moment, for replaying what the server would have seen on another day
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time { return time.Time(c) }
+
+var (
+	clockMu     sync.RWMutex
+	activeClock clock = systemClock{}
+)
+
+// now is the source of the current time for every date calculation, set the clock with setClock to pin it
+func now() time.Time {
+	clockMu.RLock()
+	defer clockMu.RUnlock()
+	return activeClock.Now()
+}
+
+// wallNow is the real time, for time budgets and file ages, which a pinned clock must not freeze or shift.
+// everything that is about dates goes through now instead.
+func wallNow() time.Time {
+	return time.Now()
+}
+
+func setClock(c clock) {
+	clockMu.Lock()
+	defer clockMu.Unlock()
+	activeClock = c
+}
+
+type SetNowInput struct {
+	Now string `json:"now,omitempty" jsonschema:"Moment to pin the clock to, RFC 3339 or YYYY-MM-DD for noon that day, empty to go back to the real clock"`
+}
+
+type SetNowOutput struct {
+	Now  string `json:"now" jsonschema:"The current time as the server now sees it, RFC 3339 in the configured timezone"`
+	Fake bool   `json:"fake" jsonschema:"Whether the clock is pinned"`
+}
+
+// handlers
+func handleSetNow(ctx context.Context, req *mcp.CallToolRequest, input SetNowInput) (
+	*mcp.CallToolResult,
+	SetNowOutput,
+	error,
+) {
+	if input.Now == "" {
+		setClock(systemClock{})
+		return nil, SetNowOutput{Now: now().In(location).Format(time.RFC3339)}, nil
+	}
+
+	t, err := parseFakeNow(input.Now)
+	if err != nil {
+		return nil, SetNowOutput{}, err
+	}
+	setClock(fixedClock(t))
+
+	return nil, SetNowOutput{Now: now().In(location).Format(time.RFC3339), Fake: true}, nil
+}
+
+// helpers
+
+// parseFakeNow reads an RFC 3339 timestamp, or a bare date taken as noon in the configured timezone
+func parseFakeNow(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	date, err := time.ParseInLocation("2006-01-02", value, location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected RFC 3339 or YYYY-MM-DD", value)
+	}
+	return date.Add(12 * time.Hour), nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// connectTestServer runs server over an in-memory transport and returns the client side of the session
+func connectTestServer(t *testing.T, server *mcp.Server) *mcp.ClientSession {
+	t.Helper()
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "v0.0.1"}, nil)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		session.Close()
+		serverSession.Wait()
+	})
+	return session
+}
+
+func hasTool(t *testing.T, session *mcp.ClientSession, name string) bool {
+	t.Helper()
+	tools, err := session.ListTools(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tool := range tools.Tools {
+		if tool.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func TestParseFakeNow(t *testing.T) {
+	oldLocation := location
+	location = time.FixedZone("UTC+9", 9*60*60)
+	defer func() { location = oldLocation }()
+
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"2024-03-15T08:30:00Z", time.Date(2024, time.March, 15, 8, 30, 0, 0, time.UTC)},
+		// a bare date is noon in the configured timezone
+		{"2024-03-15", time.Date(2024, time.March, 15, 12, 0, 0, 0, location)},
+	}
+	for _, tt := range tests {
+		got, err := parseFakeNow(tt.value)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("parseFakeNow(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+	if _, err := parseFakeNow("last tuesday"); err == nil {
+		t.Error("parseFakeNow accepted last tuesday")
+	}
+}
+
+func TestSetNowOnlyWithTesting(t *testing.T) {
+	newTestVault(t, nil)
+	if hasTool(t, connectTestServer(t, newServer(false, false)), "setNow") {
+		t.Error("setNow registered without --testing")
+	}
+	if !hasTool(t, connectTestServer(t, newServer(true, false)), "setNow") {
+		t.Error("setNow missing with --testing")
+	}
+}
+
+func TestSetNowPinsDateLogic(t *testing.T) {
+	newTestVault(t, map[string]string{
+		"2024-03-14.md": "# Thursday\n",
+		"2024-03-15.md": "# Friday\n",
+	})
+	oldLocation := location
+	location = time.UTC
+	defer func() { location = oldLocation }()
+	session := connectTestServer(t, newServer(true, false))
+	ctx := context.Background()
+
+	call := func(name string, arguments any, out any) {
+		t.Helper()
+		result, err := session.CallTool(ctx, &mcp.CallToolParams{Name: name, Arguments: arguments})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.IsError {
+			t.Fatalf("%s failed: %+v", name, result.Content)
+		}
+		data, err := json.Marshal(result.StructuredContent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(data, out); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// pinned to the 14th, a one day window starts on the 14th
+	var pinned SetNowOutput
+	call("setNow", SetNowInput{Now: "2024-03-14"}, &pinned)
+	if !pinned.Fake || pinned.Now != "2024-03-14T12:00:00Z" {
+		t.Errorf("setNow = %+v", pinned)
+	}
+	var recent EntriesOutput
+	call("getRecentEntries", GetRecentEntriesInput{Days: 1}, &recent)
+	if recent.Cutoff != "2024-03-14" {
+		t.Errorf("cutoff on the 14th = %s", recent.Cutoff)
+	}
+
+	// an empty value goes back to the real clock
+	var reset SetNowOutput
+	call("setNow", SetNowInput{}, &reset)
+	if reset.Fake || now().Year() == 2024 {
+		t.Errorf("after reset: %+v, now %v", reset, now())
+	}
+}
@@ -0,0 +1,505 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// conflict copies left by syncthing, obsidian sync and dropbox, capturing the date of the entry they belong to
+const defaultConflictPattern = `(?i)^(\d{4}-\d{2}-\d{2})(?:\.sync-conflict-[0-9a-z-]+| \([^)]*\bconflict(?:ed)?\b[^)]*\))\.md$`
+
+// THEMIS_CONFLICT_PATTERN replaces the conflict copy file name pattern, its first group must capture the entry date
+var conflictPattern = getConflictPattern()
+
+// conflict copies moved aside by resolveConflicts, keeping their vault relative path
+const trashDir = ".themis/trash"
+
+const maxDiffLines = 4000
+
+func getConflictPattern() *regexp.Regexp {
+	raw := os.Getenv("THEMIS_CONFLICT_PATTERN")
+	if strings.TrimSpace(raw) == "" {
+		raw = defaultConflictPattern
+	}
+	pattern, err := compileConflictPattern(raw)
+	if err != nil {
+		log.Fatalf("invalid THEMIS_CONFLICT_PATTERN: %v", err)
+	}
+	return pattern
+}
+
+type ResolveConflictInput struct {
+	Date         string `json:"date" jsonschema:"Entry date in YYYY-MM-DD format"`
+	ConflictPath string `json:"conflictPath,omitempty" jsonschema:"Vault relative path of the conflict copy, defaults to the first one found"`
+	Action       string `json:"action,omitempty" jsonschema:"diff (default) only shows differences, keep_main, keep_conflict, keep_both appends the copy with a marker, merge keeps the lines only one side has and refuses where both changed the same lines"`
+	DryRun       bool   `json:"dryRun,omitempty" jsonschema:"Report what would happen without changing any files"`
+}
+
+type ResolveConflictOutput struct {
+	Date         string `json:"date" jsonschema:"Entry date in YYYY-MM-DD format"`
+	MainPath     string `json:"mainPath" jsonschema:"Vault relative path of the main entry"`
+	ConflictPath string `json:"conflictPath" jsonschema:"Vault relative path of the conflict copy"`
+	Diff         string `json:"diff" jsonschema:"Line diff from the main file (-) to the conflict copy (+)"`
+	Action       string `json:"action" jsonschema:"Action taken"`
+	Result       string `json:"result,omitempty" jsonschema:"Content the main entry ends up with"`
+	DryRun       bool   `json:"dryRun" jsonschema:"Whether this was a dry run"`
+}
+
+type ResolveConflictsInput struct {
+	Strategy string `json:"strategy" jsonschema:"merge adds the copy's unique content to its dated entry and skips copies where both changed the same lines, trash moves the copy to .themis/trash"`
+	Pattern  string `json:"pattern,omitempty" jsonschema:"Regular expression for conflict copy file names whose first group captures the date, defaults to the configured pattern"`
+	DryRun   bool   `json:"dryRun,omitempty" jsonschema:"Report what would happen without changing any files"`
+}
+
+type ConflictResolution struct {
+	Date         string `json:"date" jsonschema:"Entry date the copy belongs to in YYYY-MM-DD format"`
+	ConflictPath string `json:"conflictPath" jsonschema:"Vault relative path of the conflict copy"`
+	MainPath     string `json:"mainPath" jsonschema:"Vault relative path of the dated entry"`
+	Action       string `json:"action" jsonschema:"merged, renamed when there was no dated entry and the copy took its place, trashed or skipped"`
+	AddedLines   int    `json:"addedLines,omitempty" jsonschema:"Lines from the copy merged into the entry"`
+	TrashPath    string `json:"trashPath,omitempty" jsonschema:"Vault relative path the copy was moved to"`
+	Reason       string `json:"reason,omitempty" jsonschema:"Why the copy was skipped"`
+}
+
+type ResolveConflictsOutput struct {
+	Strategy    string               `json:"strategy" jsonschema:"Strategy applied"`
+	Resolutions []ConflictResolution `json:"resolutions" jsonschema:"One item per conflict copy found, in path order"`
+	Resolved    int                  `json:"resolved" jsonschema:"Conflict copies merged, renamed or trashed"`
+	Skipped     int                  `json:"skipped" jsonschema:"Conflict copies left in place"`
+	DryRun      bool                 `json:"dryRun" jsonschema:"Whether this was a dry run"`
+}
+
+// handlers
+func handleResolveConflict(ctx context.Context, req *mcp.CallToolRequest, input ResolveConflictInput) (
+	*mcp.CallToolResult,
+	ResolveConflictOutput,
+	error,
+) {
+	entry, err := getEntryByDate(input.Date)
+	if err != nil {
+		return nil, ResolveConflictOutput{}, err
+	}
+	if len(entry.Conflicts) == 0 {
+		return nil, ResolveConflictOutput{}, fmt.Errorf("no conflict copies found for %s", input.Date)
+	}
+	if entry.Encrypted {
+		return nil, ResolveConflictOutput{}, fmt.Errorf("conflicts of encrypted entries must be resolved by hand")
+	}
+
+	conflictRel := entry.Conflicts[0]
+	if input.ConflictPath != "" {
+		conflictRel = filepath.ToSlash(input.ConflictPath)
+		found := false
+		for _, c := range entry.Conflicts {
+			found = found || c == conflictRel
+		}
+		if !found {
+			return nil, ResolveConflictOutput{}, fmt.Errorf("%s is not a conflict copy of %s, known copies: %s", input.ConflictPath, input.Date, strings.Join(entry.Conflicts, ", "))
+		}
+	}
+	conflictPath, err := resolveInVault(filepath.FromSlash(conflictRel))
+	if err != nil {
+		return nil, ResolveConflictOutput{}, err
+	}
+
+	data, err := os.ReadFile(conflictPath)
+	if err != nil {
+		return nil, ResolveConflictOutput{}, fmt.Errorf("failed to read conflict copy: %w", err)
+	}
+	conflictContent, _ := decodeContent(data)
+
+	mainLines, conflictLines := splitLines(entry.Content), splitLines(conflictContent)
+	ops := diffLines(mainLines, conflictLines)
+	output := ResolveConflictOutput{
+		Date:         entry.Date,
+		MainPath:     vaultRelative(entry.FilePath),
+		ConflictPath: conflictRel,
+		Diff:         formatDiff(ops),
+		Action:       input.Action,
+		DryRun:       input.DryRun,
+	}
+	if output.Action == "" {
+		output.Action = "diff"
+	}
+
+	var result string
+	switch output.Action {
+	case "diff":
+		return nil, output, nil
+	case "keep_main":
+		result = entry.Content
+	case "keep_conflict":
+		result = conflictContent
+	case "keep_both":
+		result = strings.TrimRight(entry.Content, "\n") + "\n\n---\n<!-- merged from conflict copy " + filepath.Base(conflictPath) + " -->\n\n" + conflictContent
+	case "merge":
+		if diffTooLarge(mainLines, conflictLines) {
+			return nil, ResolveConflictOutput{}, fmt.Errorf("%s is too large to merge line by line, use keep_main, keep_conflict or keep_both", input.Date)
+		}
+		merged, err := mergeLines(ops)
+		if err != nil {
+			return nil, ResolveConflictOutput{}, err
+		}
+		result = withTrailingNewline(merged, entry.Content)
+	default:
+		return nil, ResolveConflictOutput{}, fmt.Errorf("unknown action %q, expected diff, keep_main, keep_conflict, keep_both or merge", input.Action)
+	}
+	output.Result = result
+
+	if input.DryRun {
+		return nil, output, nil
+	}
+	if result != entry.Content {
+		original, err := readEntryPlaintext(entry)
+		if err != nil {
+			return nil, ResolveConflictOutput{}, fmt.Errorf("failed to read %s: %w", entry.FilePath, err)
+		}
+		if err := writeFileAtomic(entry.FilePath, encodeLike(original, result)); err != nil {
+			return nil, ResolveConflictOutput{}, fmt.Errorf("failed to write %s: %w", entry.FilePath, err)
+		}
+	}
+	if err := journal("remove", conflictPath, nil, func() error { return os.Remove(conflictPath) }); err != nil {
+		return nil, ResolveConflictOutput{}, fmt.Errorf("failed to remove conflict copy: %w", err)
+	}
+	cache.invalidate(conflictPath)
+
+	return nil, output, nil
+}
+
+func handleResolveConflicts(ctx context.Context, req *mcp.CallToolRequest, input ResolveConflictsInput) (
+	*mcp.CallToolResult,
+	ResolveConflictsOutput,
+	error,
+) {
+	if input.Strategy != "merge" && input.Strategy != "trash" {
+		return nil, ResolveConflictsOutput{}, fmt.Errorf("unknown strategy %q, expected merge or trash", input.Strategy)
+	}
+	pattern := conflictPattern
+	if input.Pattern != "" {
+		var err error
+		if pattern, err = compileConflictPattern(input.Pattern); err != nil {
+			return nil, ResolveConflictsOutput{}, fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+
+	// copies are collected before anything is changed so the walk never sees its own moves
+	var copies []string
+	err := filepath.WalkDir(entryRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			log.Printf("error accessing %s: %v", path, err)
+			return nil
+		}
+		if d.IsDir() {
+			if path != entryRoot && strings.HasPrefix(d.Name(), ".") || isExportsDir(path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if conflictDate(pattern, d.Name()) != "" {
+			copies = append(copies, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, ResolveConflictsOutput{}, fmt.Errorf("failed to find conflict copies: %w", err)
+	}
+
+	output := ResolveConflictsOutput{Strategy: input.Strategy, Resolutions: []ConflictResolution{}, DryRun: input.DryRun}
+	for _, path := range copies {
+		resolution := resolveConflictCopy(path, conflictDate(pattern, filepath.Base(path)), input.Strategy, input.DryRun)
+		if resolution.Action == "skipped" {
+			output.Skipped++
+		} else {
+			output.Resolved++
+		}
+		output.Resolutions = append(output.Resolutions, resolution)
+	}
+
+	return nil, output, nil
+}
+
+// helpers
+
+func compileConflictPattern(raw string) (*regexp.Regexp, error) {
+	pattern, err := regexp.Compile(raw)
+	if err != nil {
+		return nil, err
+	}
+	if pattern.NumSubexp() < 1 {
+		return nil, fmt.Errorf("%q has no group capturing the entry date", raw)
+	}
+	return pattern, nil
+}
+
+// conflictDate is the entry date a conflict copy name belongs to, empty when it is not a conflict copy
+func conflictDate(pattern *regexp.Regexp, name string) string {
+	match := pattern.FindStringSubmatch(name)
+	if match == nil {
+		return ""
+	}
+	if _, err := time.Parse("2006-01-02", match[1]); err != nil {
+		return ""
+	}
+	return match[1]
+}
+
+// resolveConflictCopy merges or trashes one conflict copy. problems with a single copy are reported on it
+// instead of failing the whole run, so one bad file doesn't hold up the rest.
+func resolveConflictCopy(path, date, strategy string, dryRun bool) ConflictResolution {
+	mainPath := filepath.Join(filepath.Dir(path), date+".md")
+	resolution := ConflictResolution{Date: date, ConflictPath: vaultRelative(path), MainPath: vaultRelative(mainPath)}
+	skip := func(format string, args ...any) ConflictResolution {
+		resolution.Action, resolution.Reason = "skipped", fmt.Sprintf(format, args...)
+		return resolution
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return skip("failed to read conflict copy: %v", err)
+	}
+
+	if strategy == "trash" {
+		trashPath, err := resolveInVault(filepath.Join(trashDir, vaultRelative(path)))
+		if err != nil {
+			return skip("%v", err)
+		}
+		if fileExists(trashPath) {
+			return skip("%s is already in the trash", vaultRelative(trashPath))
+		}
+		resolution.Action, resolution.TrashPath = "trashed", vaultRelative(trashPath)
+		if dryRun {
+			return resolution
+		}
+
+		if err := os.MkdirAll(filepath.Dir(trashPath), 0o755); err != nil {
+			return skip("failed to create %s: %v", filepath.Dir(trashPath), err)
+		}
+		if err := writeFileAtomic(trashPath, data); err != nil {
+			return skip("failed to move to trash: %v", err)
+		}
+		if err := removeConflictCopy(path); err != nil {
+			return skip("copied to trash but failed to remove: %v", err)
+		}
+		return resolution
+	}
+
+	if fileExists(mainPath + ".age") {
+		return skip("conflicts of encrypted entries must be resolved by hand")
+	}
+	copyContent, _ := decodeContent(data)
+
+	// without a dated entry the copy is the only version there is
+	if !fileExists(mainPath) {
+		resolution.Action = "renamed"
+		if dryRun {
+			return resolution
+		}
+		if err := writeNewEntry(mainPath, string(encodeLike(data, copyContent))); err != nil {
+			return skip("%v", err)
+		}
+		if err := removeConflictCopy(path); err != nil {
+			return skip("entry written but failed to remove the copy: %v", err)
+		}
+		return resolution
+	}
+
+	mainData, err := os.ReadFile(mainPath)
+	if err != nil {
+		return skip("failed to read %s: %v", resolution.MainPath, err)
+	}
+	mainContent, _ := decodeContent(mainData)
+
+	mainLines, copyLines := splitLines(mainContent), splitLines(copyContent)
+	if diffTooLarge(mainLines, copyLines) {
+		return skip("too large to diff, resolve it with resolveConflict")
+	}
+	ops := diffLines(mainLines, copyLines)
+	merged, err := mergeLines(ops)
+	if err != nil {
+		return skip("%v", err)
+	}
+	result := withTrailingNewline(merged, mainContent)
+	for _, op := range ops {
+		if op.kind == '+' && strings.TrimSpace(op.line) != "" {
+			resolution.AddedLines++
+		}
+	}
+	resolution.Action = "merged"
+	if dryRun {
+		return resolution
+	}
+
+	if result != mainContent {
+		if err := writeFileAtomic(mainPath, encodeLike(mainData, result)); err != nil {
+			return skip("failed to write %s: %v", resolution.MainPath, err)
+		}
+		cache.invalidate(mainPath)
+	}
+	if err := removeConflictCopy(path); err != nil {
+		return skip("merged but failed to remove the copy: %v", err)
+	}
+	return resolution
+}
+
+func removeConflictCopy(path string) error {
+	if err := journal("remove", path, nil, func() error { return os.Remove(path) }); err != nil {
+		return err
+	}
+	cache.invalidate(path)
+	return nil
+}
+
+// findConflicts maps entry dates to the vault relative conflict copies sitting in dir
+func findConflicts(dir string) map[string][]string {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	conflicts := map[string][]string{}
+	for _, file := range files {
+		if date := conflictDate(conflictPattern, file.Name()); date != "" && !file.IsDir() {
+			conflicts[date] = append(conflicts[date], vaultRelative(filepath.Join(dir, file.Name())))
+		}
+	}
+	return conflicts
+}
+
+type diffOp struct {
+	kind byte // ' ' unchanged, '-' only in main, '+' only in the conflict copy
+	line string
+}
+
+// splitLines cuts content into lines that all end in \n, so a last line without one still matches its
+// counterpart on the other side. withTrailingNewline puts the original ending back after merging.
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	lines := strings.SplitAfter(content, "\n")
+	return lines[:len(lines)-1]
+}
+
+// withTrailingNewline gives merged content the same ending as the main file, which splitLines normalised away
+func withTrailingNewline(merged, main string) string {
+	if main != "" && !strings.HasSuffix(main, "\n") {
+		return strings.TrimSuffix(merged, "\n")
+	}
+	return merged
+}
+
+// diffTooLarge reports whether diffLines gives up aligning a and b and lists every line as changed
+func diffTooLarge(a, b []string) bool {
+	return len(a)*len(b) > maxDiffLines*maxDiffLines
+}
+
+// diffLines aligns both sides on their longest common subsequence of lines
+func diffLines(a, b []string) []diffOp {
+	if diffTooLarge(a, b) {
+		ops := make([]diffOp, 0, len(a)+len(b))
+		for _, line := range a {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, diffOp{'+', line})
+		}
+		return ops
+	}
+
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+func formatDiff(ops []diffOp) string {
+	var b strings.Builder
+	for _, op := range ops {
+		if op.line == "" {
+			continue
+		}
+		b.WriteByte(op.kind)
+		b.WriteString(strings.TrimSuffix(op.line, "\n"))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// mergeLines keeps every hunk only one side has, lines only in the main entry as well as lines only in the copy,
+// so edits made on either device survive. it refuses where both sides changed the same lines, since without a
+// common ancestor there is no telling which version is meant.
+func mergeLines(ops []diffOp) (string, error) {
+	var b strings.Builder
+	for start := 0; start < len(ops); {
+		if ops[start].kind == ' ' {
+			b.WriteString(ops[start].line)
+			start++
+			continue
+		}
+
+		end := start
+		removed, added := false, false
+		for end < len(ops) && ops[end].kind != ' ' {
+			removed = removed || ops[end].kind == '-'
+			added = added || ops[end].kind == '+'
+			end++
+		}
+		if removed && added {
+			return "", fmt.Errorf("both versions changed the same lines near %q, use keep_both or pick one side", strings.TrimSpace(ops[start].line))
+		}
+
+		for _, op := range ops[start:end] {
+			b.WriteString(op.line)
+		}
+		start = end
+	}
+	return b.String(), nil
+}
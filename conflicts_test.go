@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplitLinesNormalisesTrailingNewline(t *testing.T) {
+	tests := []struct {
+		main, copy string
+		want       string
+		added      int
+	}{
+		{"a\nfoo", "a\nfoo\nbar", "a\nfoo\nbar", 1},
+		{"a\nfoo\n", "a\nfoo\nbar", "a\nfoo\nbar\n", 1},
+		{"a\nfoo", "a\nfoo\n", "a\nfoo", 0},
+		{"", "new\n", "new\n", 1},
+	}
+	for _, tt := range tests {
+		ops := diffLines(splitLines(tt.main), splitLines(tt.copy))
+		merged, err := mergeLines(ops)
+		if err != nil {
+			t.Fatalf("merging %q into %q: %v", tt.copy, tt.main, err)
+		}
+		if got := withTrailingNewline(merged, tt.main); got != tt.want {
+			t.Errorf("merge of %q and %q = %q, want %q", tt.main, tt.copy, got, tt.want)
+		}
+		added := 0
+		for _, op := range ops {
+			if op.kind == '+' {
+				added++
+			}
+		}
+		if added != tt.added {
+			t.Errorf("%q to %q added %d lines, want %d", tt.main, tt.copy, added, tt.added)
+		}
+	}
+}
+
+func TestResolveConflictsMerge(t *testing.T) {
+	dir := newTestVault(t, map[string]string{
+		"2024-03-15.md":                                      "# Friday\nwent for a run",
+		"2024-03-15 (conflicted copy).md":                    "# Friday\nwent for a run\ncalled mum\n",
+		"2024-03-16.sync-conflict-20240316-101010-ABCDEF.md": "only the copy\n",
+	})
+
+	_, output, err := handleResolveConflicts(context.Background(), nil, ResolveConflictsInput{Strategy: "merge"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Resolved != 2 || output.Skipped != 0 {
+		t.Fatalf("resolved %d and skipped %d, want 2 and 0: %+v", output.Resolved, output.Skipped, output.Resolutions)
+	}
+
+	byDate := map[string]ConflictResolution{}
+	for _, r := range output.Resolutions {
+		byDate[r.Date] = r
+	}
+	if r := byDate["2024-03-15"]; r.Action != "merged" || r.AddedLines != 1 {
+		t.Errorf("2024-03-15 resolution = %+v, want merged with 1 added line", r)
+	}
+	if r := byDate["2024-03-16"]; r.Action != "renamed" {
+		t.Errorf("2024-03-16 resolution = %+v, want renamed", r)
+	}
+
+	if got, want := readTestFile(t, filepath.Join(dir, "2024-03-15.md")), "# Friday\nwent for a run\ncalled mum"; got != want {
+		t.Errorf("merged entry = %q, want %q", got, want)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "2024-03-16.md")); got != "only the copy\n" {
+		t.Errorf("renamed entry = %q", got)
+	}
+	for _, name := range []string{"2024-03-15 (conflicted copy).md", "2024-03-16.sync-conflict-20240316-101010-ABCDEF.md"} {
+		if fileExists(filepath.Join(dir, name)) {
+			t.Errorf("%s was not removed", name)
+		}
+	}
+}
+
+func TestResolveConflictsTrash(t *testing.T) {
+	dir := newTestVault(t, map[string]string{
+		"2024-03-15.md":                   "main\n",
+		"2024-03-15 (conflicted copy).md": "copy\n",
+	})
+
+	_, dry, err := handleResolveConflicts(context.Background(), nil, ResolveConflictsInput{Strategy: "trash", DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dry.Resolved != 1 || !fileExists(filepath.Join(dir, "2024-03-15 (conflicted copy).md")) {
+		t.Fatalf("dry run = %+v, want one resolution and the copy left in place", dry)
+	}
+
+	_, output, err := handleResolveConflicts(context.Background(), nil, ResolveConflictsInput{Strategy: "trash"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(output.Resolutions) != 1 || output.Resolutions[0].Action != "trashed" {
+		t.Fatalf("resolutions = %+v, want one trashed copy", output.Resolutions)
+	}
+	trashed := filepath.Join(dir, filepath.FromSlash(output.Resolutions[0].TrashPath))
+	if got := readTestFile(t, trashed); got != "copy\n" {
+		t.Errorf("trashed copy = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2024-03-15 (conflicted copy).md")); !os.IsNotExist(err) {
+		t.Errorf("conflict copy still in place: %v", err)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "2024-03-15.md")); got != "main\n" {
+		t.Errorf("main entry changed to %q", got)
+	}
+}
+
+func TestResolveConflictMerge(t *testing.T) {
+	tests := []struct {
+		name       string
+		main, copy string
+		want       string
+		err        string
+	}{
+		{name: "copy adds lines", main: "# Friday\nran\n", copy: "# Friday\nran\ncalled mum\n", want: "# Friday\nran\ncalled mum\n"},
+		{name: "missing final newline is kept missing", main: "a\nfoo", copy: "a\nfoo\nbar", want: "a\nfoo\nbar"},
+		{name: "lines only main has are kept", main: "a\nkept\nb\n", copy: "a\nb\n", want: "a\nkept\nb\n"},
+		{name: "edits on both sides", main: "a\nfrom laptop\nb\nc\n", copy: "a\nb\nc\nfrom phone\n", want: "a\nfrom laptop\nb\nc\nfrom phone\n"},
+		{name: "both changed the same line", main: "a\nmain\n", copy: "a\ncopy\n", err: "both versions changed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := newTestVault(t, map[string]string{
+				"2024-03-15.md":                   tt.main,
+				"2024-03-15 (conflicted copy).md": tt.copy,
+			})
+
+			_, output, err := handleResolveConflict(context.Background(), nil, ResolveConflictInput{Date: "2024-03-15", Action: "merge"})
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want %q", err, tt.err)
+				}
+				if got := readTestFile(t, filepath.Join(dir, "2024-03-15.md")); got != tt.main {
+					t.Errorf("main entry changed to %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if output.Result != tt.want {
+				t.Errorf("result = %q, want %q", output.Result, tt.want)
+			}
+			if got := readTestFile(t, filepath.Join(dir, "2024-03-15.md")); got != tt.want {
+				t.Errorf("main entry = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveConflictsMergeSkipsUnsafeCopies(t *testing.T) {
+	big := strings.Repeat("line\n", maxDiffLines+1)
+	dir := newTestVault(t, map[string]string{
+		"2024-03-15.md":                           "a\nfrom laptop\nb\n",
+		"2024-03-15 (conflicted copy).md":         "a\nfrom phone\nb\n",
+		"2024-03-16.md":                           big,
+		"2024-03-16 (conflicted copy).md":         big + "extra\n",
+		"exports/2024-03-17 (conflicted copy).md": "exported\n",
+	})
+
+	_, output, err := handleResolveConflicts(context.Background(), nil, ResolveConflictsInput{Strategy: "merge"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Resolved != 0 || output.Skipped != 2 || len(output.Resolutions) != 2 {
+		t.Fatalf("resolved %d and skipped %d, want 0 and 2: %+v", output.Resolved, output.Skipped, output.Resolutions)
+	}
+	for _, r := range output.Resolutions {
+		if r.Action != "skipped" || r.Reason == "" {
+			t.Errorf("resolution = %+v, want skipped with a reason", r)
+		}
+	}
+
+	if got := readTestFile(t, filepath.Join(dir, "2024-03-15.md")); got != "a\nfrom laptop\nb\n" {
+		t.Errorf("overlapping edits changed the entry to %q", got)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "2024-03-16.md")); got != big {
+		t.Errorf("oversized entry changed, %d bytes instead of %d", len(got), len(big))
+	}
+	for _, name := range []string{"2024-03-15 (conflicted copy).md", "2024-03-16 (conflicted copy).md", "exports/2024-03-17 (conflicted copy).md"} {
+		if !fileExists(filepath.Join(dir, filepath.FromSlash(name))) {
+			t.Errorf("%s was removed", name)
+		}
+	}
+}
+
+func TestResolveConflictKeepsEncoding(t *testing.T) {
+	bom := "\xEF\xBB\xBF"
+	dir := newTestVault(t, map[string]string{
+		"2024-03-15.md":                   bom + "# Friday\nran\n",
+		"2024-03-15 (conflicted copy).md": "# Friday\nran\ncalled mum\n",
+	})
+
+	if _, _, err := handleResolveConflict(context.Background(), nil, ResolveConflictInput{Date: "2024-03-15", Action: "merge"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := readTestFile(t, filepath.Join(dir, "2024-03-15.md")), bom+"# Friday\nran\ncalled mum\n"; got != want {
+		t.Errorf("main entry = %q, want %q", got, want)
+	}
+}
+
+func TestResolveConflictsMergeKeepsBothSidesAndEncoding(t *testing.T) {
+	utf16 := func(text string) string { return string(encodeLike(utf16LEBOM, text)) }
+	dir := newTestVault(t, map[string]string{
+		"2024-03-15.md":                   utf16("# Friday\nfrom laptop\nran\n"),
+		"2024-03-15 (conflicted copy).md": "# Friday\nran\nfrom phone\n",
+	})
+
+	_, output, err := handleResolveConflicts(context.Background(), nil, ResolveConflictsInput{Strategy: "merge"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Resolved != 1 || output.Resolutions[0].Action != "merged" || output.Resolutions[0].AddedLines != 1 {
+		t.Fatalf("resolutions = %+v, want one merge adding 1 line", output.Resolutions)
+	}
+	if got, want := readTestFile(t, filepath.Join(dir, "2024-03-15.md")), utf16("# Friday\nfrom laptop\nran\nfrom phone\n"); got != want {
+		t.Errorf("main entry = % x, want the merge of both sides still in utf-16 % x", got, want)
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// new entries land in THEMIS_ENTRY_DIR, relative to the vault, or in the THEMIS_SCOPE folder when it is unset
+var entryDir = getEntryDir()
+
+func getEntryDir() string {
+	if dir := os.Getenv("THEMIS_ENTRY_DIR"); dir != "" {
+		return dir
+	}
+	return os.Getenv("THEMIS_SCOPE")
+}
+
+// THEMIS_TEMPLATES is either a single template path or comma separated weekday=path pairs,
+// e.g. "sunday=templates/weekly.md,default=templates/daily.md"
+var templates = getTemplates()
+
+type CreateEntryInput struct {
+	Date    string `json:"date,omitempty" jsonschema:"Entry date in YYYY-MM-DD format (default today)"`
+	Content string `json:"content,omitempty" jsonschema:"Entry content, the weekday's template is used when empty"`
+	Encrypt bool   `json:"encrypt,omitempty" jsonschema:"Store the entry age-encrypted as YYYY-MM-DD.md.age"`
+	DryRun  bool   `json:"dryRun,omitempty" jsonschema:"Report what would be created without writing any files"`
+}
+
+type CreatedEntry struct {
+	Date     string `json:"date" jsonschema:"Entry date in YYYY-MM-DD format"`
+	FilePath string `json:"path" jsonschema:"Full path of the created file"`
+	Template string `json:"template,omitempty" jsonschema:"Template the entry was created from"`
+}
+
+type CreateEntryOutput struct {
+	Entry  CreatedEntry `json:"entry" jsonschema:"The created entry"`
+	DryRun bool         `json:"dryRun" jsonschema:"Whether this was a dry run"`
+}
+
+type BackfillEntriesInput struct {
+	Dates   []string `json:"dates" jsonschema:"Dates to create skeleton entries for in YYYY-MM-DD format"`
+	Encrypt bool     `json:"encrypt,omitempty" jsonschema:"Store the entries age-encrypted as YYYY-MM-DD.md.age"`
+	DryRun  bool     `json:"dryRun,omitempty" jsonschema:"Report what would be created without writing any files"`
+}
+
+type BackfillEntriesOutput struct {
+	Created []CreatedEntry `json:"created" jsonschema:"Entries that were (or would be) created"`
+	Skipped []string       `json:"skipped" jsonschema:"Dates that already had an entry"`
+	DryRun  bool           `json:"dryRun" jsonschema:"Whether this was a dry run"`
+}
+
+// handlers
+func handleCreateEntry(ctx context.Context, req *mcp.CallToolRequest, input CreateEntryInput) (
+	*mcp.CallToolResult,
+	CreateEntryOutput,
+	error,
+) {
+	dateStr := input.Date
+	if dateStr == "" {
+		dateStr = now().In(location).Format("2006-01-02")
+	}
+	date, err := time.Parse("2006-01-02", dateStr)
+	if err != nil {
+		return nil, CreateEntryOutput{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", input.Date)
+	}
+
+	if _, err := getEntryByDate(dateStr); err == nil {
+		return nil, CreateEntryOutput{}, fmt.Errorf("an entry for %s already exists", dateStr)
+	}
+
+	created, content, err := prepareEntry(date, input.Encrypt, input.Content)
+	if err != nil {
+		return nil, CreateEntryOutput{}, err
+	}
+
+	if !input.DryRun {
+		if err := writeNewEntry(created.FilePath, content); err != nil {
+			return nil, CreateEntryOutput{}, err
+		}
+	}
+
+	return nil, CreateEntryOutput{Entry: created, DryRun: input.DryRun}, nil
+}
+
+func handleBackfillEntries(ctx context.Context, req *mcp.CallToolRequest, input BackfillEntriesInput) (
+	*mcp.CallToolResult,
+	BackfillEntriesOutput,
+	error,
+) {
+	if len(input.Dates) == 0 {
+		return nil, BackfillEntriesOutput{}, fmt.Errorf("at least one date is required")
+	}
+	if len(templates) == 0 {
+		return nil, BackfillEntriesOutput{}, fmt.Errorf("no templates configured, set THEMIS_TEMPLATES")
+	}
+
+	requested := make([]time.Time, len(input.Dates))
+	for i, dateStr := range input.Dates {
+		date, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			return nil, BackfillEntriesOutput{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", dateStr)
+		}
+		requested[i] = date
+	}
+
+	dates, err := listEntryDates()
+	if err != nil {
+		return nil, BackfillEntriesOutput{}, fmt.Errorf("failed to get entries: %w", err)
+	}
+	existing := map[string]bool{}
+	for _, date := range dates {
+		existing[date.Format("2006-01-02")] = true
+	}
+
+	output := BackfillEntriesOutput{DryRun: input.DryRun}
+	for _, date := range requested {
+		dateStr := date.Format("2006-01-02")
+		if existing[dateStr] {
+			output.Skipped = append(output.Skipped, dateStr)
+			continue
+		}
+
+		created, content, err := prepareEntry(date, input.Encrypt, "")
+		if err != nil {
+			return nil, BackfillEntriesOutput{}, err
+		}
+		if !input.DryRun {
+			if err := writeNewEntry(created.FilePath, content); err != nil {
+				return nil, BackfillEntriesOutput{}, err
+			}
+		}
+
+		existing[dateStr] = true
+		output.Created = append(output.Created, created)
+	}
+
+	return nil, output, nil
+}
+
+// helpers
+func getTemplates() map[string]string {
+	raw := strings.TrimSpace(os.Getenv("THEMIS_TEMPLATES"))
+	if raw == "" {
+		return nil
+	}
+	if !strings.Contains(raw, "=") {
+		return map[string]string{"default": raw}
+	}
+
+	result := map[string]string{}
+	for _, pair := range strings.Split(raw, ",") {
+		day, path, ok := strings.Cut(pair, "=")
+		day = strings.ToLower(strings.TrimSpace(day))
+		if !ok || (day != "default" && !isWeekdayName(day)) {
+			log.Fatalf("invalid THEMIS_TEMPLATES entry %q, expected weekday=path or default=path", pair)
+		}
+		result[day] = strings.TrimSpace(path)
+	}
+	return result
+}
+
+func isWeekdayName(name string) bool {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.ToLower(day.String()) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// templateFor picks the template configured for the date's weekday, falling back to the default
+func templateFor(date time.Time) string {
+	if path, ok := templates[strings.ToLower(date.Weekday().String())]; ok {
+		return path
+	}
+	return templates["default"]
+}
+
+// prepareEntry works out where a new entry for date goes and renders its template, which given content replaces
+// without the template being read
+func prepareEntry(date time.Time, encrypt bool, content string) (CreatedEntry, string, error) {
+	if encrypt && len(ageIdentities) == 0 {
+		return CreatedEntry{}, "", errNoIdentity
+	}
+
+	dateStr := date.Format("2006-01-02")
+	name := dateStr + ".md"
+	if encrypt {
+		name = dateStr + encryptedSuffix
+	}
+	path, err := resolveInVault(filepath.Join(entryDir, name))
+	if err != nil {
+		return CreatedEntry{}, "", err
+	}
+	created := CreatedEntry{
+		Date:     dateStr,
+		FilePath: path,
+	}
+	if content != "" {
+		return created, content, nil
+	}
+	created.Template = templateFor(date)
+	if created.Template == "" {
+		return created, "", nil
+	}
+
+	templatePath, err := resolveInVault(created.Template)
+	if err != nil {
+		return CreatedEntry{}, "", err
+	}
+	template, err := os.ReadFile(templatePath)
+	if err != nil {
+		return CreatedEntry{}, "", fmt.Errorf("failed to read template %s: %w", created.Template, err)
+	}
+
+	return created, renderTemplate(string(template), date), nil
+}
+
+func renderTemplate(template string, date time.Time) string {
+	return strings.NewReplacer(
+		"{{date}}", date.Format("2006-01-02"),
+		"{{title}}", date.Format("2006-01-02"),
+		"{{weekday}}", displayLocale.weekday(date.Weekday()),
+		"{{month}}", displayLocale.month(date.Month()),
+		"{{year}}", date.Format("2006"),
+	).Replace(template)
+}
+
+// writeNewEntry creates the entry file, refusing to replace one that appeared in the meantime
+func writeNewEntry(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+
+	data, err := encodeEntryFile(path, content)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt %s: %w", path, err)
+	}
+
+	return journal("create", path, data, func() error {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", path, err)
+		}
+		defer cache.invalidate(path)
+		if _, err := file.Write(data); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		return file.Close()
+	})
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// useTestTemplates configures templates for the rest of the test
+func useTestTemplates(t *testing.T, configured map[string]string) {
+	t.Helper()
+	old := templates
+	templates = configured
+	t.Cleanup(func() { templates = old })
+}
+
+// createVault has a friday entry and a weekday template next to the default one
+func createVault(t *testing.T) string {
+	t.Helper()
+	dir := newTestVault(t, map[string]string{
+		"2024-03-14.md":       "# Thursday\n",
+		"templates/daily.md":  "# {{weekday}} {{date}}\n",
+		"templates/weekly.md": "# Week of {{month}} {{year}}\n",
+	})
+	useTestTemplates(t, map[string]string{"sunday": "templates/weekly.md", "default": "templates/daily.md"})
+	return dir
+}
+
+func TestCreateEntry(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     CreateEntryInput
+		templates map[string]string
+		template  string
+		content   string
+	}{
+		{
+			name:     "default template",
+			input:    CreateEntryInput{Date: "2024-03-15"},
+			template: "templates/daily.md",
+			content:  "# Friday 2024-03-15\n",
+		},
+		{
+			name:     "weekday template",
+			input:    CreateEntryInput{Date: "2024-03-17"},
+			template: "templates/weekly.md",
+			content:  "# Week of March 2024\n",
+		},
+		{
+			name:    "content replaces the template",
+			input:   CreateEntryInput{Date: "2024-03-15", Content: "written by hand\n"},
+			content: "written by hand\n",
+		},
+		{
+			name:      "content needs no readable template",
+			input:     CreateEntryInput{Date: "2024-03-15", Content: "written by hand\n"},
+			templates: map[string]string{"default": "templates/missing.md"},
+			content:   "written by hand\n",
+		},
+		{
+			name:      "no template configured",
+			input:     CreateEntryInput{Date: "2024-03-15"},
+			templates: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := createVault(t)
+			if tt.templates != nil {
+				useTestTemplates(t, tt.templates)
+			}
+
+			_, output, err := handleCreateEntry(context.Background(), nil, tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(dir, tt.input.Date+".md")
+			if want := (CreatedEntry{Date: tt.input.Date, FilePath: path, Template: tt.template}); output.Entry != want {
+				t.Errorf("entry = %+v, want %+v", output.Entry, want)
+			}
+			if got := readTestFile(t, path); got != tt.content {
+				t.Errorf("content = %q, want %q", got, tt.content)
+			}
+		})
+	}
+}
+
+func TestCreateEntryRefuses(t *testing.T) {
+	dir := createVault(t)
+	ctx := context.Background()
+
+	if _, _, err := handleCreateEntry(ctx, nil, CreateEntryInput{Date: "2024-03-14"}); err == nil {
+		t.Error("created a second entry for 2024-03-14")
+	}
+	if _, _, err := handleCreateEntry(ctx, nil, CreateEntryInput{Date: "14.03.2024"}); err == nil {
+		t.Error("accepted a date that is not YYYY-MM-DD")
+	}
+
+	_, output, err := handleCreateEntry(ctx, nil, CreateEntryInput{Date: "2024-03-16", DryRun: true})
+	if err != nil || !output.DryRun || output.Entry.Date != "2024-03-16" {
+		t.Errorf("dry run = %+v, %v", output, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2024-03-16.md")); !os.IsNotExist(err) {
+		t.Errorf("the dry run wrote the entry: %v", err)
+	}
+
+	useTestTemplates(t, map[string]string{"default": "templates/missing.md"})
+	if _, _, err := handleCreateEntry(ctx, nil, CreateEntryInput{Date: "2024-03-15"}); err == nil {
+		t.Error("created an entry from a missing template")
+	}
+	if readTestFile(t, filepath.Join(dir, "2024-03-14.md")) != "# Thursday\n" {
+		t.Error("the existing entry was changed")
+	}
+}
+
+func TestBackfillEntries(t *testing.T) {
+	dir := createVault(t)
+	ctx := context.Background()
+	input := BackfillEntriesInput{Dates: []string{"2024-03-14", "2024-03-16", "2024-03-17", "2024-03-16"}}
+
+	_, output, err := handleBackfillEntries(ctx, nil, BackfillEntriesInput{Dates: input.Dates, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !output.DryRun || len(output.Created) != 2 {
+		t.Errorf("dry run = %+v", output)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2024-03-16.md")); !os.IsNotExist(err) {
+		t.Errorf("the dry run wrote an entry: %v", err)
+	}
+
+	// a date given twice is created once, like one that already has an entry
+	_, output, err = handleBackfillEntries(ctx, nil, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var created []string
+	for _, entry := range output.Created {
+		created = append(created, entry.Date)
+	}
+	if !reflect.DeepEqual(created, []string{"2024-03-16", "2024-03-17"}) || !reflect.DeepEqual(output.Skipped, []string{"2024-03-14", "2024-03-16"}) {
+		t.Errorf("created %v, skipped %v", created, output.Skipped)
+	}
+	for date, want := range map[string]string{"2024-03-16": "# Saturday 2024-03-16\n", "2024-03-17": "# Week of March 2024\n"} {
+		if got := readTestFile(t, filepath.Join(dir, date+".md")); got != want {
+			t.Errorf("%s = %q, want %q", date, got, want)
+		}
+	}
+
+	if _, _, err := handleBackfillEntries(ctx, nil, BackfillEntriesInput{Dates: []string{"2024-13-01"}}); err == nil {
+		t.Error("accepted an invalid date")
+	}
+	useTestTemplates(t, nil)
+	if _, _, err := handleBackfillEntries(ctx, nil, BackfillEntriesInput{Dates: []string{"2024-03-18"}}); err == nil {
+		t.Error("backfilled without a template")
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+)
+
+const encryptedSuffix = ".md.age"
+
+var errNoIdentity = errors.New("no age identity configured, set THEMIS_AGE_IDENTITY")
+
+// identities from the THEMIS_AGE_IDENTITY file, nil when encrypted entries stay locked
+var ageIdentities = getAgeIdentities()
+
+func getAgeIdentities() []age.Identity {
+	path := os.Getenv("THEMIS_AGE_IDENTITY")
+	if path == "" {
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("failed to open age identity: %v", err)
+	}
+	defer file.Close()
+
+	identities, err := age.ParseIdentities(file)
+	if err != nil {
+		log.Fatalf("failed to parse age identity %s: %v", path, err)
+	}
+	return identities
+}
+
+func decryptEntry(ciphertext []byte) ([]byte, error) {
+	if len(ageIdentities) == 0 {
+		return nil, errNoIdentity
+	}
+
+	reader, err := age.Decrypt(bytes.NewReader(ciphertext), ageIdentities...)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(reader)
+}
+
+// encryptEntry encrypts to the recipients of the configured x25519 identities
+func encryptEntry(plaintext []byte) ([]byte, error) {
+	var recipients []age.Recipient
+	for _, identity := range ageIdentities {
+		if x, ok := identity.(*age.X25519Identity); ok {
+			recipients = append(recipients, x.Recipient())
+		}
+	}
+	if len(recipients) == 0 {
+		if len(ageIdentities) == 0 {
+			return nil, errNoIdentity
+		}
+		return nil, fmt.Errorf("the configured age identity has no x25519 recipient to encrypt to")
+	}
+
+	var buf bytes.Buffer
+	writer, err := age.Encrypt(&buf, recipients...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(plaintext); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeEntryFile turns entry text into the bytes stored at path, encrypting .md.age files
+func encodeEntryFile(path, content string) ([]byte, error) {
+	if !strings.HasSuffix(path, encryptedSuffix) {
+		return []byte(content), nil
+	}
+	return encryptEntry([]byte(content))
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+)
+
+// useTestIdentity swaps in a fresh age identity for the rest of the test, nil leaves the server without one
+func useTestIdentity(t *testing.T, identity *age.X25519Identity) {
+	t.Helper()
+	old := ageIdentities
+	ageIdentities = nil
+	if identity != nil {
+		ageIdentities = []age.Identity{identity}
+	}
+	t.Cleanup(func() { ageIdentities = old })
+}
+
+func newTestIdentity(t *testing.T) *age.X25519Identity {
+	t.Helper()
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return identity
+}
+
+// encryptedTestVault holds one entry encrypted to identity
+func encryptedTestVault(t *testing.T, identity *age.X25519Identity, content string) string {
+	t.Helper()
+	dir := newTestVault(t, nil)
+	useTestIdentity(t, identity)
+	data, err := encryptEntry([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(dir, "2024-03-15"+encryptedSuffix), string(data))
+	return dir
+}
+
+func TestEncryptedEntryDecrypts(t *testing.T) {
+	encryptedTestVault(t, newTestIdentity(t), "---\nmood: calm\n---\nsecret #therapy\n")
+
+	entry, err := getEntryByDate("2024-03-15")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !entry.Encrypted || entry.Locked || entry.Content != "---\nmood: calm\n---\nsecret #therapy\n" {
+		t.Errorf("entry = %+v, want decrypted content", entry)
+	}
+	if entry.Frontmatter["mood"] != "calm" || entry.Warnings != nil {
+		t.Errorf("frontmatter = %v, warnings = %v", entry.Frontmatter, entry.Warnings)
+	}
+}
+
+func TestEncryptedEntryWithoutIdentityIsLocked(t *testing.T) {
+	encryptedTestVault(t, newTestIdentity(t), "secret\n")
+	useTestIdentity(t, nil)
+
+	entry, err := getEntryByDate("2024-03-15")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a missing identity is expected, so the entry is metadata only without a warning
+	if !entry.Encrypted || !entry.Locked || entry.Content != "" || entry.Warnings != nil {
+		t.Errorf("entry = %+v, want locked metadata without content or warnings", entry)
+	}
+}
+
+func TestEncryptedEntryWrongIdentityWarns(t *testing.T) {
+	encryptedTestVault(t, newTestIdentity(t), "secret\n")
+	useTestIdentity(t, newTestIdentity(t))
+
+	_, output, err := handleGetEntriesByYear(context.Background(), nil, GetEntriesByYearInput{Year: 2024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(output.Entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(output.Entries))
+	}
+	entry := output.Entries[0]
+	if !entry.Locked || entry.Content != "" {
+		t.Errorf("entry = %+v, want locked without ciphertext", entry)
+	}
+	if len(entry.Warnings) != 1 || !strings.HasPrefix(entry.Warnings[0], "failed to decrypt: ") {
+		t.Errorf("warnings = %v, want a decrypt failure", entry.Warnings)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// THEMIS_AUTO_CREATE_DAILY=true (or --auto-create-daily) creates today's entry on startup and at every local midnight
+var autoCreateDaily = os.Getenv("THEMIS_AUTO_CREATE_DAILY") == "true"
+
+// THEMIS_SKIP_WEEKDAYS lists days the daily entry is not created on, e.g. "saturday,sunday"
+var skipWeekdays = getSkipWeekdays()
+
+func getSkipWeekdays() map[time.Weekday]bool {
+	skip := map[time.Weekday]bool{}
+	raw := strings.TrimSpace(os.Getenv("THEMIS_SKIP_WEEKDAYS"))
+	if raw == "" {
+		return skip
+	}
+
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		found := false
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			if strings.ToLower(day.String()) == name {
+				skip[day], found = true, true
+			}
+		}
+		if !found {
+			log.Fatalf("invalid THEMIS_SKIP_WEEKDAYS entry %q, expected a weekday name", name)
+		}
+	}
+	return skip
+}
+
+// startDailyRollover runs until ctx is done, the returned channel closes once it has stopped
+func startDailyRollover(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			ensureDailyEntry(now())
+
+			_, midnight := dayBounds(now())
+			timer := time.NewTimer(midnight.Sub(now()))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	}()
+	return done
+}
+
+// ensureDailyEntry creates the entry for t's day in the configured timezone unless it exists or the weekday is skipped
+func ensureDailyEntry(t time.Time) {
+	local := t.In(location)
+	if skipWeekdays[local.Weekday()] {
+		return
+	}
+
+	date := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+	dateStr := date.Format("2006-01-02")
+	if _, err := getEntryByDate(dateStr); err == nil {
+		return
+	}
+
+	created, content, err := prepareEntry(date, false, "")
+	if err != nil {
+		log.Printf("failed to prepare daily entry for %s: %v", dateStr, err)
+		return
+	}
+	if err := writeNewEntry(created.FilePath, content); err != nil {
+		// obsidian may have created the note between the check and the write
+		if !errors.Is(err, os.ErrExist) {
+			log.Printf("failed to create daily entry for %s: %v", dateStr, err)
+		}
+		return
+	}
+	log.Printf("created daily entry %s", vaultRelative(created.FilePath))
+}
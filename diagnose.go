@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	diagnoseSampleEntries = 5
+	diagnoseWalkBudget    = 1500 * time.Millisecond
+	diagnoseWatchWait     = 500 * time.Millisecond
+)
+
+type DiagnoseInput struct{}
+
+type DiagnosticCheck struct {
+	Name   string `json:"name" jsonschema:"What was checked"`
+	Status string `json:"status" jsonschema:"pass, warn, fail or skip"`
+	Detail string `json:"detail" jsonschema:"What was found"`
+	Hint   string `json:"hint,omitempty" jsonschema:"How to fix a failing or suspicious check"`
+}
+
+type DiagnoseOutput struct {
+	OK     bool              `json:"ok" jsonschema:"Whether no check failed"`
+	Checks []DiagnosticCheck `json:"checks" jsonschema:"Results in the order they ran"`
+}
+
+// handlers
+func handleDiagnose(ctx context.Context, req *mcp.CallToolRequest, input DiagnoseInput) (
+	*mcp.CallToolResult,
+	DiagnoseOutput,
+	error,
+) {
+	return nil, diagnose(false), nil
+}
+
+// helpers
+
+// diagnose checks the setup behind an empty or surprising result. it samples the vault instead of walking all of it
+// so it finishes in a couple of seconds on any vault size. only probe lets it write to the vault.
+func diagnose(probe bool) DiagnoseOutput {
+	var checks []DiagnosticCheck
+
+	vault := DiagnosticCheck{Name: "vault", Status: "pass", Detail: themisPath}
+	if info, err := os.Stat(themisPath); errors.Is(err, fs.ErrPermission) {
+		vault.Status, vault.Detail = "fail", err.Error()
+		vault.Hint = "give the server user permission to reach the vault through its parent folders"
+	} else if err != nil {
+		vault.Status, vault.Detail = "fail", err.Error()
+		vault.Hint = "create the vault directory or symlink your journal folder to " + themisPath
+	} else if !info.IsDir() {
+		vault.Status, vault.Detail = "fail", themisPath+" is not a directory"
+		vault.Hint = "point " + themisPath + " at the folder holding your entries"
+	} else if _, err := os.ReadDir(themisPath); err != nil {
+		vault.Status, vault.Detail = "fail", err.Error()
+		vault.Hint = "give the server user read permission on the vault"
+	}
+	checks = append(checks, vault)
+	if vault.Status == "fail" {
+		return finishDiagnose(checks)
+	}
+
+	checks = append(checks, sampleEntriesCheck())
+	var seen int64
+	if activeWatcher != nil {
+		seen = activeWatcher.events.Load()
+	}
+	write, probed := writeCheck(probe)
+	checks = append(checks, write, watcherCheck(probed, seen), indexCheck(), walCheck(), timezoneCheck())
+	return finishDiagnose(checks)
+}
+
+func finishDiagnose(checks []DiagnosticCheck) DiagnoseOutput {
+	output := DiagnoseOutput{OK: true, Checks: checks}
+	for _, check := range checks {
+		if check.Status == "fail" {
+			output.OK = false
+		}
+	}
+	return output
+}
+
+// sampleEntriesCheck looks for the first few files walkEntries would accept, giving up after a time budget.
+// the budget runs on the wall clock, a pinned clock would never let it expire.
+func sampleEntriesCheck() DiagnosticCheck {
+	var samples, misnamed []string
+	deadline := wallNow().Add(diagnoseWalkBudget)
+	filepath.WalkDir(entryRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || wallNow().After(deadline) || len(samples) >= diagnoseSampleEntries {
+			return filepath.SkipAll
+		}
+		if d.IsDir() {
+			if path != entryRoot && strings.HasPrefix(d.Name(), ".") || isExportsDir(path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(d.Name(), ".md") && !strings.HasSuffix(d.Name(), encryptedSuffix) {
+			return nil
+		}
+		dateStr := strings.TrimSuffix(strings.TrimSuffix(d.Name(), ".age"), ".md")
+		if _, err := time.Parse("2006-01-02", dateStr); err != nil {
+			if len(misnamed) < diagnoseSampleEntries {
+				misnamed = append(misnamed, vaultRelative(path))
+			}
+			return nil
+		}
+		samples = append(samples, vaultRelative(path))
+		return nil
+	})
+
+	check := DiagnosticCheck{Name: "entries", Status: "pass", Detail: "found " + strings.Join(samples, ", ")}
+	if len(samples) == 0 {
+		check.Status, check.Detail = "fail", "no YYYY-MM-DD.md files found"
+		check.Hint = "entries must be named like 2024-03-15.md"
+		if len(misnamed) > 0 {
+			check.Detail += ", markdown files with other names: " + strings.Join(misnamed, ", ")
+		}
+	}
+	return check
+}
+
+// writeCheck creates and removes a temp file in the vault when probe is set, reporting whether it did so the watcher
+// check can look for it
+func writeCheck(probe bool) (DiagnosticCheck, bool) {
+	if !probe {
+		return DiagnosticCheck{Name: "write", Status: "skip", Detail: "not probed, themis --doctor --repair creates and removes a temp file in the vault"}, false
+	}
+	check := DiagnosticCheck{Name: "write", Status: "pass", Detail: "created and removed a temp file in the vault"}
+	file, err := os.CreateTemp(themisPath, ".themis-diagnose-*.md")
+	if err != nil {
+		check.Status, check.Detail = "fail", err.Error()
+		check.Hint = "write tools need write permission on the vault, read tools keep working without it"
+		return check, false
+	}
+	file.Close()
+	if err := os.Remove(file.Name()); err != nil {
+		check.Status, check.Detail = "warn", "could not remove "+file.Name()+": "+err.Error()
+	}
+	return check, true
+}
+
+// watcherCheck waits briefly for the write probe to show up as events beyond the seen ones
+func watcherCheck(probed bool, seen int64) DiagnosticCheck {
+	check := DiagnosticCheck{Name: "watcher", Status: "skip", Detail: "file watching is off"}
+	if activeWatcher == nil {
+		if watchEnabled {
+			check.Status, check.Detail = "fail", "THEMIS_WATCH is set but no watcher is running"
+			check.Hint = "check the server log for watcher errors, e.g. the inotify watch limit"
+		}
+		return check
+	}
+	if !probed {
+		check.Detail = "no write probe to observe"
+		return check
+	}
+
+	// like the walk budget, the wait is real time whatever the clock is pinned to
+	deadline := wallNow().Add(diagnoseWatchWait)
+	for activeWatcher.events.Load() == seen && wallNow().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if activeWatcher.events.Load() == seen {
+		check.Status, check.Detail = "fail", "no file events received"
+		check.Hint = "raise fs.inotify.max_user_watches or turn THEMIS_WATCH off"
+		return check
+	}
+	check.Status, check.Detail = "pass", fmt.Sprintf("saw the write probe, %d file events so far", activeWatcher.events.Load())
+	return check
+}
+
+// indexCheck reports the entry index, or the length index when none is configured, without refreshing it,
+// which could mean reading every entry
+func indexCheck() DiagnosticCheck {
+	if vaultIndex.enabled() {
+		entries, stale, err := vaultIndex.freshness()
+		switch {
+		case err != nil:
+			return DiagnosticCheck{Name: "index", Status: "warn", Detail: fmt.Sprintf("%s can't be used: %v", vaultIndex.path, err),
+				Hint: "it is rebuilt from the entries on the next query, or delete it to start over"}
+		case stale > 0:
+			return DiagnosticCheck{Name: "index", Status: "pass", Detail: fmt.Sprintf("%d entries indexed in %s, %d files changed since and are read again on the next query", entries, vaultIndex.path, stale)}
+		}
+		return DiagnosticCheck{Name: "index", Status: "pass", Detail: fmt.Sprintf("%d entries indexed in %s, up to date", entries, vaultIndex.path)}
+	}
+
+	lengths.mu.Lock()
+	indexed, built := len(lengths.files), lengths.sorted != nil
+	lengths.mu.Unlock()
+
+	if !built {
+		return DiagnosticCheck{Name: "index", Status: "skip", Detail: fmt.Sprintf("%d files counted, distribution not built yet", indexed),
+			Hint: "it is built on the first request returning percentiles"}
+	}
+	return DiagnosticCheck{Name: "index", Status: "pass", Detail: fmt.Sprintf("%d files indexed, rebuilt on the next request after any change", indexed)}
+}
+
+func timezoneCheck() DiagnosticCheck {
+	check := DiagnosticCheck{Name: "timezone", Status: "pass", Detail: fmt.Sprintf("%s, today is %s", location, now().In(location).Format("2006-01-02 15:04"))}
+	if os.Getenv("THEMIS_TIMEZONE") == "" {
+		check.Status = "warn"
+		check.Hint = "THEMIS_TIMEZONE is unset so the system timezone decides where days start, set it to an IANA name like Europe/Berlin"
+	}
+	return check
+}
+
+// runDoctor prints the diagnosis for the --doctor cli mode, reporting whether every check passed. it only reports
+// interrupted writes unless repair asks to replay them first, as the server does at startup, and only probes
+// writing with repair.
+func runDoctor(w io.Writer, repair bool) bool {
+	if repair {
+		recoverWAL()
+	}
+	output := diagnose(repair)
+	for _, check := range output.Checks {
+		fmt.Fprintf(w, "%-5s %-9s %s\n", strings.ToUpper(check.Status), check.Name, check.Detail)
+		if check.Hint != "" {
+			fmt.Fprintf(w, "      %s\n", check.Hint)
+		}
+	}
+	return output.OK
+}
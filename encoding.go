@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+)
+
+// decodeContent turns raw file bytes into utf-8 text, stripping a utf-8 bom and transcoding utf-16 files that start with a bom
+func decodeContent(data []byte) (string, string) {
+	switch {
+	case bytes.HasPrefix(data, utf8BOM):
+		return string(data[len(utf8BOM):]), ""
+	case bytes.HasPrefix(data, utf16LEBOM):
+		return decodeUTF16(data[len(utf16LEBOM):], binary.LittleEndian)
+	case bytes.HasPrefix(data, utf16BEBOM):
+		return decodeUTF16(data[len(utf16BEBOM):], binary.BigEndian)
+	}
+
+	if !utf8.Valid(data) {
+		return string(data), "content is not valid utf-8"
+	}
+	return string(data), ""
+}
+
+func decodeUTF16(data []byte, order binary.ByteOrder) (string, string) {
+	var warning string
+	if len(data)%2 != 0 {
+		warning = "utf-16 content has an odd number of bytes, the last byte was dropped"
+		data = data[:len(data)-1]
+	}
+
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[i*2:])
+	}
+
+	return string(utf16.Decode(units)), warning
+}
+
+// encodeLike turns content back into the encoding of original, the raw bytes decodeContent read it from, so a
+// rewritten entry keeps its bom and a utf-16 file stays utf-16
+func encodeLike(original []byte, content string) []byte {
+	switch {
+	case bytes.HasPrefix(original, utf8BOM):
+		return append(append([]byte{}, utf8BOM...), content...)
+	case bytes.HasPrefix(original, utf16LEBOM):
+		return encodeUTF16(utf16LEBOM, content, binary.LittleEndian)
+	case bytes.HasPrefix(original, utf16BEBOM):
+		return encodeUTF16(utf16BEBOM, content, binary.BigEndian)
+	}
+	return []byte(content)
+}
+
+func encodeUTF16(bom []byte, content string, order binary.ByteOrder) []byte {
+	units := utf16.Encode([]rune(content))
+	data := make([]byte, len(bom)+len(units)*2)
+	copy(data, bom)
+	for i, unit := range units {
+		order.PutUint16(data[len(bom)+i*2:], unit)
+	}
+	return data
+}
+
+// checkContentEncoding validates the contentEncoding option of read tools, empty means plain
+func checkContentEncoding(encoding string) error {
+	if encoding != "" && encoding != "plain" && encoding != "base64" {
+		return fmt.Errorf("unknown contentEncoding %q, expected plain or base64", encoding)
+	}
+	return nil
+}
+
+// encodeContent base64 encodes each entry's content for clients whose json handling mangles unusual characters,
+// keeping even invalid utf-8 byte for byte. contentEncoding is set so the client knows to decode.
+func encodeContent(entries []Entry, encoding string) {
+	if encoding != "base64" {
+		return
+	}
+	for i := range entries {
+		if entries[i].Content != "" {
+			entries[i].Content = base64.StdEncoding.EncodeToString([]byte(entries[i].Content))
+			entries[i].ContentEncoding = "base64"
+		}
+	}
+}
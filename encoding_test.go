@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    string
+		warning string
+	}{
+		{"plain", []byte("# Größe\n"), "# Größe\n", ""},
+		{"utf-8 bom", append([]byte{0xEF, 0xBB, 0xBF}, "# Größe\n"...), "# Größe\n", ""},
+		{"utf-16le", []byte{0xFF, 0xFE, 'G', 0, 0xF6, 0, '\n', 0}, "Gö\n", ""},
+		{"utf-16be", []byte{0xFE, 0xFF, 0, 'G', 0, 0xF6, 0, '\n'}, "Gö\n", ""},
+		{"utf-16 surrogate pair", []byte{0xFF, 0xFE, 0x3D, 0xD8, 0x00, 0xDE}, "😀", ""},
+		{"odd utf-16", []byte{0xFF, 0xFE, 'G', 0, 'x'}, "G", "utf-16 content has an odd number of bytes, the last byte was dropped"},
+		{"invalid utf-8", []byte{'a', 0xC3, 'b'}, "a\xc3b", "content is not valid utf-8"},
+	}
+	for _, tt := range tests {
+		got, warning := decodeContent(tt.data)
+		if got != tt.want || warning != tt.warning {
+			t.Errorf("%s: got %q with warning %q, want %q with %q", tt.name, got, warning, tt.want, tt.warning)
+		}
+	}
+}
+
+func TestUTF16EntryRead(t *testing.T) {
+	dir := newTestVault(t, nil)
+	// saved as utf-16le with a bom, the way some windows editors do
+	text := "---\ntags: [trip]\n---\nbody\n"
+	data := []byte{0xFF, 0xFE}
+	for _, r := range text {
+		data = append(data, byte(r), 0)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "2024-03-15.md"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, output, err := handleGetEntriesByYear(context.Background(), nil, GetEntriesByYearInput{Year: 2024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(output.Entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(output.Entries))
+	}
+	entry := output.Entries[0]
+	if entry.Content != text || entry.Warnings != nil || !reflect.DeepEqual(entry.Frontmatter["tags"], []any{"trip"}) {
+		t.Errorf("entry = %+v", entry)
+	}
+}
+
+func TestBase64ContentRoundTrip(t *testing.T) {
+	// characters json handling tends to mangle: nul, line separators, escapes, crlf, an astral emoji and invalid utf-8
+	raw := "# Friday \"quoted\" \\ path\r\nnul \x00 sep \u2028 para \u2029 😀 bad \xc3\x28 end\n"
+	dir := newTestVault(t, nil)
+	if err := os.WriteFile(filepath.Join(dir, "2024-03-15.md"), []byte(raw), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	_, output, err := handleGetEntriesByYear(ctx, nil, GetEntriesByYearInput{Year: 2024, ContentEncoding: "base64"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var received EntriesOutput
+	if err := json.Unmarshal(data, &received); err != nil {
+		t.Fatal(err)
+	}
+	if len(received.Entries) != 1 || received.Entries[0].ContentEncoding != "base64" {
+		t.Fatalf("entries = %+v, want one base64 entry", received.Entries)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(received.Entries[0].Content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decoded) != raw {
+		t.Errorf("decoded = %q, want %q", decoded, raw)
+	}
+
+	// plain is the default and leaves the encoding flag off
+	_, output, err = handleGetEntriesByYear(ctx, nil, GetEntriesByYearInput{Year: 2024, ContentEncoding: "plain"})
+	if err != nil || output.Entries[0].ContentEncoding != "" || output.Entries[0].Content != raw {
+		t.Errorf("plain entry = %+v, %v", output.Entries, err)
+	}
+	if _, _, err := handleGetEntriesByYear(ctx, nil, GetEntriesByYearInput{Year: 2024, ContentEncoding: "hex"}); err == nil {
+		t.Error("unknown contentEncoding accepted")
+	}
+}
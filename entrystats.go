@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+var taskPattern = regexp.MustCompile(`^[ \t]*(?:[-*+]|\d+[.)])[ \t]+\[([ xX])\]`)
+
+type GetEntryStatsInput struct {
+	Date string `json:"date" jsonschema:"Entry date in YYYY-MM-DD format"`
+}
+
+type EntryStatsOutput struct {
+	Date           string   `json:"date" jsonschema:"Entry date in YYYY-MM-DD format"`
+	WordCount      int      `json:"wordCount" jsonschema:"Words in the body, counted like everywhere else in the server"`
+	CharCount      int      `json:"charCount" jsonschema:"Characters in the body, frontmatter not counted"`
+	LineCount      int      `json:"lineCount" jsonschema:"Lines in the body, frontmatter not counted"`
+	ReadingMinutes float64  `json:"readingMinutes" jsonschema:"Estimated reading time in minutes"`
+	Tags           []string `json:"tags" jsonschema:"Frontmatter and inline tags, in order of first appearance"`
+	OutgoingLinks  []string `json:"outgoingLinks" jsonschema:"Wikilink and markdown link targets, embeds excluded, in order of first appearance"`
+	TodoCount      int      `json:"todoCount" jsonschema:"Open - [ ] tasks"`
+	DoneCount      int      `json:"doneCount" jsonschema:"Completed - [x] tasks"`
+}
+
+// handlers
+func handleGetEntryStats(ctx context.Context, req *mcp.CallToolRequest, input GetEntryStatsInput) (
+	*mcp.CallToolResult,
+	EntryStatsOutput,
+	error,
+) {
+	entry, err := getEntryByDate(input.Date)
+	if err != nil {
+		return nil, EntryStatsOutput{}, err
+	}
+	if entry.Locked {
+		return nil, EntryStatsOutput{}, fmt.Errorf("entry %s is encrypted and could not be decrypted", entry.Date)
+	}
+
+	_, body, _ := splitFrontmatter(entry.Content)
+	output := EntryStatsOutput{
+		Date:           entry.Date,
+		WordCount:      entry.WordCount,
+		CharCount:      utf8.RuneCountInString(body),
+		ReadingMinutes: entry.ReadingTimeMinutes,
+		Tags:           extractTags(entry.Frontmatter, body),
+		OutgoingLinks:  outgoingLinks(body),
+	}
+	if output.Tags == nil {
+		output.Tags = []string{}
+	}
+	if trimmed := strings.TrimRight(body, "\r\n"); trimmed != "" {
+		output.LineCount = strings.Count(trimmed, "\n") + 1
+	}
+
+	for _, task := range entryTasks(body) {
+		if task.done {
+			output.DoneCount++
+		} else {
+			output.TodoCount++
+		}
+	}
+
+	return nil, output, nil
+}
+
+// helpers
+
+type task struct {
+	text string
+	done bool
+}
+
+// entryTasks lists the - [ ] and - [x] checklist items of a body outside fenced code, in document order
+func entryTasks(body string) []task {
+	var tasks []task
+	inFence := false
+	for _, line := range strings.Split(body, "\n") {
+		if isFenceLine(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		if match := taskPattern.FindStringSubmatchIndex(line); match != nil {
+			tasks = append(tasks, task{
+				text: strings.TrimSpace(line[match[1]:]),
+				done: line[match[2]:match[3]] != " ",
+			})
+		}
+	}
+	return tasks
+}
+
+// outgoingLinks lists wikilink and markdown link targets outside fenced code, without embeds or anchors
+func outgoingLinks(body string) []string {
+	links := []string{}
+	seen := map[string]bool{}
+	add := func(target string) {
+		target = strings.TrimSpace(target)
+		if target == "" || seen[target] {
+			return
+		}
+		seen[target] = true
+		links = append(links, target)
+	}
+
+	inFence := false
+	for _, line := range strings.Split(body, "\n") {
+		if isFenceLine(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		for _, match := range wikilinkPattern.FindAllStringSubmatch(line, -1) {
+			if match[1] == "" {
+				add(strings.TrimSuffix(strings.TrimSpace(match[2]), ".md"))
+			}
+		}
+		for _, match := range markdownImagePattern.FindAllStringSubmatch(line, -1) {
+			if match[1] == "" && !strings.HasPrefix(match[2], "#") {
+				add(strings.Trim(match[2], "<>"))
+			}
+		}
+	}
+	return links
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestGetEntryStats(t *testing.T) {
+	newTestVault(t, map[string]string{
+		"2024-03-15.md": "---\ntags: [travel]\nmood: 4\n---\n# Friday #trip\nWent to [[Vienna]] for café and [[People/Alice|Alice]].\n![[photo.jpg]]\n" +
+			"- [ ] pack\n- [x] book train\n* [X] call mum\n1. [ ] visa\n```\n- [ ] not a task [[Fenced]]\n```\nsee [notes](notes.md), [top](#top) and [[Vienna]] again\n",
+		"2024-03-16.md": "",
+	})
+	ctx := context.Background()
+
+	// counts leave out the frontmatter, links skip embeds, anchors and fenced code, characters are runes
+	_, output, err := handleGetEntryStats(ctx, nil, GetEntryStatsInput{Date: "2024-03-15"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := EntryStatsOutput{
+		Date:           "2024-03-15",
+		WordCount:      24,
+		CharCount:      233,
+		LineCount:      11,
+		ReadingMinutes: 0.1,
+		Tags:           []string{"travel", "trip"},
+		OutgoingLinks:  []string{"Vienna", "People/Alice", "notes.md"},
+		TodoCount:      2,
+		DoneCount:      2,
+	}
+	if !reflect.DeepEqual(output, want) {
+		t.Errorf("stats = %+v, want %+v", output, want)
+	}
+
+	_, output, err = handleGetEntryStats(ctx, nil, GetEntryStatsInput{Date: "2024-03-16"})
+	if err != nil || output.LineCount != 0 || output.Tags == nil || output.OutgoingLinks == nil {
+		t.Errorf("empty entry = %+v, %v", output, err)
+	}
+
+	if _, _, err := handleGetEntryStats(ctx, nil, GetEntryStatsInput{Date: "2024-03-17"}); err == nil || err.Error() != "no entry found for 2024-03-17" {
+		t.Errorf("missing date: err = %v", err)
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+var wikilinkPattern = regexp.MustCompile(`(!?)\[\[([^\]|#^]*)([#^][^\]|]*)?(?:\|([^\]]*))?\]\]`)
+
+type ExportHTMLInput struct {
+	Start     string `json:"start,omitempty" jsonschema:"First date to export in YYYY-MM-DD format"`
+	End       string `json:"end,omitempty" jsonschema:"Last date to export in YYYY-MM-DD format"`
+	Mode      string `json:"mode,omitempty" jsonschema:"site (default) writes a static site under exports/html, document returns a single html page instead"`
+	Overwrite bool   `json:"overwrite,omitempty" jsonschema:"Replace an existing export directory"`
+}
+
+type ExportHTMLOutput struct {
+	OutputDir string `json:"outputDir,omitempty" jsonschema:"Directory the site was written to"`
+	Pages     int    `json:"pages" jsonschema:"Number of html pages written, or entries rendered in document mode"`
+	HTML      string `json:"html,omitempty" jsonschema:"The rendered document in document mode"`
+}
+
+// handlers
+func handleExportHTML(ctx context.Context, req *mcp.CallToolRequest, input ExportHTMLInput) (
+	*mcp.CallToolResult,
+	ExportHTMLOutput,
+	error,
+) {
+	var output ExportHTMLOutput
+	var err error
+	switch input.Mode {
+	case "", "site":
+		output, err = exportSite(ctx, input.Start, input.End, input.Overwrite)
+	case "document":
+		output, err = exportDocument(ctx, input.Start, input.End)
+	default:
+		err = fmt.Errorf("unknown mode %q, expected site or document", input.Mode)
+	}
+	if err != nil {
+		return nil, ExportHTMLOutput{}, err
+	}
+	return nil, output, nil
+}
+
+// runExport implements the `themis export` subcommand
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", "html", "export format, only html is supported")
+	start := flags.String("start", "", "first date to export in YYYY-MM-DD format")
+	end := flags.String("end", "", "last date to export in YYYY-MM-DD format")
+	overwrite := flags.Bool("overwrite", false, "replace an existing export directory")
+	locale := flags.String("locale", os.Getenv("THEMIS_LOCALE"), "BCP-47 language tag for weekday and month names, e.g. de")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	names, err := lookupLocale(*locale)
+	if err != nil {
+		return err
+	}
+	displayLocale = names
+	if *format != "html" {
+		return fmt.Errorf("unsupported export format %q", *format)
+	}
+
+	output, err := exportSite(context.Background(), *start, *end, *overwrite)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("wrote %d pages to %s\n", output.Pages, output.OutputDir)
+	return nil
+}
+
+// helpers
+type sitePage struct {
+	Title   string
+	Root    string // relative path back to the site root
+	Body    template.HTML
+	Entries []Entry
+	Tags    []TagCount
+}
+
+var siteTemplate = template.Must(template.New("page").Funcs(template.FuncMap{"tagFile": tagFileName}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { max-width: 42rem; margin: 2rem auto; padding: 0 1rem; font: 17px/1.6 Georgia, serif; color: #222; }
+nav { font-size: 0.9em; margin-bottom: 2rem; }
+nav a { margin-right: 1rem; }
+a { color: #3b5998; }
+pre, code { background: #f4f4f4; }
+</style>
+</head>
+<body>
+<nav><a href="{{.Root}}index.html">Entries</a><a href="{{.Root}}tags/index.html">Tags</a></nav>
+<h1>{{.Title}}</h1>
+{{.Body}}
+{{if .Entries}}<ul>{{range .Entries}}<li><a href="{{$.Root}}entries/{{.Date}}.html">{{.Date}}</a></li>{{end}}</ul>{{end}}
+{{if .Tags}}<ul>{{range .Tags}}<li><a href="{{tagFile .Tag}}">#{{.Tag}}</a> ({{.Count}})</li>{{end}}</ul>{{end}}
+</body>
+</html>
+`))
+
+func exportSite(ctx context.Context, start, end string, overwrite bool) (ExportHTMLOutput, error) {
+	filter, err := rangeFilter(start, end)
+	if err != nil {
+		return ExportHTMLOutput{}, err
+	}
+
+	outDir, err := resolveInVault(filepath.Join(exportsDir, "html"))
+	if err != nil {
+		return ExportHTMLOutput{}, err
+	}
+	if _, err := os.Stat(outDir); err == nil {
+		if !overwrite {
+			return ExportHTMLOutput{}, fmt.Errorf("export directory %s already exists, pass overwrite to replace it", outDir)
+		}
+		if err := os.RemoveAll(outDir); err != nil {
+			return ExportHTMLOutput{}, fmt.Errorf("failed to remove previous export: %w", err)
+		}
+	}
+
+	entries, err := collectEntries(ctx, filter)
+	if err != nil {
+		return ExportHTMLOutput{}, fmt.Errorf("failed to get entries: %w", err)
+	}
+
+	exported := map[string]bool{}
+	for _, entry := range entries {
+		exported[entry.Date] = true
+	}
+
+	for _, dir := range []string{"entries", "tags"} {
+		if err := os.MkdirAll(filepath.Join(outDir, dir), 0o755); err != nil {
+			return ExportHTMLOutput{}, fmt.Errorf("failed to create export directory: %w", err)
+		}
+	}
+
+	output := ExportHTMLOutput{OutputDir: outDir}
+	writePage := func(rel string, page sitePage) error {
+		var buf bytes.Buffer
+		if err := siteTemplate.Execute(&buf, page); err != nil {
+			return err
+		}
+		path, err := resolveInVault(filepath.Join(outDir, rel))
+		if err != nil {
+			return err
+		}
+		output.Pages++
+		return os.WriteFile(path, buf.Bytes(), 0o644)
+	}
+
+	byTag := map[string][]Entry{}
+	for _, entry := range entries {
+		_, body, _ := splitFrontmatter(entry.Content)
+		for _, tag := range extractTags(entry.Frontmatter, body) {
+			byTag[tag] = append(byTag[tag], entry)
+		}
+
+		rendered, err := renderMarkdown(linkWikilinks(body, func(target string) (string, bool) {
+			return target + ".html", exported[target]
+		}))
+		if err != nil {
+			return ExportHTMLOutput{}, fmt.Errorf("failed to render %s: %w", entry.Date, err)
+		}
+		if err := writePage(filepath.Join("entries", entry.Date+".html"), sitePage{Title: entryTitle(entry), Root: "../", Body: rendered}); err != nil {
+			return ExportHTMLOutput{}, fmt.Errorf("failed to write %s: %w", entry.Date, err)
+		}
+	}
+
+	if err := writePage("index.html", sitePage{Title: "Diary", Entries: entries}); err != nil {
+		return ExportHTMLOutput{}, fmt.Errorf("failed to write index: %w", err)
+	}
+
+	var tags []TagCount
+	for tag, tagged := range byTag {
+		tags = append(tags, TagCount{Tag: tag, Count: len(tagged)})
+		if err := writePage(filepath.Join("tags", tagFileName(tag)), sitePage{Title: "#" + tag, Root: "../", Entries: tagged}); err != nil {
+			return ExportHTMLOutput{}, fmt.Errorf("failed to write tag page %s: %w", tag, err)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Tag < tags[j].Tag })
+	if err := writePage(filepath.Join("tags", "index.html"), sitePage{Title: "Tags", Root: "../", Tags: tags}); err != nil {
+		return ExportHTMLOutput{}, fmt.Errorf("failed to write tag index: %w", err)
+	}
+
+	return output, nil
+}
+
+type documentSection struct {
+	Date  string
+	Title string
+	Body  template.HTML
+}
+
+var documentTemplate = template.Must(template.New("document").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { max-width: 42rem; margin: 2rem auto; padding: 0 1rem; font: 17px/1.6 Georgia, serif; color: #222; }
+nav ol { columns: 2; font-size: 0.9em; }
+section { border-top: 1px solid #ddd; margin-top: 2.5rem; padding-top: 1rem; }
+a { color: #3b5998; }
+pre, code { background: #f4f4f4; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<nav><ol>{{range .Sections}}<li><a href="#{{.Date}}">{{.Date}}</a></li>{{end}}</ol></nav>
+{{range .Sections}}<section id="{{.Date}}">
+<h2>{{.Title}}</h2>
+{{.Body}}
+</section>
+{{end}}
+</body>
+</html>
+`))
+
+// exportDocument renders the range into one self-contained html page with a table of contents
+func exportDocument(ctx context.Context, start, end string) (ExportHTMLOutput, error) {
+	filter, err := rangeFilter(start, end)
+	if err != nil {
+		return ExportHTMLOutput{}, err
+	}
+
+	entries, err := collectEntries(ctx, filter)
+	if err != nil {
+		return ExportHTMLOutput{}, fmt.Errorf("failed to get entries: %w", err)
+	}
+
+	included := map[string]bool{}
+	for _, entry := range entries {
+		included[entry.Date] = true
+	}
+
+	var sections []documentSection
+	for _, entry := range entries {
+		_, body, _ := splitFrontmatter(entry.Content)
+		rendered, err := renderMarkdown(linkWikilinks(body, func(target string) (string, bool) {
+			return "#" + target, included[target]
+		}))
+		if err != nil {
+			return ExportHTMLOutput{}, fmt.Errorf("failed to render %s: %w", entry.Date, err)
+		}
+		sections = append(sections, documentSection{Date: entry.Date, Title: entryTitle(entry), Body: rendered})
+	}
+
+	title := "Diary"
+	if len(entries) > 0 {
+		title = fmt.Sprintf("Diary %s to %s", entries[0].Date, entries[len(entries)-1].Date)
+	}
+
+	var buf bytes.Buffer
+	if err := documentTemplate.Execute(&buf, map[string]any{"Title": title, "Sections": sections}); err != nil {
+		return ExportHTMLOutput{}, fmt.Errorf("failed to render document: %w", err)
+	}
+
+	return ExportHTMLOutput{Pages: len(sections), HTML: buf.String()}, nil
+}
+
+func renderMarkdown(source string) (template.HTML, error) {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(source), &buf); err != nil {
+		return "", err
+	}
+	// goldmark omits raw html from the source unless explicitly allowed, so the output is safe to embed
+	return template.HTML(buf.String()), nil
+}
+
+// linkWikilinks rewrites [[wikilinks]] into markdown links when resolve finds the target, and plain text otherwise
+func linkWikilinks(body string, resolve func(target string) (string, bool)) string {
+	return wikilinkPattern.ReplaceAllStringFunc(body, func(match string) string {
+		parts := wikilinkPattern.FindStringSubmatch(match)
+		target := strings.TrimSuffix(strings.TrimSpace(parts[2]), ".md")
+		text := strings.TrimSpace(parts[4])
+		if text == "" {
+			text = target + strings.TrimPrefix(parts[3], "#")
+		}
+
+		name := filepath.Base(target)
+		if href, ok := resolve(name); ok && parts[1] == "" {
+			return "[" + escapeMarkdown(text) + "](" + href + ")"
+		}
+		return escapeMarkdown(text)
+	})
+}
+
+func escapeMarkdown(text string) string {
+	return strings.NewReplacer(`\`, `\\`, `[`, `\[`, `]`, `\]`, `*`, `\*`, `_`, `\_`, "`", "\\`", `<`, `\<`).Replace(text)
+}
+
+func entryTitle(entry Entry) string {
+	date, err := time.Parse("2006-01-02", entry.Date)
+	if err != nil {
+		return entry.Date
+	}
+	return displayLocale.formatLongDate(date)
+}
+
+func tagFileName(tag string) string {
+	return strings.ReplaceAll(tag, "/", "-") + ".html"
+}
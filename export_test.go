@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func exportVault(t *testing.T) string {
+	t.Helper()
+	return newTestVault(t, map[string]string{
+		"2024-03-14.md": "---\nmood: 4\n---\n# Thursday\nsee [[2024-03-15|tomorrow]] and [[2024-03-01]] #work\n",
+		"2024-03-15.md": "# Friday\n<script>alert(1)</script>\nback to [[2024-03-14]]\n",
+		"2024-04-01.md": "# outside the range\n",
+	})
+}
+
+func TestExportHTMLDocument(t *testing.T) {
+	exportVault(t)
+
+	_, output, err := handleExportHTML(context.Background(), nil, ExportHTMLInput{Start: "2024-03-01", End: "2024-03-31", Mode: "document"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Pages != 2 || output.OutputDir != "" {
+		t.Errorf("pages = %d in %q, want 2 sections and no directory", output.Pages, output.OutputDir)
+	}
+
+	html := output.HTML
+	for _, want := range []string{
+		"<title>Diary 2024-03-14 to 2024-03-15</title>",
+		`<section id="2024-03-14">`,
+		`<a href="#2024-03-15">tomorrow</a>`,
+		`<a href="#2024-03-14">2024-03-14</a>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("document is missing %s", want)
+		}
+	}
+	// links to dates outside the export stay text, frontmatter and raw html are left out
+	for _, unwanted := range []string{`href="#2024-03-01"`, "<script>", "mood: 4", "outside the range"} {
+		if strings.Contains(html, unwanted) {
+			t.Errorf("document contains %s", unwanted)
+		}
+	}
+	if strings.Index(html, `id="2024-03-14"`) > strings.Index(html, `id="2024-03-15"`) {
+		t.Error("sections are not oldest first")
+	}
+}
+
+func TestExportHTMLSite(t *testing.T) {
+	dir := exportVault(t)
+	input := ExportHTMLInput{Start: "2024-03-01", End: "2024-03-31"}
+
+	_, output, err := handleExportHTML(context.Background(), nil, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// two entries, the index, one tag page and the tag index
+	if output.Pages != 5 {
+		t.Errorf("pages = %d, want 5", output.Pages)
+	}
+	page := readTestFile(t, filepath.Join(output.OutputDir, "entries", "2024-03-14.html"))
+	if !strings.Contains(page, `<a href="2024-03-15.html">tomorrow</a>`) || strings.Contains(page, `href="2024-03-01.html"`) {
+		t.Errorf("entry page = %s", page)
+	}
+	if _, err := os.Stat(filepath.Join(output.OutputDir, "tags", tagFileName("work"))); err != nil {
+		t.Errorf("tag page: %v", err)
+	}
+	if rel, _ := filepath.Rel(dir, output.OutputDir); rel != filepath.Join(exportsDir, "html") {
+		t.Errorf("output dir = %s", output.OutputDir)
+	}
+
+	if _, _, err := handleExportHTML(context.Background(), nil, input); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("second export err = %v, want already exists", err)
+	}
+	input.Overwrite = true
+	if _, _, err := handleExportHTML(context.Background(), nil, input); err != nil {
+		t.Errorf("overwrite: %v", err)
+	}
+	if _, _, err := handleExportHTML(context.Background(), nil, ExportHTMLInput{Mode: "pdf"}); err == nil {
+		t.Error("want an error for an unknown mode")
+	}
+}
+
+func TestExportToDirectory(t *testing.T) {
+	dir := exportVault(t)
+	writeTestFile(t, filepath.Join(dir, "2024", "2024-03-16.md"), "# Saturday\n")
+	ctx := context.Background()
+
+	_, output, err := handleExportToDirectory(ctx, nil, ExportToDirectoryInput{OutputDir: "march", Start: "2024-03-01", End: "2024-03-31"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ExportedFile{
+		{Date: "2024-03-14", Path: "exports/march/2024-03-14.md", Bytes: len(readTestFile(t, filepath.Join(dir, "2024-03-14.md")))},
+		{Date: "2024-03-15", Path: "exports/march/2024-03-15.md", Bytes: len(readTestFile(t, filepath.Join(dir, "2024-03-15.md")))},
+		{Date: "2024-03-16", Path: "exports/march/2024/2024-03-16.md", Bytes: len("# Saturday\n")},
+	}
+	if output.OutputDir != "exports/march" || output.Format != "markdown" || output.Count != 3 || !reflect.DeepEqual(output.Files, want) {
+		t.Errorf("output = %+v, want %+v", output, want)
+	}
+	// markdown copies are byte for byte, and never read back as entries
+	for _, rel := range []string{"2024-03-14.md", "2024/2024-03-16.md"} {
+		if got, orig := readTestFile(t, filepath.Join(dir, "exports", "march", rel)), readTestFile(t, filepath.Join(dir, rel)); got != orig {
+			t.Errorf("%s exported as %q, want %q", rel, got, orig)
+		}
+	}
+	if _, year, err := handleGetEntriesByYear(ctx, nil, GetEntriesByYearInput{Year: 2024}); err != nil || year.Count != 4 {
+		t.Errorf("after export the vault has %d entries, %v, want 4", year.Count, err)
+	}
+
+	// a second export into the same folder needs overwrite, which replaces what was there
+	if _, _, err := handleExportToDirectory(ctx, nil, ExportToDirectoryInput{OutputDir: "march", Format: "json", Start: "2024-03-14", End: "2024-03-14"}); err == nil || !strings.Contains(err.Error(), "pass overwrite") {
+		t.Errorf("export into a used folder: err = %v", err)
+	}
+	_, output, err = handleExportToDirectory(ctx, nil, ExportToDirectoryInput{OutputDir: "march", Format: "json", Start: "2024-03-14", End: "2024-03-14", Overwrite: true})
+	if err != nil || output.Count != 1 || output.Files[0].Path != "exports/march/2024-03-14.json" {
+		t.Fatalf("json export = %+v, %v", output, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "exports", "march", "2024-03-15.md")); !os.IsNotExist(err) {
+		t.Errorf("overwrite left the previous export behind: %v", err)
+	}
+
+	var exported map[string]any
+	if err := json.Unmarshal([]byte(readTestFile(t, filepath.Join(dir, "exports", "march", "2024-03-14.json"))), &exported); err != nil {
+		t.Fatal(err)
+	}
+	if exported["date"] != "2024-03-14" || !reflect.DeepEqual(exported["tags"], []any{"work"}) || !reflect.DeepEqual(exported["frontmatter"], map[string]any{"mood": float64(4)}) {
+		t.Errorf("json = %v", exported)
+	}
+	if _, ok := exported["filePath"]; ok {
+		t.Errorf("json export has the server's file path: %v", exported)
+	}
+}
+
+func TestExportToDirectoryRefuses(t *testing.T) {
+	exportVault(t)
+	for _, input := range []ExportToDirectoryInput{
+		{OutputDir: ""},
+		{OutputDir: "."},
+		{OutputDir: "../daily"},
+		{OutputDir: "../../elsewhere"},
+		{OutputDir: "march", Format: "pdf"},
+		{OutputDir: "march", Start: "March"},
+	} {
+		if _, _, err := handleExportToDirectory(context.Background(), nil, input); err == nil {
+			t.Errorf("%+v accepted", input)
+		}
+	}
+}
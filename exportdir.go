@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// exportsDir holds everything the export tools write, entry walks and the watcher skip it so exported copies
+// are never read back as entries
+const exportsDir = "exports"
+
+type ExportToDirectoryInput struct {
+	OutputDir string `json:"outputDir" jsonschema:"Directory to write to, relative to the vault's exports folder, e.g. markdown"`
+	Format    string `json:"format,omitempty" jsonschema:"markdown (default) copies each entry as is, json writes each entry with its parsed metadata"`
+	Start     string `json:"start,omitempty" jsonschema:"First date to export in YYYY-MM-DD format"`
+	End       string `json:"end,omitempty" jsonschema:"Last date to export in YYYY-MM-DD format"`
+	Overwrite bool   `json:"overwrite,omitempty" jsonschema:"Replace the output directory when it already has files in it"`
+}
+
+type ExportedFile struct {
+	Date  string `json:"date" jsonschema:"Entry date in YYYY-MM-DD format"`
+	Path  string `json:"path" jsonschema:"Vault relative path of the written file"`
+	Bytes int    `json:"bytes" jsonschema:"Size of the written file"`
+}
+
+type ExportToDirectoryOutput struct {
+	OutputDir string         `json:"outputDir" jsonschema:"Vault relative directory the entries were written to"`
+	Format    string         `json:"format" jsonschema:"Format the entries were written in"`
+	Files     []ExportedFile `json:"files" jsonschema:"Manifest of written files, oldest first"`
+	Skipped   []string       `json:"skipped,omitempty" jsonschema:"Dates of encrypted entries that could not be decrypted and were not exported"`
+	Count     int            `json:"count" jsonschema:"Number of files written"`
+}
+
+// handlers
+func handleExportToDirectory(ctx context.Context, req *mcp.CallToolRequest, input ExportToDirectoryInput) (
+	*mcp.CallToolResult,
+	ExportToDirectoryOutput,
+	error,
+) {
+	format := input.Format
+	if format == "" {
+		format = "markdown"
+	}
+	if format != "markdown" && format != "json" {
+		return nil, ExportToDirectoryOutput{}, fmt.Errorf("unknown format %q, expected markdown or json", input.Format)
+	}
+	filter, err := rangeFilter(input.Start, input.End)
+	if err != nil {
+		return nil, ExportToDirectoryOutput{}, err
+	}
+
+	root := filepath.Join(themisPath, exportsDir)
+	outDir, err := resolveInVault(filepath.Join(exportsDir, input.OutputDir))
+	if err != nil {
+		return nil, ExportToDirectoryOutput{}, err
+	}
+	if !isWithin(root, outDir) || outDir == root {
+		return nil, ExportToDirectoryOutput{}, fmt.Errorf("outputDir must name a folder inside %s", exportsDir)
+	}
+	if files, err := os.ReadDir(outDir); err == nil && len(files) > 0 {
+		if !input.Overwrite {
+			return nil, ExportToDirectoryOutput{}, fmt.Errorf("output directory %s is not empty, pass overwrite to replace it", vaultRelative(outDir))
+		}
+		if err := os.RemoveAll(outDir); err != nil {
+			return nil, ExportToDirectoryOutput{}, fmt.Errorf("failed to remove previous export: %w", err)
+		}
+	}
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return nil, ExportToDirectoryOutput{}, fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	// each entry is written as soon as it is read, keeping its folder below the entry root so dates never collide
+	output := ExportToDirectoryOutput{OutputDir: vaultRelative(outDir), Format: format, Files: []ExportedFile{}}
+	err = eachEntry(ctx, filter, func(entry Entry) error {
+		if entry.Locked {
+			output.Skipped = append(output.Skipped, entry.Date)
+			return nil
+		}
+
+		rel, err := filepath.Rel(entryRoot, entry.FilePath)
+		if err != nil {
+			return err
+		}
+		rel = strings.TrimSuffix(strings.TrimSuffix(rel, ".age"), ".md")
+		data := []byte(entry.Content)
+		if format == "json" {
+			rel += ".json"
+			if data, err = json.MarshalIndent(exportedEntry(entry), "", "  "); err != nil {
+				return err
+			}
+		} else {
+			rel += ".md"
+		}
+
+		path, err := resolveInVault(filepath.Join(outDir, rel))
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", entry.Date, err)
+		}
+		output.Files = append(output.Files, ExportedFile{Date: entry.Date, Path: vaultRelative(path), Bytes: len(data)})
+		return nil
+	})
+	if err != nil {
+		return nil, ExportToDirectoryOutput{}, fmt.Errorf("failed to export entries: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, ExportToDirectoryOutput{}, err
+	}
+
+	sort.Slice(output.Files, func(i, j int) bool {
+		if output.Files[i].Date != output.Files[j].Date {
+			return output.Files[i].Date < output.Files[j].Date
+		}
+		return output.Files[i].Path < output.Files[j].Path
+	})
+	output.Count = len(output.Files)
+	return nil, output, nil
+}
+
+// helpers
+
+// exportedEntry keeps what another app needs from an entry, dropping server side details like the file path
+func exportedEntry(entry Entry) map[string]any {
+	_, body, _ := splitFrontmatter(entry.Content)
+	exported := map[string]any{
+		"date":      entry.Date,
+		"content":   entry.Content,
+		"tags":      extractTags(entry.Frontmatter, body),
+		"wordCount": entry.WordCount,
+	}
+	if entry.Frontmatter != nil {
+		exported["frontmatter"] = entry.Frontmatter
+	}
+	if entry.InlineFields != nil {
+		exported["inlineFields"] = entry.InlineFields
+	}
+	if entry.ModTime != "" {
+		exported["modTime"] = entry.ModTime
+	}
+	return exported
+}
+
+func isExportsDir(path string) bool {
+	return path == filepath.Join(themisPath, exportsDir)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strings"
+
+	"github.com/curator4/mcp-server-diary/query"
+)
+
+// entryFields lists the selectable Entry fields by json name, each with how to clear it.
+// partial, contentOffset and truncated describe what happened to the content and are always kept.
+var entryFields = []struct {
+	name  string
+	clear func(*Entry)
+}{
+	{"date", func(e *Entry) { e.Date = "" }},
+	{"path", func(e *Entry) { e.FilePath = "" }},
+	{"content", func(e *Entry) { e.Content, e.ContentEncoding = "", "" }},
+	{"preview", func(e *Entry) { e.Preview = "" }},
+	{"frontmatter", func(e *Entry) { e.Frontmatter = nil }},
+	{"inlineFields", func(e *Entry) { e.InlineFields = nil }},
+	{"warnings", func(e *Entry) { e.Warnings = nil }},
+	{"wordCount", func(e *Entry) { e.WordCount = 0 }},
+	{"readingTimeMinutes", func(e *Entry) { e.ReadingTimeMinutes = 0 }},
+	{"percentile", func(e *Entry) { e.Percentile = 0 }},
+	{"estimatedTokens", func(e *Entry) { e.EstimatedTokens = 0 }},
+	{"encrypted", func(e *Entry) { e.Encrypted = false }},
+	{"locked", func(e *Entry) { e.Locked = false }},
+	{"modTime", func(e *Entry) { e.ModTime = "" }},
+	{"conflicts", func(e *Entry) { e.Conflicts = nil }},
+	{"relativeLabel", func(e *Entry) { e.RelativeLabel = "" }},
+}
+
+// defaultFields is used when a request has no fields parameter, THEMIS_DEFAULT_FIELDS is a comma separated list.
+// nil means every field.
+var defaultFields = getDefaultFields()
+
+func getDefaultFields() map[string]bool {
+	raw := os.Getenv("THEMIS_DEFAULT_FIELDS")
+	if raw == "" {
+		return nil
+	}
+
+	fields, err := fieldSet(strings.Split(raw, ","))
+	if err != nil {
+		log.Fatalf("invalid THEMIS_DEFAULT_FIELDS: %v", err)
+	}
+	return fields
+}
+
+// parseFields validates requested field names, an empty list selects the server default
+func parseFields(names []string) (map[string]bool, error) {
+	return query.ParseFields(names, entryFieldNames(), defaultFields)
+}
+
+func fieldSet(names []string) (map[string]bool, error) {
+	return query.FieldSet(names, entryFieldNames())
+}
+
+func entryFieldNames() []string {
+	names := make([]string, len(entryFields))
+	for i, field := range entryFields {
+		names[i] = field.name
+	}
+	return names
+}
+
+// selectFields clears every field not in fields so it is omitted from the json, nil keeps everything
+func selectFields(entries []Entry, fields map[string]bool) {
+	if fields == nil {
+		return
+	}
+	for i := range entries {
+		for _, field := range entryFields {
+			if !fields[field.name] {
+				field.clear(&entries[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestVault points the server at a fresh temporary vault holding files, keyed by vault relative path,
+// and puts the previous vault, clock and length index back when the test ends
+func newTestVault(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+
+	oldVault, oldRoot, oldLengths := themisPath, entryRoot, lengths
+	themisPath, entryRoot = dir, dir
+	lengths = &lengthIndex{files: map[string]indexedLength{}}
+	t.Cleanup(func() {
+		themisPath, entryRoot, lengths = oldVault, oldRoot, oldLengths
+		setClock(systemClock{})
+	})
+
+	for rel, content := range files {
+		writeTestFile(t, filepath.Join(dir, filepath.FromSlash(rel)), content)
+	}
+	return dir
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// setTestNow pins the clock for the rest of the test, newTestVault resets it
+func setTestNow(t *testing.T, value string) time.Time {
+	t.Helper()
+	moment, err := parseFakeNow(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setClock(fixedClock(moment))
+	return moment
+}
+
+func entryDates(entries []Entry) []string {
+	dates := make([]string, len(entries))
+	for i, entry := range entries {
+		dates[i] = entry.Date
+	}
+	return dates
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// splitFrontmatter separates a leading --- delimited yaml block from the body.
+// ok is false when the content has no frontmatter.
+func splitFrontmatter(content string) (raw string, body string, ok bool) {
+	lines := strings.SplitAfter(content, "\n")
+	if strings.TrimRight(lines[0], "\r\n") != "---" {
+		return "", content, false
+	}
+
+	offset := len(lines[0])
+	for _, line := range lines[1:] {
+		if strings.TrimRight(line, "\r\n") == "---" {
+			return content[len(lines[0]):offset], content[offset+len(line):], true
+		}
+		offset += len(line)
+	}
+
+	return "", content, false
+}
+
+// parseFrontmatter decodes the frontmatter block into a map, returning the body alongside it
+func parseFrontmatter(content string) (map[string]any, string, error) {
+	raw, body, ok := splitFrontmatter(content)
+	if !ok {
+		return nil, content, nil
+	}
+
+	fm := map[string]any{}
+	if err := yaml.Unmarshal([]byte(raw), &fm); err != nil {
+		return nil, body, err
+	}
+
+	// yaml decodes bare dates as timestamps, keep them as plain date strings instead
+	for key, value := range fm {
+		if t, ok := value.(time.Time); ok {
+			fm[key] = formatYAMLTime(t)
+		}
+	}
+
+	return fm, body, nil
+}
+
+func formatYAMLTime(t time.Time) string {
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0 {
+		return t.Format("2006-01-02")
+	}
+	return t.Format(time.RFC3339)
+}
+
+// checkDateConsistency warns when the frontmatter date disagrees with the filename date
+func checkDateConsistency(fileDate string, fm map[string]any) string {
+	value, ok := fm["date"]
+	if !ok {
+		return ""
+	}
+
+	fmDate := strings.TrimSpace(fmt.Sprint(value))
+	if len(fmDate) >= 10 {
+		if _, err := time.Parse("2006-01-02", fmDate[:10]); err == nil {
+			fmDate = fmDate[:10]
+		}
+	}
+
+	if fmDate == fileDate {
+		return ""
+	}
+	return fmt.Sprintf("frontmatter date %q does not match filename date %s", fmDate, fileDate)
+}
+
+var inlineTagPattern = regexp.MustCompile(`(?:^|[\s(])#([\p{L}\p{N}_/-]+)`)
+
+// extractTags collects frontmatter tags plus inline #tags, in order of first appearance
+func extractTags(fm map[string]any, body string) []string {
+	var tags []string
+	seen := map[string]bool{}
+	add := func(tag string) {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+		if tag == "" || seen[tag] {
+			return
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+
+	for _, key := range []string{"tags", "tag"} {
+		switch v := fm[key].(type) {
+		case string:
+			for _, tag := range strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ' ' }) {
+				add(tag)
+			}
+		case []any:
+			for _, item := range v {
+				if s, ok := item.(string); ok {
+					add(s)
+				}
+			}
+		}
+	}
+
+	inFence := false
+	for _, line := range strings.Split(body, "\n") {
+		if isFenceLine(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		for _, match := range inlineTagPattern.FindAllStringSubmatchIndex(line, -1) {
+			// a heading anchor in a markdown link, [top](#top), is not a tag
+			if line[match[0]] == '(' && match[0] > 0 && line[match[0]-1] == ']' {
+				continue
+			}
+			// obsidian requires at least one non-numeric character in a tag
+			tag := line[match[2]:match[3]]
+			if strings.Trim(tag, "0123456789") == "" {
+				continue
+			}
+			add(tag)
+		}
+	}
+
+	return tags
+}
+
+func isFenceLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")
+}
+
+var (
+	embedPattern       = regexp.MustCompile(`!\[[^\]]*\]\([^)]*\)|!\[\[[^\]]*\]\]`)
+	linkPattern        = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	aliasedLinkPattern = regexp.MustCompile(`\[\[(?:[^\]|]*\|)?([^\]]*)\]\]`)
+	htmlCommentPattern = regexp.MustCompile(`(?s)<!--.*?-->`)
+)
+
+// countWords counts the words a reader would see in the entry body. frontmatter, fenced code, comments,
+// embeds, link targets and tokens that are only markdown markers (#, -, >, **) are not words.
+func countWords(content string) int {
+	_, body, _ := splitFrontmatter(content)
+
+	var prose []string
+	inFence := false
+	for _, line := range strings.Split(body, "\n") {
+		if isFenceLine(line) {
+			inFence = !inFence
+			continue
+		}
+		if !inFence {
+			prose = append(prose, line)
+		}
+	}
+
+	text := htmlCommentPattern.ReplaceAllString(strings.Join(prose, "\n"), " ")
+	text = embedPattern.ReplaceAllString(text, " ")
+	text = linkPattern.ReplaceAllString(text, "$1")
+	text = aliasedLinkPattern.ReplaceAllString(text, "$1")
+
+	count := 0
+	for _, field := range strings.Fields(text) {
+		if strings.IndexFunc(field, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsNumber(r) }) >= 0 {
+			count++
+		}
+	}
+	return count
+}
+
+var (
+	bracketedFieldPattern = regexp.MustCompile(`[\[(]([\p{L}\p{N}_ -]+?)::[ \t]*([^\])]*?)[ \t]*[\])]`)
+	inlineFieldKeyPattern = regexp.MustCompile(`(?:^|[ \t])([\p{L}\p{N}_-]+)::(?:[ \t]|$)`)
+	listMarkerPattern     = regexp.MustCompile(`^[ \t]*(?:[-*+>]|\d+[.)])[ \t]+(?:\[.\][ \t]+)?`)
+)
+
+// parseInlineFields collects dataview style key:: value fields from the body, skipping fenced code.
+// a line may hold several [key:: value] or (key:: value) fields, or bare fields where each value runs
+// up to the next key::. keys are lowercased and a key may repeat, so values are kept in order of appearance.
+func parseInlineFields(body string) map[string][]string {
+	var fields map[string][]string
+	add := func(key, value string) {
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		if key == "" || value == "" {
+			return
+		}
+		if fields == nil {
+			fields = map[string][]string{}
+		}
+		fields[key] = append(fields[key], value)
+	}
+
+	inFence := false
+	for _, line := range strings.Split(body, "\n") {
+		if isFenceLine(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence || !strings.Contains(line, "::") {
+			continue
+		}
+
+		for _, match := range bracketedFieldPattern.FindAllStringSubmatch(line, -1) {
+			add(match[1], match[2])
+		}
+		line = bracketedFieldPattern.ReplaceAllString(line, " ")
+
+		line = listMarkerPattern.ReplaceAllString(strings.TrimRight(line, "\r"), "")
+		keys := inlineFieldKeyPattern.FindAllStringSubmatchIndex(line, -1)
+		for i, key := range keys {
+			end := len(line)
+			if i+1 < len(keys) {
+				end = keys[i+1][0]
+			}
+			add(line[key[2]:key[3]], line[key[1]:end])
+		}
+	}
+
+	return fields
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckDateConsistency(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"# no frontmatter\n", ""},
+		{"---\nmood: 3\n---\n", ""},
+		{"---\ndate: 2024-03-15\n---\n", ""},
+		{"---\ndate: \"2024-03-15\"\n---\n", ""},
+		{"---\ndate: 2024-03-15T08:30:00+01:00\n---\n", ""},
+		{"---\ndate: 2024-03-14\n---\n", `frontmatter date "2024-03-14" does not match filename date 2024-03-15`},
+		{"---\ndate: yesterday\n---\n", `frontmatter date "yesterday" does not match filename date 2024-03-15`},
+	}
+	for _, tt := range tests {
+		fm, _, err := parseFrontmatter(tt.content)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.content, err)
+		}
+		if got := checkDateConsistency("2024-03-15", fm); got != tt.want {
+			t.Errorf("%q: warning = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestEntryWarnings(t *testing.T) {
+	newTestVault(t, map[string]string{
+		"2024-03-14.md": "---\ndate: 2024-03-14\n---\nfine\n",
+		"2024-03-15.md": "---\ndate: 2024-03-16\n---\ncopied from tomorrow\n",
+		"2024-03-16.md": "---\nmood: [unclosed\n---\nbroken\n",
+	})
+
+	_, output, err := handleGetEntriesByDates(context.Background(), nil, GetEntriesByDatesInput{Dates: []string{"2024-03-14", "2024-03-15", "2024-03-16"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(output.Entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(output.Entries))
+	}
+	if output.Entries[0].Warnings != nil {
+		t.Errorf("consistent entry warnings = %v", output.Entries[0].Warnings)
+	}
+	if want := []string{`frontmatter date "2024-03-16" does not match filename date 2024-03-15`}; !reflect.DeepEqual(output.Entries[1].Warnings, want) {
+		t.Errorf("mismatched entry warnings = %v, want %v", output.Entries[1].Warnings, want)
+	}
+	if warnings := output.Entries[2].Warnings; len(warnings) != 1 || !strings.HasPrefix(warnings[0], "invalid frontmatter:") {
+		t.Errorf("broken frontmatter warnings = %v", warnings)
+	}
+}
+
+func TestExtractTags(t *testing.T) {
+	fm := map[string]any{"tags": []any{"travel", "#trip"}, "tag": "work, gym"}
+	body := "# Friday #trip (#side) #2024 #q1\nsee [top](#top) and [[Vienna#Day one]]\n```\n#fenced\n```\nissue#12 ends #done\n"
+
+	want := []string{"travel", "trip", "work", "gym", "side", "q1", "done"}
+	if got := extractTags(fm, body); !reflect.DeepEqual(got, want) {
+		t.Errorf("tags = %q, want %q", got, want)
+	}
+}
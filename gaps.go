@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type GetLongestGapInput struct{}
+
+type Gap struct {
+	Days int    `json:"days" jsonschema:"Number of consecutive days without an entry"`
+	From string `json:"from,omitempty" jsonschema:"First day without an entry in YYYY-MM-DD format"`
+	To   string `json:"to,omitempty" jsonschema:"Last day without an entry in YYYY-MM-DD format"`
+}
+
+type LongestGapOutput struct {
+	Longest   Gap    `json:"longest" jsonschema:"Longest stretch without entries between the first and last entry"`
+	Current   Gap    `json:"current" jsonschema:"Days since the last entry up to today, zero when there is an entry today"`
+	LastEntry string `json:"lastEntry,omitempty" jsonschema:"Date of the most recent entry"`
+}
+
+// handlers
+func handleGetLongestGap(ctx context.Context, req *mcp.CallToolRequest, input GetLongestGapInput) (
+	*mcp.CallToolResult,
+	LongestGapOutput,
+	error,
+) {
+	dates, err := listEntryDates()
+	if err != nil {
+		return nil, LongestGapOutput{}, fmt.Errorf("failed to get entries: %w", err)
+	}
+	if len(dates) == 0 {
+		return nil, LongestGapOutput{}, nil
+	}
+
+	var output LongestGapOutput
+	for i := 1; i < len(dates); i++ {
+		if gap := gapBetween(dates[i-1], dates[i]); gap.Days > output.Longest.Days {
+			output.Longest = gap
+		}
+	}
+
+	last := dates[len(dates)-1]
+	output.LastEntry = last.Format("2006-01-02")
+
+	today := now().In(location)
+	todayDate := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	if todayDate.After(last) {
+		// today itself has no entry yet, so it counts towards the current gap
+		output.Current = gapBetween(last, todayDate.AddDate(0, 0, 1))
+	}
+
+	return nil, output, nil
+}
+
+// helpers
+
+// gapBetween describes the days strictly between two entry dates
+func gapBetween(prev, next time.Time) Gap {
+	days := int(next.Sub(prev).Hours()/24) - 1
+	if days <= 0 {
+		return Gap{}
+	}
+	return Gap{
+		Days: days,
+		From: prev.AddDate(0, 0, 1).Format("2006-01-02"),
+		To:   next.AddDate(0, 0, -1).Format("2006-01-02"),
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGetLongestGap(t *testing.T) {
+	newTestVault(t, map[string]string{
+		"2024-02-27.md":      "x\n",
+		"2024-03-02.md":      "x\n",
+		"2024-03-03.md":      "x\n",
+		"2024/2024-03-03.md": "same day again\n",
+		"2024-03-05.md":      "x\n",
+	})
+	oldLocation := location
+	location = time.UTC
+	defer func() { location = oldLocation }()
+
+	tests := []struct {
+		now     string
+		current Gap
+	}{
+		{"2024-03-05T20:00:00Z", Gap{}},
+		{"2024-03-06T08:00:00Z", Gap{Days: 1, From: "2024-03-06", To: "2024-03-06"}},
+		{"2024-03-10T08:00:00Z", Gap{Days: 5, From: "2024-03-06", To: "2024-03-10"}},
+	}
+	for _, tt := range tests {
+		setTestNow(t, tt.now)
+		_, output, err := handleGetLongestGap(context.Background(), nil, GetLongestGapInput{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the longest gap spans the leap day
+		if want := (Gap{Days: 3, From: "2024-02-28", To: "2024-03-01"}); output.Longest != want {
+			t.Errorf("longest = %+v, want %+v", output.Longest, want)
+		}
+		if output.Current != tt.current || output.LastEntry != "2024-03-05" {
+			t.Errorf("at %s: current = %+v after %s, want %+v", tt.now, output.Current, output.LastEntry, tt.current)
+		}
+	}
+}
+
+func TestGetLongestGapEmptyVault(t *testing.T) {
+	newTestVault(t, nil)
+	_, output, err := handleGetLongestGap(context.Background(), nil, GetLongestGapInput{})
+	if err != nil || output != (LongestGapOutput{}) {
+		t.Errorf("output = %+v, %v, want nothing", output, err)
+	}
+}
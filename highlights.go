@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	defaultHighlightsLimit = 100
+	maxHighlightsLimit     = 500
+)
+
+var (
+	highlightPattern  = regexp.MustCompile(`==([^=\s](?:.*?[^=\s])?)==`)
+	blockquotePattern = regexp.MustCompile(`^ {0,3}> ?(.*)$`)
+)
+
+type GetHighlightsInput struct {
+	Start string `json:"start,omitempty" jsonschema:"First date to include in YYYY-MM-DD format"`
+	End   string `json:"end,omitempty" jsonschema:"Last date to include in YYYY-MM-DD format"`
+	Tag   string `json:"tag,omitempty" jsonschema:"Only include entries with this tag"`
+	Limit int    `json:"limit,omitempty" jsonschema:"Maximum number of highlights to return (default 100, max 500)"`
+}
+
+type Highlight struct {
+	Date    string `json:"date" jsonschema:"Date of the entry the highlight comes from"`
+	Kind    string `json:"kind" jsonschema:"Either highlight for ==marked== text or blockquote"`
+	Text    string `json:"text" jsonschema:"The flagged text with inner markdown preserved"`
+	Context string `json:"context,omitempty" jsonschema:"The sentence surrounding a highlight"`
+}
+
+type HighlightsOutput struct {
+	Highlights []Highlight `json:"highlights" jsonschema:"Highlights in chronological order"`
+	Count      int         `json:"count" jsonschema:"Total number of highlights returned"`
+	Truncated  bool        `json:"truncated" jsonschema:"Whether more highlights matched than the limit allowed"`
+}
+
+// handlers
+func handleGetHighlights(ctx context.Context, req *mcp.CallToolRequest, input GetHighlightsInput) (
+	*mcp.CallToolResult,
+	HighlightsOutput,
+	error,
+) {
+	filter, err := rangeFilter(input.Start, input.End)
+	if err != nil {
+		return nil, HighlightsOutput{}, err
+	}
+	limit := input.Limit
+	if limit <= 0 {
+		limit = defaultHighlightsLimit
+	}
+	limit = min(limit, maxHighlightsLimit)
+
+	if input.Tag != "" {
+		filter.Tags = []string{input.Tag}
+	}
+	entries, err := collectEntries(ctx, filter)
+	if err != nil {
+		return nil, HighlightsOutput{}, fmt.Errorf("failed to get entries: %w", err)
+	}
+
+	output := HighlightsOutput{Highlights: []Highlight{}}
+	for _, entry := range entries {
+		for _, highlight := range extractHighlights(entry) {
+			if len(output.Highlights) == limit {
+				output.Truncated = true
+				break
+			}
+			output.Highlights = append(output.Highlights, highlight)
+		}
+	}
+	output.Count = len(output.Highlights)
+
+	return nil, output, nil
+}
+
+// helpers
+func extractHighlights(entry Entry) []Highlight {
+	_, body, _ := splitFrontmatter(entry.Content)
+
+	var highlights []Highlight
+	var quote []string
+	flushQuote := func() {
+		if len(quote) > 0 {
+			highlights = append(highlights, Highlight{Date: entry.Date, Kind: "blockquote", Text: strings.Join(quote, "\n")})
+			quote = nil
+		}
+	}
+
+	inFence := false
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if isFenceLine(line) {
+			inFence = !inFence
+			flushQuote()
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		if match := blockquotePattern.FindStringSubmatch(line); match != nil {
+			quote = append(quote, match[1])
+		} else {
+			flushQuote()
+		}
+
+		for _, loc := range highlightPattern.FindAllStringSubmatchIndex(line, -1) {
+			highlights = append(highlights, Highlight{
+				Date:    entry.Date,
+				Kind:    "highlight",
+				Text:    line[loc[2]:loc[3]],
+				Context: surroundingSentence(line, loc[0], loc[1]),
+			})
+		}
+	}
+	flushQuote()
+
+	return highlights
+}
+
+// surroundingSentence widens [start, end) of line to the enclosing sentence boundaries
+func surroundingSentence(line string, start, end int) string {
+	from := 0
+	for i := start - 1; i > 0; i-- {
+		if strings.ContainsRune(".!?", rune(line[i-1])) && line[i] == ' ' {
+			from = i
+			break
+		}
+	}
+
+	to := len(line)
+	for i := end; i < len(line); i++ {
+		if strings.ContainsRune(".!?", rune(line[i])) && (i+1 == len(line) || line[i+1] == ' ') {
+			to = i + 1
+			break
+		}
+	}
+
+	sentence := strings.TrimSpace(line[from:to])
+	return strings.TrimSpace(strings.TrimLeft(sentence, ">-*+ "))
+}
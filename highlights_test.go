@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestGetHighlights(t *testing.T) {
+	newTestVault(t, map[string]string{
+		"2024-03-10.md": "---\ntags: [work]\n---\nMet the team. The ==launch date== moved again! Nobody was surprised.\n> the best way out\n> is always through\nafter\n```\n==not this==\n> nor this\n```\n",
+		"2024-03-12.md": "# Tuesday\nquiet day #home\n==one== and ==two==\n",
+		"2024-03-14.md": "nothing flagged\n",
+	})
+	ctx := context.Background()
+
+	launch := Highlight{Date: "2024-03-10", Kind: "highlight", Text: "launch date", Context: "The ==launch date== moved again!"}
+	quote := Highlight{Date: "2024-03-10", Kind: "blockquote", Text: "the best way out\nis always through"}
+	one := Highlight{Date: "2024-03-12", Kind: "highlight", Text: "one", Context: "==one== and ==two=="}
+	two := Highlight{Date: "2024-03-12", Kind: "highlight", Text: "two", Context: "==one== and ==two=="}
+
+	tests := []struct {
+		name      string
+		input     GetHighlightsInput
+		want      []Highlight
+		truncated bool
+	}{
+		// nothing inside a code fence is flagged
+		{"all", GetHighlightsInput{}, []Highlight{launch, quote, one, two}, false},
+		{"range", GetHighlightsInput{Start: "2024-03-11"}, []Highlight{one, two}, false},
+		{"frontmatter tag", GetHighlightsInput{Tag: "#WORK"}, []Highlight{launch, quote}, false},
+		{"inline tag", GetHighlightsInput{Tag: "home"}, []Highlight{one, two}, false},
+		{"limit", GetHighlightsInput{Limit: 3}, []Highlight{launch, quote, one}, true},
+		{"none", GetHighlightsInput{Start: "2024-03-13"}, []Highlight{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, output, err := handleGetHighlights(ctx, nil, tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(output.Highlights, tt.want) || output.Count != len(tt.want) || output.Truncated != tt.truncated {
+				t.Errorf("output = %+v, want %+v, truncated %v", output, tt.want, tt.truncated)
+			}
+		})
+	}
+
+	// an empty result is an empty list, which the output schema requires
+	session := connectTestServer(t, newServer(false, false))
+	if _, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "getHighlights", Arguments: map[string]any{"start": "2024-03-13"}}); err != nil {
+		t.Errorf("getHighlights without matches: %v", err)
+	}
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const indexVersion = 1
+
+// vaultIndex persists per entry metadata between runs, it is off unless THEMIS_INDEX_PATH is set
+var vaultIndex = newEntryIndex(getIndexPath())
+
+// entryIndex keeps the date, word count, tags and content hash of every entry file in scope. refresh stats
+// the tree and only reads files whose mtime or size changed, so metadata tools skip reading content.
+type entryIndex struct {
+	mu      sync.Mutex
+	path    string
+	records map[string]indexRecord // by absolute file path
+	loaded  bool
+	reread  int // files read by the last refresh
+}
+
+type indexRecord struct {
+	Date      string    `json:"date"`
+	Path      string    `json:"path"` // vault relative, absolute paths are resolved on load
+	ModTime   time.Time `json:"modTime"`
+	Size      int64     `json:"size"`
+	WordCount int       `json:"wordCount"`
+	Tags      []string  `json:"tags,omitempty"`
+	Hash      string    `json:"hash"`
+	Locked    bool      `json:"locked,omitempty"`
+}
+
+type IndexState struct {
+	Path    string `json:"path" jsonschema:"Where the index is stored"`
+	Entries int    `json:"entries" jsonschema:"Entry files in the index"`
+	Reread  int    `json:"reread" jsonschema:"Files read again by the last refresh because they were new or changed"`
+}
+
+func getIndexPath() string {
+	raw := strings.TrimSpace(os.Getenv("THEMIS_INDEX_PATH"))
+	if raw == "" {
+		return ""
+	}
+	if filepath.IsAbs(raw) {
+		return filepath.Clean(raw)
+	}
+
+	// relative paths are kept inside the vault, e.g. .themis/index.json
+	path, err := resolveInVault(raw)
+	if err != nil {
+		log.Fatalf("invalid THEMIS_INDEX_PATH: %v", err)
+	}
+	return path
+}
+
+func newEntryIndex(path string) *entryIndex {
+	return &entryIndex{path: path, records: map[string]indexRecord{}}
+}
+
+func (x *entryIndex) enabled() bool {
+	return x != nil && x.path != ""
+}
+
+// entries refreshes the index and returns the records filter lets through, oldest first. ok is false when
+// the index is disabled and callers have to walk the entries themselves.
+func (x *entryIndex) entries(filter func(date time.Time) bool) ([]indexRecord, bool, error) {
+	if !x.enabled() {
+		return nil, false, nil
+	}
+	if err := checkEntryRoot(); err != nil {
+		return nil, true, err
+	}
+	x.refresh()
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	var records []indexRecord
+	for _, record := range x.records {
+		date, err := time.Parse("2006-01-02", record.Date)
+		if err == nil && filter(date) {
+			records = append(records, record)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Date != records[j].Date {
+			return records[i].Date < records[j].Date
+		}
+		return records[i].Path < records[j].Path
+	})
+	return records, true, nil
+}
+
+// refresh brings the index in line with the files on disk and saves it when anything changed. the saved
+// index is loaded on the first call, so a restart only reads the files edited while the server was down.
+func (x *entryIndex) refresh() {
+	if x.enabled() {
+		x.refreshFrom(entryStamps())
+	}
+}
+
+// refreshFrom is refresh with the entry files already stat'ed, for callers that need the stamps themselves
+func (x *entryIndex) refreshFrom(stamps map[string]fileStamp) {
+	if !x.enabled() {
+		return
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if !x.loaded {
+		if err := x.load(); err != nil {
+			log.Printf("rebuilding entry index: %v", err)
+		}
+		x.loaded = true
+	}
+
+	changed := false
+	for path := range x.records {
+		if _, ok := stamps[path]; !ok {
+			delete(x.records, path)
+			changed = true
+		}
+	}
+	x.reread = 0
+	for path, stamp := range stamps {
+		// locked entries are tried again in case an identity was configured since
+		previous, ok := x.records[path]
+		unchanged := ok && sameStamp(fileStamp{modTime: previous.ModTime, size: previous.Size}, stamp)
+		if unchanged && !previous.Locked {
+			continue
+		}
+		record, err := indexFile(path, stamp)
+		if err != nil {
+			log.Printf("error indexing %s: %v", path, err)
+			promReadErrors.Inc()
+			continue
+		}
+		if unchanged && record.Locked {
+			continue
+		}
+		x.records[path] = record
+		x.reread++
+		changed = true
+	}
+
+	if changed {
+		if err := x.save(); err != nil {
+			log.Printf("failed to save entry index: %v", err)
+		}
+	}
+	x.seedLengths()
+}
+
+// forget drops the record for path so the next refresh reads the file again even when its mtime and size
+// look unchanged. the saved index is loaded first and saved again, so the dropped record doesn't come back.
+func (x *entryIndex) forget(path string) {
+	if !x.enabled() {
+		return
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if !x.loaded {
+		if err := x.load(); err != nil {
+			log.Printf("rebuilding entry index: %v", err)
+		}
+		x.loaded = true
+	}
+	if _, ok := x.records[path]; !ok {
+		return
+	}
+	delete(x.records, path)
+	if err := x.save(); err != nil {
+		log.Printf("failed to save entry index: %v", err)
+	}
+}
+
+// load must be called with mu held
+func (x *entryIndex) load() error {
+	data, err := os.ReadFile(x.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var stored struct {
+		Version int           `json:"version"`
+		Entries []indexRecord `json:"entries"`
+	}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("invalid %s: %w", x.path, err)
+	}
+	if stored.Version != indexVersion {
+		return fmt.Errorf("%s has version %d, expected %d", x.path, stored.Version, indexVersion)
+	}
+	for _, record := range stored.Entries {
+		x.records[filepath.Join(themisPath, filepath.FromSlash(record.Path))] = record
+	}
+	return nil
+}
+
+// save must be called with mu held. the index is derived from the vault, so it is replaced without the wal.
+func (x *entryIndex) save() error {
+	stored := struct {
+		Version int           `json:"version"`
+		Entries []indexRecord `json:"entries"`
+	}{Version: indexVersion, Entries: make([]indexRecord, 0, len(x.records))}
+	for _, record := range x.records {
+		stored.Entries = append(stored.Entries, record)
+	}
+	sort.Slice(stored.Entries, func(i, j int) bool { return stored.Entries[i].Path < stored.Entries[j].Path })
+
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(x.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(x.path), err)
+	}
+	return replaceFile(x.path, append(data, '\n'))
+}
+
+// seedLengths hands the indexed word counts to the length index so percentiles don't read every entry.
+// must be called with mu held.
+func (x *entryIndex) seedLengths() {
+	lengths.mu.Lock()
+	defer lengths.mu.Unlock()
+	for path, record := range x.records {
+		stamp := fileStamp{modTime: record.ModTime, size: record.Size}
+		if indexed, ok := lengths.files[path]; ok && sameStamp(indexed.stamp, stamp) {
+			continue
+		}
+		words := record.WordCount
+		if record.Locked {
+			words = -1
+		}
+		lengths.files[path] = indexedLength{stamp: stamp, words: words}
+		lengths.sorted = nil
+	}
+}
+
+// freshness compares the index with the files on disk without reading any of them, stale counts files added,
+// changed or removed since the last refresh. the saved index is loaded when no refresh has run yet.
+func (x *entryIndex) freshness() (entries, stale int, err error) {
+	stamps := entryStamps()
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if !x.loaded {
+		if err := x.load(); err != nil {
+			return 0, 0, err
+		}
+		x.loaded = true
+	}
+
+	for path, stamp := range stamps {
+		if record, ok := x.records[path]; !ok || !sameStamp(fileStamp{modTime: record.ModTime, size: record.Size}, stamp) {
+			stale++
+		}
+	}
+	for path := range x.records {
+		if _, ok := stamps[path]; !ok {
+			stale++
+		}
+	}
+	return len(x.records), stale, nil
+}
+
+func (x *entryIndex) state() IndexState {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return IndexState{Path: x.path, Entries: len(x.records), Reread: x.reread}
+}
+
+// indexFile reads one entry file and derives its record the same way walkEntriesContext builds an entry
+func indexFile(path string, stamp fileStamp) (indexRecord, error) {
+	data, err := cache.read(path)
+	if err != nil {
+		return indexRecord{}, err
+	}
+	sum := sha256.Sum256(data)
+	record := indexRecord{
+		Date:    strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".age"), ".md"),
+		Path:    vaultRelative(path),
+		ModTime: stamp.modTime,
+		Size:    stamp.size,
+		Hash:    hex.EncodeToString(sum[:]),
+	}
+
+	if strings.HasSuffix(path, encryptedSuffix) {
+		if data, err = decryptEntry(data); err != nil {
+			record.Locked = true
+			return record, nil
+		}
+	}
+	text, _ := decodeContent(data)
+	record.WordCount = countWords(text)
+	fm, body, _ := parseFrontmatter(text)
+	record.Tags = extractTags(fm, body)
+	return record, nil
+}
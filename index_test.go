@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useTestIndex turns the entry index on for the rest of the test, stored at .themis/index.json in the vault
+func useTestIndex(t *testing.T, dir string) string {
+	t.Helper()
+	path := filepath.Join(dir, ".themis", "index.json")
+	old := vaultIndex
+	vaultIndex = newEntryIndex(path)
+	t.Cleanup(func() { vaultIndex = old })
+	return path
+}
+
+func touch(t *testing.T, path string, at time.Time) {
+	t.Helper()
+	if err := os.Chtimes(path, at, at); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEntryIndexBuildReuseRefresh(t *testing.T) {
+	dir := newTestVault(t, map[string]string{
+		"2024-03-13.md": "one two #work\n",
+		"2024-03-14.md": "---\ntags: [gym]\n---\none\n",
+		"2024-03-15.md": "one two three\n",
+	})
+	path := useTestIndex(t, dir)
+	all := func(time.Time) bool { return true }
+
+	// the first refresh reads every entry and saves the index
+	records, indexed, err := vaultIndex.entries(all)
+	if err != nil || !indexed {
+		t.Fatalf("entries = %v, %v", indexed, err)
+	}
+	if got := vaultIndex.state(); got.Entries != 3 || got.Reread != 3 {
+		t.Errorf("after build: %+v, want 3 entries all read", got)
+	}
+	if records[0].WordCount != 3 || !reflect.DeepEqual(records[0].Tags, []string{"work"}) || !reflect.DeepEqual(records[1].Tags, []string{"gym"}) {
+		t.Errorf("records = %+v", records)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("index not saved: %v", err)
+	}
+
+	// a restart loads the saved index and reads nothing
+	vaultIndex = newEntryIndex(path)
+	if _, _, err := vaultIndex.entries(all); err != nil {
+		t.Fatal(err)
+	}
+	if got := vaultIndex.state(); got.Entries != 3 || got.Reread != 0 {
+		t.Errorf("after reload: %+v, want 3 entries none read", got)
+	}
+
+	// only the changed and added files are read again, removed ones drop out
+	later := time.Now().Add(time.Minute)
+	writeTestFile(t, filepath.Join(dir, "2024-03-15.md"), "one two three four five\n")
+	touch(t, filepath.Join(dir, "2024-03-15.md"), later)
+	writeTestFile(t, filepath.Join(dir, "2024-03-16.md"), "new\n")
+	if err := os.Remove(filepath.Join(dir, "2024-03-13.md")); err != nil {
+		t.Fatal(err)
+	}
+	records, _, err = vaultIndex.entries(all)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := vaultIndex.state(); got.Entries != 3 || got.Reread != 2 {
+		t.Errorf("after edits: %+v, want 3 entries 2 read", got)
+	}
+	dates := make([]string, len(records))
+	for i, record := range records {
+		dates[i] = record.Date
+	}
+	if !reflect.DeepEqual(dates, []string{"2024-03-14", "2024-03-15", "2024-03-16"}) || records[1].WordCount != 5 {
+		t.Errorf("records = %+v", records)
+	}
+}
+
+func TestEntryIndexServesMetadataScans(t *testing.T) {
+	dir := newTestVault(t, map[string]string{
+		"2024-03-14.md": "one #work\n",
+		"2024-03-15.md": "one two three #work\n",
+	})
+	useTestIndex(t, dir)
+	vaultIndex.refresh()
+
+	// scans served by the index read no content once it is current
+	before := cache.state()
+	dates, err := listEntryDates()
+	if err != nil || len(dates) != 2 {
+		t.Fatalf("dates = %v, %v", dates, err)
+	}
+	_, stats, err := handleGetYearStats(context.Background(), nil, GetYearStatsInput{Year: 2024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalEntries != 2 || stats.TotalWords != 6 || len(stats.TopTags) != 1 || stats.TopTags[0].Tag != "work" {
+		t.Errorf("year stats = %+v", stats)
+	}
+	if sorted, ok := lengths.distribution(context.Background()); !ok || !reflect.DeepEqual(sorted, []int{2, 4}) {
+		t.Errorf("distribution = %v, %v", sorted, ok)
+	}
+	after := cache.state()
+	if reads := after.Hits + after.Misses - before.Hits - before.Misses; reads != 0 {
+		t.Errorf("read %d files, want none", reads)
+	}
+}
+
+func TestIndexCheckReportsFreshness(t *testing.T) {
+	dir := newTestVault(t, map[string]string{"2024-03-14.md": "one\n"})
+	useTestIndex(t, dir)
+	vaultIndex.refresh()
+
+	if check := indexCheck(); check.Status != "pass" || !strings.Contains(check.Detail, "1 entries indexed") || !strings.Contains(check.Detail, "up to date") {
+		t.Errorf("fresh index check = %+v", check)
+	}
+
+	writeTestFile(t, filepath.Join(dir, "2024-03-15.md"), "two\n")
+	if check := indexCheck(); !strings.Contains(check.Detail, "1 files changed since") {
+		t.Errorf("stale index check = %+v", check)
+	}
+	if got := vaultIndex.state(); got.Entries != 1 {
+		t.Errorf("the check refreshed the index: %+v", got)
+	}
+}
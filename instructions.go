@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// THEMIS_INSTRUCTIONS is a text/template file, relative to the vault, replacing the generated instructions.
+// {{.Default}} pulls the generated text in so personal conventions can be added to it.
+var instructionsTemplate = getInstructionsTemplate()
+
+// THEMIS_READ_ONLY=true (or --read-only) leaves writeTools unregistered, so no tool call can change the vault
+var readOnlyVault = os.Getenv("THEMIS_READ_ONLY") == "true"
+
+// writeTools change files in the vault, the server counts as read-only when none of them is registered
+var writeTools = []string{"createEntry", "backfillEntries", "addTagToRange", "replaceText", "resolveConflict", "resolveConflicts", "splitEntry", "pinEntry", "unpinEntry", "exportHTML", "exportToDirectory"}
+
+// toolHints says which tool answers which kind of question, tools that are not registered are left out
+var toolHints = []struct{ tool, hint string }{
+	{"getRecentEntries", "the last few days"},
+	{"getMorningBriefing", "a daily review of recent days, open todos and tags"},
+	{"getEntriesByDates", "specific days"},
+	{"getEntriesByYear", "a whole year"},
+	{"getEntryNeighborhood", "the days around one date"},
+	{"searchFrontmatter", "frontmatter values such as mood or location"},
+	{"filterEntriesWithField", "which days have a frontmatter field recorded at all"},
+	{"getOutline", "the structure of entries without their content"},
+	{"getThread", "one ongoing storyline"},
+	{"getYearStats", "a year in review"},
+}
+
+type instructionsData struct {
+	VaultName  string
+	Scope      string
+	DateFormat string
+	Timezone   string
+	Today      string
+	ReadOnly   bool
+	Tools      []*mcp.Tool
+	WriteTools []string
+	Default    string
+}
+
+func getInstructionsTemplate() *template.Template {
+	name := strings.TrimSpace(os.Getenv("THEMIS_INSTRUCTIONS"))
+	if name == "" {
+		return nil
+	}
+
+	path, err := resolveInVault(name)
+	if err != nil {
+		log.Fatalf("invalid THEMIS_INSTRUCTIONS: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalf("failed to read THEMIS_INSTRUCTIONS: %v", err)
+	}
+	tmpl, err := template.New(filepath.Base(path)).Parse(string(data))
+	if err != nil {
+		log.Fatalf("invalid THEMIS_INSTRUCTIONS template: %v", err)
+	}
+	return tmpl
+}
+
+// helpers
+
+// instructionsMiddleware fills in the initialize instructions from the tools actually registered. it has to be the
+// innermost middleware so listing the tools doesn't count as a call.
+func instructionsMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		result, err := next(ctx, method, req)
+		initialize, ok := result.(*mcp.InitializeResult)
+		if err != nil || !ok {
+			return result, err
+		}
+
+		session, _ := req.GetSession().(*mcp.ServerSession)
+		listed, err := next(ctx, "tools/list", &mcp.ListToolsRequest{Session: session, Params: &mcp.ListToolsParams{}})
+		if err != nil {
+			log.Printf("failed to list tools for instructions: %v", err)
+			return result, nil
+		}
+		tools, _ := listed.(*mcp.ListToolsResult)
+		if tools == nil {
+			return result, nil
+		}
+
+		instructions, err := buildInstructions(tools.Tools)
+		if err != nil {
+			log.Printf("failed to build instructions: %v", err)
+			return result, nil
+		}
+		initialize.Instructions = instructions
+		return result, nil
+	}
+}
+
+// buildInstructions describes the vault's conventions to the model, from the active configuration
+func buildInstructions(tools []*mcp.Tool) (string, error) {
+	data := instructionsData{
+		VaultName:  filepath.Base(themisPath),
+		DateFormat: "YYYY-MM-DD",
+		Timezone:   location.String(),
+		Today:      now().In(location).Format("2006-01-02"),
+		ReadOnly:   true,
+		Tools:      tools,
+	}
+	if location == time.Local {
+		data.Timezone = "the server's local timezone"
+	}
+	if entryRoot != themisPath {
+		data.Scope = vaultRelative(entryRoot)
+	}
+
+	registered := map[string]bool{}
+	for _, tool := range tools {
+		registered[tool.Name] = true
+	}
+	for _, name := range writeTools {
+		if registered[name] {
+			data.WriteTools = append(data.WriteTools, name)
+			data.ReadOnly = false
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "This server gives access to the %q diary vault.", data.VaultName)
+	if data.Scope != "" {
+		fmt.Fprintf(&b, " Only entries in its %s folder are read unless a tool call passes another scope.", data.Scope)
+	}
+	b.WriteString(" Each entry is one markdown file per day named YYYY-MM-DD.md, or YYYY-MM-DD.md.age when age-encrypted.")
+	fmt.Fprintf(&b, "\n\nDates in parameters and results are always %s. Today is %s in %s, relative days such as \"yesterday\" should be resolved against it.",
+		data.DateFormat, data.Today, data.Timezone)
+	b.WriteString("\n\nEntries may start with a yaml frontmatter block. Tags come from its tags list and from inline #tags in the body, key:: value lines are inline fields and thread:: marks an ongoing storyline.")
+
+	var hints []string
+	for _, h := range toolHints {
+		if registered[h.tool] {
+			hints = append(hints, fmt.Sprintf("%s for %s", h.tool, h.hint))
+		}
+	}
+	if len(hints) > 0 {
+		fmt.Fprintf(&b, "\n\nPrefer %s.", strings.Join(hints, ", "))
+	}
+
+	if data.ReadOnly {
+		b.WriteString("\n\nThe server is read-only, no tool changes the vault.")
+	} else {
+		fmt.Fprintf(&b, "\n\nThese tools change files in the vault: %s. Use dryRun first where a tool offers it and confirm with the user before writing.",
+			strings.Join(data.WriteTools, ", "))
+	}
+
+	names := make([]string, len(tools))
+	for i, tool := range tools {
+		names[i] = tool.Name
+	}
+	fmt.Fprintf(&b, "\n\nAvailable tools: %s.", strings.Join(names, ", "))
+
+	data.Default = b.String()
+	if instructionsTemplate == nil {
+		return data.Default, nil
+	}
+
+	var out bytes.Buffer
+	if err := instructionsTemplate.Execute(&out, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
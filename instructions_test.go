@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func tools(names ...string) []*mcp.Tool {
+	list := make([]*mcp.Tool, len(names))
+	for i, name := range names {
+		list[i] = &mcp.Tool{Name: name}
+	}
+	return list
+}
+
+// instructionsVault is a vault named journal scoped to its daily folder, with the clock and timezone pinned
+func instructionsVault(t *testing.T) {
+	t.Helper()
+	dir := filepath.Join(newTestVault(t, nil), "journal")
+	writeTestFile(t, filepath.Join(dir, "daily", "2024-03-15.md"), "# Friday\n")
+	themisPath, entryRoot = dir, filepath.Join(dir, "daily")
+
+	oldLocation, oldTemplate := location, instructionsTemplate
+	location, instructionsTemplate = time.FixedZone("Europe/Vienna", 3600), nil
+	t.Cleanup(func() { location, instructionsTemplate = oldLocation, oldTemplate })
+	setTestNow(t, "2024-03-15T23:30:00Z")
+}
+
+func TestBuildInstructionsReadOnly(t *testing.T) {
+	instructionsVault(t)
+
+	got, err := buildInstructions(tools("getRecentEntries", "getThread", "getMetrics"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`the "journal" diary vault`,
+		"Only entries in its daily folder are read",
+		"Today is 2024-03-16 in Europe/Vienna",
+		"Prefer getRecentEntries for the last few days, getThread for one ongoing storyline.",
+		"The server is read-only, no tool changes the vault.",
+		"Available tools: getRecentEntries, getThread, getMetrics.",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("instructions do not say %q:\n%s", want, got)
+		}
+	}
+	// hints for tools that are not registered are left out
+	if strings.Contains(got, "getYearStats") || strings.Contains(got, "change files") {
+		t.Errorf("instructions mention unregistered or write tools:\n%s", got)
+	}
+}
+
+func TestBuildInstructionsReadWrite(t *testing.T) {
+	instructionsVault(t)
+	entryRoot = themisPath
+
+	got, err := buildInstructions(tools("getRecentEntries", "createEntry", "pinEntry"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, "These tools change files in the vault: createEntry, pinEntry.") {
+		t.Errorf("write tools not listed:\n%s", got)
+	}
+	if strings.Contains(got, "read-only") || strings.Contains(got, "Only entries in") {
+		t.Errorf("read-write unscoped vault described as read-only or scoped:\n%s", got)
+	}
+}
+
+func TestBuildInstructionsTemplate(t *testing.T) {
+	instructionsVault(t)
+	instructionsTemplate = template.Must(template.New("instructions").Parse(
+		"{{.Default}}\n\n{{.VaultName}} uses #w for work.{{if .ReadOnly}} Read-only.{{else}} Writes: {{range .WriteTools}}{{.}} {{end}}{{end}}"))
+
+	for _, tt := range []struct {
+		tools []*mcp.Tool
+		tail  string
+	}{
+		{tools("getRecentEntries"), "journal uses #w for work. Read-only."},
+		{tools("getRecentEntries", "replaceText"), "journal uses #w for work. Writes: replaceText "},
+	} {
+		got, err := buildInstructions(tt.tools)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(got, `This server gives access to the "journal" diary vault.`) || !strings.HasSuffix(got, tt.tail) {
+			t.Errorf("instructions = %q, want the default followed by %q", got, tt.tail)
+		}
+	}
+}
+
+func TestInitializeInstructions(t *testing.T) {
+	instructionsVault(t)
+
+	// the full server can write, a read-only one leaves the write tools out and announces itself read-only
+	readWrite := connectTestServer(t, newServer(false, false)).InitializeResult().Instructions
+	if !strings.Contains(readWrite, "These tools change files in the vault: "+strings.Join(writeTools, ", ")+".") {
+		t.Errorf("read-write instructions:\n%s", readWrite)
+	}
+
+	session := connectTestServer(t, newServer(false, true))
+	readOnly := session.InitializeResult().Instructions
+	if !strings.Contains(readOnly, "The server is read-only") || strings.Contains(readOnly, "createEntry") {
+		t.Errorf("read-only instructions:\n%s", readOnly)
+	}
+
+	// and it really is, every remaining tool runs with its defaults, which is where export style tools write.
+	// tools with required arguments are turned away, that doesn't matter here, only that nothing is written
+	before := vaultSnapshot(t, themisPath)
+	list, err := session.ListTools(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range writeTools {
+		if hasTool(t, session, name) {
+			t.Errorf("the read-only server registers %s", name)
+		}
+	}
+	for _, tool := range list.Tools {
+		session.CallTool(context.Background(), &mcp.CallToolParams{Name: tool.Name, Arguments: map[string]any{}})
+	}
+	if after := vaultSnapshot(t, themisPath); !reflect.DeepEqual(after, before) {
+		t.Errorf("a tool on the read-only server changed the vault:\nbefore %v\nafter  %v", before, after)
+	}
+}
+
+// vaultSnapshot maps every file under dir, hidden ones included, to its content
+func vaultSnapshot(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := map[string]string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		files[path] = readTestFile(t, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
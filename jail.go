@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// errPathOutsideVault is returned for any path that would escape the vault, surfaced to clients as PATH_OUTSIDE_VAULT
+var errPathOutsideVault = errors.New("PATH_OUTSIDE_VAULT")
+
+// resolveInVault turns a vault relative (or absolute) path built from user or model input into a path on disk,
+// refusing anything that ends up outside the vault once .. segments and symlinks are resolved.
+// the path itself may not exist yet, its closest existing ancestor is what gets checked.
+func resolveInVault(relOrName string) (string, error) {
+	path := relOrName
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(themisPath, path)
+	}
+	path = filepath.Clean(path)
+
+	root, err := filepath.EvalSymlinks(themisPath)
+	if err != nil {
+		root = filepath.Clean(themisPath)
+	}
+	if !isWithin(filepath.Clean(themisPath), path) && !isWithin(root, path) {
+		return "", fmt.Errorf("%w: %s", errPathOutsideVault, relOrName)
+	}
+
+	// walk up to the deepest part that exists and check where its symlinks really point
+	existing, rest := path, ""
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", relOrName, err)
+	}
+	if !isWithin(root, filepath.Join(resolved, rest)) {
+		return "", fmt.Errorf("%w: %s", errPathOutsideVault, relOrName)
+	}
+
+	return path, nil
+}
+
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func symlink(t *testing.T, target, link string) {
+	t.Helper()
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+}
+
+// jailVault returns a vault with links that stay inside it and links escaping to a sibling outside directory
+func jailVault(t *testing.T) (vault, outside string) {
+	t.Helper()
+	vault = newTestVault(t, map[string]string{
+		"2024-03-14.md":  "# Thursday\n",
+		"media/pier.png": "png",
+	})
+	outside = t.TempDir()
+	writeTestFile(t, filepath.Join(outside, "2024-03-15.md"), "# not part of the diary\n")
+	writeTestFile(t, filepath.Join(outside, "secret.txt"), "secret")
+
+	symlink(t, outside, filepath.Join(vault, "escape"))
+	symlink(t, filepath.Join(outside, "2024-03-15.md"), filepath.Join(vault, "2024-03-15.md"))
+	symlink(t, filepath.Join(vault, "media"), filepath.Join(vault, "photos"))
+	symlink(t, filepath.Join(vault, "2024-03-14.md"), filepath.Join(vault, "2024-03-16.md"))
+	return vault, outside
+}
+
+func TestResolveInVault(t *testing.T) {
+	vault, outside := jailVault(t)
+
+	allowed := []struct{ path, want string }{
+		{"2024-03-14.md", filepath.Join(vault, "2024-03-14.md")},
+		{"2024/03/2024-03-20.md", filepath.Join(vault, "2024", "03", "2024-03-20.md")},
+		{"media/../2024-03-14.md", filepath.Join(vault, "2024-03-14.md")},
+		{filepath.Join(vault, "media", "pier.png"), filepath.Join(vault, "media", "pier.png")},
+		{"photos/pier.png", filepath.Join(vault, "photos", "pier.png")},
+		{".", vault},
+	}
+	for _, tt := range allowed {
+		got, err := resolveInVault(tt.path)
+		if err != nil || got != tt.want {
+			t.Errorf("resolveInVault(%q) = %q, %v, want %q", tt.path, got, err, tt.want)
+		}
+	}
+
+	escapes := []string{
+		"..",
+		"../secret.txt",
+		"media/../../secret.txt",
+		filepath.Join(outside, "secret.txt"),
+		"escape/secret.txt",
+		"escape/not-there-yet/2024-03-20.md",
+		"2024-03-15.md",
+	}
+	for _, path := range escapes {
+		if got, err := resolveInVault(path); !errors.Is(err, errPathOutsideVault) {
+			t.Errorf("resolveInVault(%q) = %q, %v, want PATH_OUTSIDE_VAULT", path, got, err)
+		}
+	}
+}
+
+func TestResolveInVaultThroughSymlinkedVault(t *testing.T) {
+	target := newTestVault(t, map[string]string{"2024-03-14.md": "# Thursday\n"})
+	link := filepath.Join(t.TempDir(), "vault")
+	symlink(t, target, link)
+	themisPath, entryRoot = link, link
+
+	for _, path := range []string{"2024-03-14.md", filepath.Join(target, "2024-03-14.md")} {
+		if _, err := resolveInVault(path); err != nil {
+			t.Errorf("resolveInVault(%q): %v", path, err)
+		}
+	}
+	if _, err := resolveInVault(filepath.Join(filepath.Dir(target), "elsewhere.md")); !errors.Is(err, errPathOutsideVault) {
+		t.Errorf("sibling of the real vault: err = %v, want PATH_OUTSIDE_VAULT", err)
+	}
+}
+
+func TestWalkSkipsSymlinksOutOfTheVault(t *testing.T) {
+	jailVault(t)
+
+	_, output, err := handleGetEntriesByYear(context.Background(), nil, GetEntriesByYearInput{Year: 2024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the escaping file and folder links are skipped, the link to an entry inside the vault is read
+	if got := entryDates(output.Entries); !reflect.DeepEqual(got, []string{"2024-03-16", "2024-03-14"}) {
+		t.Errorf("dates = %v", got)
+	}
+}
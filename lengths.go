@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"io/fs"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const wordsPerMinute = 200
+
+// lengths caches per file word counts and the vault wide length distribution behind percentiles
+var lengths = &lengthIndex{files: map[string]indexedLength{}}
+
+type lengthIndex struct {
+	mu     sync.Mutex
+	files  map[string]indexedLength
+	sorted []int // word counts of every readable entry, ascending, nil when stale
+}
+
+type indexedLength struct {
+	stamp fileStamp
+	words int // -1 for locked entries, which are left out of the distribution
+}
+
+// words returns the word count for content read from path, reusing the count while the file is unchanged
+func (x *lengthIndex) words(path string, stamp fileStamp, content string) int {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if indexed, ok := x.files[path]; ok && sameStamp(indexed.stamp, stamp) {
+		return indexed.words
+	}
+	words := countWords(content)
+	x.files[path] = indexedLength{stamp: stamp, words: words}
+	x.sorted = nil
+	return words
+}
+
+// forget drops the word count of path so it is counted again on the next read
+func (x *lengthIndex) forget(path string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if _, ok := x.files[path]; ok {
+		delete(x.files, path)
+		x.sorted = nil
+	}
+}
+
+// distribution returns the sorted word counts of the vault, rebuilding them first when any entry file
+// was added, changed or removed since they were last built. ok is false when ctx ran out during the
+// rebuild, the counts read so far are kept so the next call picks up from there.
+func (x *lengthIndex) distribution(ctx context.Context) (sorted []int, ok bool) {
+	// a configured entry index seeds the counts, so only files it hasn't seen are read below. one stat pass
+	// serves both.
+	stamps := entryStamps()
+	vaultIndex.refreshFrom(stamps)
+
+	x.mu.Lock()
+	current := len(stamps) == len(x.files)
+	if current {
+		for path, stamp := range stamps {
+			if indexed, ok := x.files[path]; !ok || !sameStamp(indexed.stamp, stamp) {
+				current = false
+				break
+			}
+		}
+	}
+	// counts seeded from the entry index are current without having been read this run
+	if current && x.sorted == nil {
+		x.sorted = x.sortedWords()
+	}
+	sorted = x.sorted
+	x.mu.Unlock()
+	if current {
+		return sorted, true
+	}
+
+	// reading every entry goes through words, which refreshes the counts of changed files
+	locked := map[string]bool{}
+	progress, err := walkEntriesContext(ctx, func(date time.Time) bool { return true }, func(entry Entry) error {
+		if entry.Locked {
+			locked[entry.FilePath] = true
+		}
+		return nil
+	})
+	if err != nil || progress.Partial {
+		return nil, false
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for path := range x.files {
+		if _, ok := stamps[path]; !ok {
+			delete(x.files, path)
+		}
+	}
+	for path := range locked {
+		x.files[path] = indexedLength{stamp: stamps[path], words: -1}
+	}
+
+	x.sorted = x.sortedWords()
+	return x.sorted, true
+}
+
+// sortedWords must be called with mu held
+func (x *lengthIndex) sortedWords() []int {
+	sorted := make([]int, 0, len(x.files))
+	for _, indexed := range x.files {
+		if indexed.words >= 0 {
+			sorted = append(sorted, indexed.words)
+		}
+	}
+	sort.Ints(sorted)
+	return sorted
+}
+
+// applyLengthStats sets the vault percentile of each entry's length. it reports false and leaves percentiles
+// unset when ctx ran out before the distribution was complete.
+func applyLengthStats(ctx context.Context, entries []Entry) bool {
+	if len(entries) == 0 {
+		return true
+	}
+
+	sorted, ok := lengths.distribution(ctx)
+	if !ok {
+		return false
+	}
+	for i := range entries {
+		if entries[i].Locked || len(sorted) == 0 {
+			continue
+		}
+		atOrBelow := sort.Search(len(sorted), func(j int) bool { return sorted[j] > entries[i].WordCount })
+		entries[i].Percentile = math.Round(float64(atOrBelow)/float64(len(sorted))*1000) / 10
+	}
+	return true
+}
+
+func readingTime(words int) float64 {
+	return math.Round(float64(words)/wordsPerMinute*10) / 10
+}
+
+func sameStamp(a, b fileStamp) bool {
+	return a.size == b.size && a.modTime.Equal(b.modTime)
+}
+
+// entryStamps stats every entry file in scope without reading them
+func entryStamps() map[string]fileStamp {
+	return entryStampsIn(entryRoot)
+}
+
+// entryStampsIn is entryStamps for the entry files under root, leaving out temp files like the walk does
+func entryStampsIn(root string) map[string]fileStamp {
+	stamps := map[string]fileStamp{}
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() && isExportsDir(path) {
+			return filepath.SkipDir
+		}
+		if err != nil || d.IsDir() || !(strings.HasSuffix(d.Name(), ".md") || strings.HasSuffix(d.Name(), encryptedSuffix)) || isTempFile(d.Name()) {
+			return nil
+		}
+		dateStr := strings.TrimSuffix(strings.TrimSuffix(d.Name(), ".age"), ".md")
+		if _, err := time.Parse("2006-01-02", dateStr); err != nil {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			stamps[path] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		}
+		return nil
+	})
+	return stamps
+}
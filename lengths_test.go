@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/curator4/mcp-server-diary/query"
+)
+
+func TestLengthDistribution(t *testing.T) {
+	dir := newTestVault(t, map[string]string{
+		"2024-03-13.md": "one two three\n",
+		"2024-03-14.md": "one\n",
+		"2024-03-15.md": "one two\n",
+	})
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if sorted, ok := lengths.distribution(cancelled); ok || sorted != nil {
+		t.Fatalf("distribution under a cancelled context = %v, %v, want nothing", sorted, ok)
+	}
+
+	sorted, ok := lengths.distribution(context.Background())
+	if !ok || !reflect.DeepEqual(sorted, []int{1, 2, 3}) {
+		t.Fatalf("distribution = %v, %v", sorted, ok)
+	}
+
+	// a changed file is counted again
+	path := filepath.Join(dir, "2024-03-14.md")
+	writeTestFile(t, path, "one two three four five\n")
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	sorted, ok = lengths.distribution(context.Background())
+	if !ok || !reflect.DeepEqual(sorted, []int{2, 3, 5}) {
+		t.Errorf("distribution after an edit = %v, %v", sorted, ok)
+	}
+}
+
+func TestEntriesOutputPercentilesWithinBudget(t *testing.T) {
+	newTestVault(t, map[string]string{
+		"2024-03-13.md": "one two three four\n",
+		"2024-03-14.md": "one\n",
+		"2024-03-15.md": "one two\n",
+	})
+	result, err := entryEngine.Execute(context.Background(), query.Filter[Entry]{Start: time.Date(2024, time.March, 14, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the walk finished but the budget ran out before the entry outside the range was counted
+	expired := result
+	expired.Items = append([]Entry(nil), result.Items...)
+	expired.Deadline = time.Now().Add(-time.Second)
+	output := entriesOutput(context.Background(), entryQuery{}, expired)
+	if !output.Partial || output.Entries[0].Percentile != 0 {
+		t.Errorf("output = partial %v with percentile %v, want partial without percentiles", output.Partial, output.Entries[0].Percentile)
+	}
+
+	output = entriesOutput(context.Background(), entryQuery{}, result)
+	if output.Partial || output.Entries[0].Percentile != 66.7 || output.Entries[1].Percentile != 33.3 {
+		t.Errorf("output = partial %v with entries %+v", output.Partial, output.Entries)
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	defaultRateLimit     = 600 // calls per minute
+	defaultMaxConcurrent = 8
+)
+
+// tools that stay callable while the limiter is rejecting calls
+var rateLimitExempt = map[string]bool{"getMetrics": true}
+
+var limiter = newRateLimiter(getEnvInt("THEMIS_RATE_LIMIT", defaultRateLimit), getEnvInt("THEMIS_MAX_CONCURRENT", defaultMaxConcurrent))
+
+// tools that never walk the vault and so skip the scan limit, every other tool scans entry files
+var scanExempt = map[string]bool{"getMetrics": true, "continueResult": true, "setNow": true}
+
+// THEMIS_MAX_CONCURRENT_SCANS caps how many vault scans run at once, 0 (the default) is unlimited
+var scans = newScanLimiter(getEnvInt("THEMIS_MAX_CONCURRENT_SCANS", 0))
+
+// rateLimiter is a token bucket refilled continuously at perMinute tokens a minute, plus a cap on in-flight calls.
+// a zero perMinute or maxConcurrent disables that half of the limiter.
+type rateLimiter struct {
+	mu            sync.Mutex
+	perMinute     int
+	tokens        float64
+	lastRefill    time.Time
+	maxConcurrent int
+	inFlight      int
+	rejected      int
+}
+
+type LimiterState struct {
+	RatePerMinute   int `json:"ratePerMinute" jsonschema:"Configured calls per minute, 0 when unlimited"`
+	TokensAvailable int `json:"tokensAvailable" jsonschema:"Calls that can be made right now before being limited"`
+	MaxConcurrent   int `json:"maxConcurrent" jsonschema:"Configured cap on concurrently running tools, 0 when unlimited"`
+	InFlight        int `json:"inFlight" jsonschema:"Tool calls currently running"`
+	Rejected        int `json:"rejected" jsonschema:"Calls rejected as RATE_LIMITED since startup"`
+
+	MaxConcurrentScans int   `json:"maxConcurrentScans" jsonschema:"Configured cap on concurrent vault scans, 0 when unlimited"`
+	ScansRunning       int64 `json:"scansRunning" jsonschema:"Vault scans currently running"`
+	ScansWaiting       int64 `json:"scansWaiting" jsonschema:"Calls queued for a scan slot"`
+}
+
+// scanLimiter is a semaphore on vault scans. calls over the limit wait for a slot instead of being rejected.
+type scanLimiter struct {
+	slots   chan struct{} // nil when unlimited
+	running atomic.Int64
+	waiting atomic.Int64
+}
+
+func newRateLimiter(perMinute, maxConcurrent int) *rateLimiter {
+	return &rateLimiter{
+		perMinute:     perMinute,
+		tokens:        float64(perMinute),
+		lastRefill:    wallNow(),
+		maxConcurrent: maxConcurrent,
+	}
+}
+
+// acquire takes a token and an in-flight slot, returning how long to wait before retrying when neither is free.
+// release must be called once the call finishes if acquire succeeded.
+func (l *rateLimiter) acquire() (ok bool, retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill()
+	if l.maxConcurrent > 0 && l.inFlight >= l.maxConcurrent {
+		l.rejected++
+		return false, time.Second
+	}
+	if l.perMinute > 0 {
+		if l.tokens < 1 {
+			l.rejected++
+			perToken := time.Minute / time.Duration(l.perMinute)
+			return false, time.Duration(math.Ceil((1 - l.tokens) * float64(perToken)))
+		}
+		l.tokens--
+	}
+
+	l.inFlight++
+	return true, 0
+}
+
+func (l *rateLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight--
+}
+
+func (l *rateLimiter) refill() {
+	current := wallNow()
+	elapsed := current.Sub(l.lastRefill)
+	l.lastRefill = current
+	l.tokens = math.Min(float64(l.perMinute), l.tokens+elapsed.Minutes()*float64(l.perMinute))
+}
+
+func (l *rateLimiter) state() LimiterState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill()
+	return LimiterState{
+		RatePerMinute:      l.perMinute,
+		TokensAvailable:    int(l.tokens),
+		MaxConcurrent:      l.maxConcurrent,
+		InFlight:           l.inFlight,
+		Rejected:           l.rejected,
+		MaxConcurrentScans: cap(scans.slots),
+		ScansRunning:       scans.running.Load(),
+		ScansWaiting:       scans.waiting.Load(),
+	}
+}
+
+func newScanLimiter(max int) *scanLimiter {
+	s := &scanLimiter{}
+	if max > 0 {
+		s.slots = make(chan struct{}, max)
+	}
+	return s
+}
+
+// acquire blocks until a scan slot is free or ctx is done. release must be called if it returns nil.
+func (s *scanLimiter) acquire(ctx context.Context) error {
+	if s.slots != nil {
+		s.waiting.Add(1)
+		defer s.waiting.Add(-1)
+		select {
+		case s.slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	s.running.Add(1)
+	return nil
+}
+
+func (s *scanLimiter) release() {
+	s.running.Add(-1)
+	if s.slots != nil {
+		<-s.slots
+	}
+}
+
+// receivingMiddleware wraps every request, outermost first. the scan limiter sits outside the rate limiter so calls
+// queued for a scan slot don't hold one of the maxConcurrent in-flight slots and crowd out everything else.
+var receivingMiddleware = []mcp.Middleware{metricsMiddleware, scanLimitMiddleware, rateLimitMiddleware, instructionsMiddleware}
+
+// rateLimitMiddleware rejects tool calls over the limit with a RATE_LIMITED tool error instead of queueing them
+func rateLimitMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		call, ok := req.(*mcp.CallToolRequest)
+		if !ok || rateLimitExempt[call.Params.Name] {
+			return next(ctx, method, req)
+		}
+
+		ok, retryAfter := limiter.acquire()
+		if !ok {
+			seconds := int(math.Ceil(retryAfter.Seconds()))
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{&mcp.TextContent{
+					Text: fmt.Sprintf("RATE_LIMITED: too many tool calls, retry after %ds", seconds),
+				}},
+				Meta: mcp.Meta{"error": "RATE_LIMITED", "retryAfterSeconds": seconds},
+			}, nil
+		}
+		defer limiter.release()
+
+		return next(ctx, method, req)
+	}
+}
+
+// scanLimitMiddleware holds scanning tool calls until a scan slot is free, giving up when the client cancels
+func scanLimitMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		call, ok := req.(*mcp.CallToolRequest)
+		if !ok || scanExempt[call.Params.Name] {
+			return next(ctx, method, req)
+		}
+
+		if err := scans.acquire(ctx); err != nil {
+			return nil, fmt.Errorf("cancelled while waiting for a scan slot: %w", err)
+		}
+		defer scans.release()
+
+		return next(ctx, method, req)
+	}
+}
+
+func getEnvInt(name string, fallback int) int {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 0 {
+		log.Fatalf("%s must be a non-negative integer, got %q", name, raw)
+	}
+	return value
+}
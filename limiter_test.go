@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// withLimits swaps in the given limiters for the rest of the test
+func withLimits(t *testing.T, maxConcurrent, maxScans int) {
+	t.Helper()
+	oldLimiter, oldScans := limiter, scans
+	limiter, scans = newRateLimiter(0, maxConcurrent), newScanLimiter(maxScans)
+	t.Cleanup(func() { limiter, scans = oldLimiter, oldScans })
+}
+
+// chain wraps calls in receivingMiddleware the way the server does, outermost first. the tool listing
+// metricsMiddleware labels calls with is answered here, so calls only sees the tool calls themselves.
+func chain(calls mcp.MethodHandler) mcp.MethodHandler {
+	handler := func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		if method == "tools/list" {
+			return &mcp.ListToolsResult{}, nil
+		}
+		return calls(ctx, method, req)
+	}
+	for i := len(receivingMiddleware) - 1; i >= 0; i-- {
+		handler = receivingMiddleware[i](handler)
+	}
+	return handler
+}
+
+func callTool(name string) *mcp.CallToolRequest {
+	return &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{Name: name}}
+}
+
+func TestScansQueueWithoutBeingRateLimited(t *testing.T) {
+	const calls = 12
+	withLimits(t, 8, 1)
+
+	var running, most atomic.Int64
+	proceed := make(chan struct{})
+	handler := chain(func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		now := running.Add(1)
+		defer running.Add(-1)
+		if now > most.Load() {
+			most.Store(now)
+		}
+		<-proceed
+		return &mcp.CallToolResult{}, nil
+	})
+
+	var wg sync.WaitGroup
+	results := make([]*mcp.CallToolResult, calls)
+	for i := range calls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := handler(context.Background(), "tools/call", callTool("getEntriesByYear"))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			results[i] = result.(*mcp.CallToolResult)
+		}()
+	}
+
+	// every call but the running one waits for the scan slot without taking an in-flight slot
+	deadline := time.Now().Add(5 * time.Second)
+	for scans.waiting.Load() != calls-1 || running.Load() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("waiting = %d and running = %d, want %d waiting behind 1", scans.waiting.Load(), running.Load(), calls-1)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if state := limiter.state(); state.InFlight != 1 {
+		t.Errorf("in flight = %d, want only the running scan", state.InFlight)
+	}
+
+	// exempt tools still get through while the scans queue
+	if result, err := chain(func(context.Context, string, mcp.Request) (mcp.Result, error) {
+		return &mcp.CallToolResult{}, nil
+	})(context.Background(), "tools/call", callTool("getMetrics")); err != nil || result.(*mcp.CallToolResult).IsError {
+		t.Errorf("getMetrics = %+v, %v", result, err)
+	}
+
+	close(proceed)
+	wg.Wait()
+	for i, result := range results {
+		if result == nil || result.IsError {
+			t.Errorf("call %d = %+v, want success", i, result)
+		}
+	}
+	if most.Load() != 1 {
+		t.Errorf("%d scans ran at once, want 1", most.Load())
+	}
+	if rejected := limiter.state().Rejected; rejected != 0 {
+		t.Errorf("%d calls rejected, want none", rejected)
+	}
+}
+
+func TestRateLimitRejectsOverMaxConcurrent(t *testing.T) {
+	withLimits(t, 1, 0)
+
+	proceed := make(chan struct{})
+	started := make(chan struct{})
+	handler := chain(func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		close(started)
+		<-proceed
+		return &mcp.CallToolResult{}, nil
+	})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler(context.Background(), "tools/call", callTool("getEntriesByYear"))
+	}()
+	<-started
+
+	result, err := handler(context.Background(), "tools/call", callTool("getEntriesByYear"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rejected := result.(*mcp.CallToolResult)
+	if !rejected.IsError || rejected.Meta["error"] != "RATE_LIMITED" {
+		t.Errorf("second call = %+v, want RATE_LIMITED", rejected)
+	}
+	close(proceed)
+	<-done
+}
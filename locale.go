@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// localeNames holds the display strings of one language. longDate lays out a full date using
+// {weekday}, {day}, {month} and {year}.
+type localeNames struct {
+	weekdays [7]string // sunday first, like time.Weekday
+	months   [12]string
+	longDate string
+}
+
+var locales = map[string]localeNames{
+	"en": {
+		weekdays: [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+		months:   [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+		longDate: "{weekday}, {month} {day}, {year}",
+	},
+	"de": {
+		weekdays: [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		months:   [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		longDate: "{weekday}, {day}. {month} {year}",
+	},
+	"fr": {
+		weekdays: [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+		months:   [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		longDate: "{weekday} {day} {month} {year}",
+	},
+	"es": {
+		weekdays: [7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+		months:   [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		longDate: "{weekday}, {day} de {month} de {year}",
+	},
+	"nl": {
+		weekdays: [7]string{"zondag", "maandag", "dinsdag", "woensdag", "donderdag", "vrijdag", "zaterdag"},
+		months:   [12]string{"januari", "februari", "maart", "april", "mei", "juni", "juli", "augustus", "september", "oktober", "november", "december"},
+		longDate: "{weekday} {day} {month} {year}",
+	},
+}
+
+// displayLocale names weekdays and months in generated content. it is english until the flags are parsed, then
+// --locale or, without it, THEMIS_LOCALE picks it as a BCP-47 tag such as de or de-AT.
+// filenames and dates in tool output stay ISO whatever the locale.
+var displayLocale = locales["en"]
+
+// lookupLocale matches a BCP-47 tag on its language subtag, an empty tag is english
+func lookupLocale(tag string) (localeNames, error) {
+	if tag == "" {
+		return locales["en"], nil
+	}
+
+	language, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	if names, ok := locales[strings.ToLower(language)]; ok {
+		return names, nil
+	}
+
+	supported := make([]string, 0, len(locales))
+	for code := range locales {
+		supported = append(supported, code)
+	}
+	sort.Strings(supported)
+	return localeNames{}, fmt.Errorf("unsupported locale %q, supported languages are: %s", tag, strings.Join(supported, ", "))
+}
+
+func (l localeNames) weekday(d time.Weekday) string {
+	return l.weekdays[d]
+}
+
+func (l localeNames) month(m time.Month) string {
+	return l.months[m-1]
+}
+
+func (l localeNames) formatLongDate(t time.Time) string {
+	return strings.NewReplacer(
+		"{weekday}", l.weekday(t.Weekday()),
+		"{day}", strconv.Itoa(t.Day()),
+		"{month}", l.month(t.Month()),
+		"{year}", strconv.Itoa(t.Year()),
+	).Replace(l.longDate)
+}
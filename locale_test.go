@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocalizedNames(t *testing.T) {
+	date := time.Date(2024, time.March, 15, 0, 0, 0, 0, time.UTC)
+	const template = "# {{weekday}} {{date}}\n{{month}} {{year}}\n"
+
+	tests := []struct {
+		tag      string
+		template string
+		title    string
+	}{
+		{"", "# Friday 2024-03-15\nMarch 2024\n", "Friday, March 15, 2024"},
+		{"de-AT", "# Freitag 2024-03-15\nMärz 2024\n", "Freitag, 15. März 2024"},
+		{"fr_CA", "# vendredi 2024-03-15\nmars 2024\n", "vendredi 15 mars 2024"},
+	}
+	old := displayLocale
+	defer func() { displayLocale = old }()
+	for _, tt := range tests {
+		names, err := lookupLocale(tt.tag)
+		if err != nil {
+			t.Fatalf("lookupLocale(%q): %v", tt.tag, err)
+		}
+		displayLocale = names
+		if got := renderTemplate(template, date); got != tt.template {
+			t.Errorf("%q: template = %q, want %q", tt.tag, got, tt.template)
+		}
+		if got := entryTitle(Entry{Date: "2024-03-15"}); got != tt.title {
+			t.Errorf("%q: title = %q, want %q", tt.tag, got, tt.title)
+		}
+	}
+}
+
+func TestLookupLocaleUnsupported(t *testing.T) {
+	_, err := lookupLocale("ja")
+	if err == nil || !strings.Contains(err.Error(), "de, en, es, fr, nl") {
+		t.Errorf("err = %v, want the supported languages listed", err)
+	}
+}
+
+func TestLocaleFlagOverridesEnv(t *testing.T) {
+	t.Setenv("THEMIS_LOCALE", "xx")
+	old := displayLocale
+	defer func() { displayLocale = old }()
+
+	// an unsupported format stops the export right after the locale is picked
+	err := runExport([]string{"--locale", "de", "--format", "pdf"})
+	if err == nil || !strings.Contains(err.Error(), "unsupported export format") {
+		t.Fatalf("error = %v, want the format rejected, not the locale", err)
+	}
+	if displayLocale.weekday(time.Friday) != "Freitag" {
+		t.Errorf("display locale = %+v, want german", displayLocale)
+	}
+
+	if err := runExport([]string{"--format", "pdf"}); err == nil || !strings.Contains(err.Error(), `unsupported locale "xx"`) {
+		t.Errorf("error = %v, want THEMIS_LOCALE rejected without --locale", err)
+	}
+}
@@ -2,14 +2,20 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"io/fs"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sort"
 	"strings"
+	"syscall"
 	"time"
 
+	"github.com/curator4/mcp-server-diary/query"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
@@ -20,63 +26,729 @@ func getVaultPath() string {
 
 var themisPath = getVaultPath()
 
+// THEMIS_SCOPE limits where entries are read from to one subfolder of the vault, e.g. daily,
+// so notes in sibling folders never show up in date queries
+var entryRoot = getEntryRoot()
+
+func getEntryRoot() string {
+	root, err := resolveScope(os.Getenv("THEMIS_SCOPE"))
+	if err != nil {
+		log.Fatalf("invalid THEMIS_SCOPE: %v", err)
+	}
+	return root
+}
+
+// resolveScope turns a vault relative folder into the directory entries are read from, the whole vault when empty
+func resolveScope(scope string) (string, error) {
+	scope = strings.TrimSpace(scope)
+	if scope == "" {
+		return themisPath, nil
+	}
+
+	root, err := resolveInVault(filepath.FromSlash(scope))
+	if err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("%s is not a folder in the vault", scope)
+	}
+	return root, nil
+}
+
+// entryScope is the folder one call reads entries from, its scope input when given and THEMIS_SCOPE otherwise
+func entryScope(scope string) (string, error) {
+	if strings.TrimSpace(scope) == "" {
+		return entryRoot, nil
+	}
+	root, err := resolveScope(scope)
+	if err != nil {
+		return "", fmt.Errorf("invalid scope: %w", err)
+	}
+	return root, nil
+}
+
+// location decides where calendar days start and end, THEMIS_TIMEZONE takes an IANA name like Europe/Berlin
+var location = getLocation()
+
+func getLocation() *time.Location {
+	name := os.Getenv("THEMIS_TIMEZONE")
+	if name == "" {
+		return time.Local
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		log.Fatalf("invalid THEMIS_TIMEZONE %q: %v", name, err)
+	}
+	return loc
+}
+
 type Entry struct {
-	Date     string `json:"date" jsonschema:"Entry date in YYYY-MM-DD format"`
-	FilePath string `json:"path" jsonschema:"Full path to the diary entry file"`
-	Content  string `json:"content" jsonschema:"Full markdown content of the entry"`
+	Date     string `json:"date,omitempty" jsonschema:"Entry date in YYYY-MM-DD format"`
+	FilePath string `json:"path,omitempty" jsonschema:"Full path to the diary entry file"`
+	Content  string `json:"content,omitempty" jsonschema:"Full markdown content of the entry, left out when omitContent is set"`
+	Preview  string `json:"preview,omitempty" jsonschema:"Opening characters of the plaintext body when previewChars is set"`
+
+	ContentEncoding string `json:"contentEncoding,omitempty" jsonschema:"Set to base64 when content was base64 encoded on request, absent for plain text"`
+
+	Frontmatter        map[string]any      `json:"frontmatter,omitempty" jsonschema:"Parsed yaml frontmatter of the entry, if any"`
+	InlineFields       map[string][]string `json:"inlineFields,omitempty" jsonschema:"Dataview style key:: value fields from the body by lowercased key, kept apart from frontmatter"`
+	Warnings           []string            `json:"warnings,omitempty" jsonschema:"Problems noticed while reading the entry"`
+	WordCount          int                 `json:"wordCount,omitempty" jsonschema:"Words in the body, not counting frontmatter or markdown syntax"`
+	ReadingTimeMinutes float64             `json:"readingTimeMinutes,omitempty" jsonschema:"Estimated reading time at 200 words per minute"`
+	Percentile         float64             `json:"percentile,omitempty" jsonschema:"Percentage of vault entries at or below this entry's word count, 100 is the longest"`
+	EstimatedTokens    int                 `json:"estimatedTokens,omitempty" jsonschema:"Estimated model tokens needed for the content, or for the preview when content is omitted"`
+	Encrypted          bool                `json:"encrypted,omitempty" jsonschema:"Whether the entry is stored age-encrypted"`
+	Locked             bool                `json:"locked,omitempty" jsonschema:"Encrypted entry that could not be decrypted, only metadata is returned"`
+	Partial            bool                `json:"partial,omitempty" jsonschema:"Content is one piece of an entry split across chunked responses"`
+	Truncated          bool                `json:"truncated,omitempty" jsonschema:"Content was cut to maxContentLength, omissions are marked [...]"`
+	ContentOffset      int                 `json:"contentOffset,omitempty" jsonschema:"Byte offset of this piece within the full content of a partial entry"`
+	ModTime            string              `json:"modTime,omitempty" jsonschema:"When the file was last modified, RFC 3339 in the configured timezone"`
+	Conflicts          []string            `json:"conflicts,omitempty" jsonschema:"Vault relative paths of sync conflict copies of this entry"`
+	RelativeLabel      string              `json:"relativeLabel,omitempty" jsonschema:"The date relative to today, e.g. yesterday, 3 days ago or in 2 weeks, when includeRelativeLabels is set"`
+
+	modTime time.Time
 }
 
 type GetRecentEntriesInput struct {
-	Days int `json:"days" jsonschema:"Number of days to retrieve (e.g., 7 for last week)"`
+	Days      int    `json:"days,omitempty" jsonschema:"Number of days to retrieve (e.g., 7 for last week)"`
+	Months    int    `json:"months,omitempty" jsonschema:"Calendar months to go back from the start of today, combinable with days and years"`
+	Years     int    `json:"years,omitempty" jsonschema:"Calendar years to go back from the start of today, combinable with days and months"`
+	AsOf      string `json:"asOf,omitempty" jsonschema:"Compute the window as of this YYYY-MM-DD date instead of today"`
+	MaxTokens int    `json:"maxTokens,omitempty" jsonschema:"Drop the oldest entries once the estimated token total would exceed this budget"`
+
+	Tags     []string `json:"tags,omitempty" jsonschema:"Only entries carrying every one of these tags, e.g. [\"work\"]"`
+	Text     string   `json:"text,omitempty" jsonschema:"Only entries containing this text, compared case-insensitively"`
+	Weekdays []string `json:"weekdays,omitempty" jsonschema:"Only entries written on these weekdays, e.g. [\"saturday\", \"sunday\"]"`
+
+	PreviewChars     int  `json:"previewChars,omitempty" jsonschema:"Add a plaintext preview of this many characters to each entry"`
+	OmitContent      bool `json:"omitContent,omitempty" jsonschema:"Leave out the full content, e.g. together with previewChars for list views"`
+	StripFrontmatter bool `json:"stripFrontmatter,omitempty" jsonschema:"Remove the --- frontmatter block from content, the parsed frontmatter field is still returned"`
+
+	MaxContentLength int    `json:"maxContentLength,omitempty" jsonschema:"Cut each entry's content to this many characters, frontmatter not counted"`
+	TruncateStrategy string `json:"truncateStrategy,omitempty" jsonschema:"How maxContentLength cuts: head (default) keeps the start, tail the end, structured every heading plus the first sentence of each section"`
+
+	IncludeRelativeLabels bool `json:"includeRelativeLabels,omitempty" jsonschema:"Label each entry relative to today, e.g. yesterday, 3 days ago or last week"`
+	ContentBlocks         bool `json:"contentBlocks,omitempty" jsonschema:"Also return each entry as a formatted text block under a date heading for inline display, the structured result is unchanged"`
+
+	Fields          []string `json:"fields,omitempty" jsonschema:"Entry fields to return, e.g. [\"date\", \"content\"], defaults to the server's configured set"`
+	ContentEncoding string   `json:"contentEncoding,omitempty" jsonschema:"plain (default) or base64 to base64 encode content for byte-faithful transport"`
+	TimeoutMs       int      `json:"timeoutMs,omitempty" jsonschema:"Time budget for this call in milliseconds, partial results are returned when it runs out (default 10000)"`
+	Scope           string   `json:"scope,omitempty" jsonschema:"Vault subfolder to read entries from, e.g. daily, in place of the server's THEMIS_SCOPE, . for the whole vault"`
+}
+
+type GetEntriesByYearInput struct {
+	Year        int  `json:"year" jsonschema:"Year to fetch (e.g., 2023)"`
+	OmitContent bool `json:"omitContent,omitempty" jsonschema:"Leave out the full content, returning only entry metadata"`
+	MaxTokens   int  `json:"maxTokens,omitempty" jsonschema:"Drop the oldest entries once the estimated token total would exceed this budget"`
+
+	Tags     []string `json:"tags,omitempty" jsonschema:"Only entries carrying every one of these tags, e.g. [\"work\"]"`
+	Text     string   `json:"text,omitempty" jsonschema:"Only entries containing this text, compared case-insensitively"`
+	Weekdays []string `json:"weekdays,omitempty" jsonschema:"Only entries written on these weekdays, e.g. [\"saturday\", \"sunday\"]"`
+
+	PreviewChars     int  `json:"previewChars,omitempty" jsonschema:"Add a plaintext preview of this many characters to each entry"`
+	StripFrontmatter bool `json:"stripFrontmatter,omitempty" jsonschema:"Remove the --- frontmatter block from content, the parsed frontmatter field is still returned"`
+
+	MaxContentLength int    `json:"maxContentLength,omitempty" jsonschema:"Cut each entry's content to this many characters, frontmatter not counted"`
+	TruncateStrategy string `json:"truncateStrategy,omitempty" jsonschema:"How maxContentLength cuts: head (default) keeps the start, tail the end, structured every heading plus the first sentence of each section"`
+
+	IncludeRelativeLabels bool `json:"includeRelativeLabels,omitempty" jsonschema:"Label each entry relative to today, e.g. yesterday, 3 days ago or last week"`
+	ContentBlocks         bool `json:"contentBlocks,omitempty" jsonschema:"Also return each entry as a formatted text block under a date heading for inline display, the structured result is unchanged"`
+
+	Fields          []string `json:"fields,omitempty" jsonschema:"Entry fields to return, e.g. [\"date\", \"content\"], defaults to the server's configured set"`
+	ContentEncoding string   `json:"contentEncoding,omitempty" jsonschema:"plain (default) or base64 to base64 encode content for byte-faithful transport"`
+	TimeoutMs       int      `json:"timeoutMs,omitempty" jsonschema:"Time budget for this call in milliseconds, partial results are returned when it runs out (default 10000)"`
+	Scope           string   `json:"scope,omitempty" jsonschema:"Vault subfolder to read entries from, e.g. daily, in place of the server's THEMIS_SCOPE, . for the whole vault"`
+}
+
+// maxRecentYears caps how far back getRecentEntries reaches
+const maxRecentYears = 100
+
+// maxBatchDates caps how many days getEntriesByDates fetches in one call
+const maxBatchDates = 50
+
+type GetEntriesByDatesInput struct {
+	Dates       []string `json:"dates" jsonschema:"Dates to fetch in YYYY-MM-DD format, at most 50"`
+	OmitContent bool     `json:"omitContent,omitempty" jsonschema:"Leave out the full content, returning only entry metadata"`
+	MaxTokens   int      `json:"maxTokens,omitempty" jsonschema:"Drop the oldest entries once the estimated token total would exceed this budget"`
+
+	PreviewChars     int  `json:"previewChars,omitempty" jsonschema:"Add a plaintext preview of this many characters to each entry"`
+	StripFrontmatter bool `json:"stripFrontmatter,omitempty" jsonschema:"Remove the --- frontmatter block from content, the parsed frontmatter field is still returned"`
+
+	MaxContentLength int    `json:"maxContentLength,omitempty" jsonschema:"Cut each entry's content to this many characters, frontmatter not counted"`
+	TruncateStrategy string `json:"truncateStrategy,omitempty" jsonschema:"How maxContentLength cuts: head (default) keeps the start, tail the end, structured every heading plus the first sentence of each section"`
+
+	IncludeRelativeLabels bool `json:"includeRelativeLabels,omitempty" jsonschema:"Label each entry relative to today, e.g. yesterday, 3 days ago or last week"`
+	ContentBlocks         bool `json:"contentBlocks,omitempty" jsonschema:"Also return each entry as a formatted text block under a date heading for inline display, the structured result is unchanged"`
+
+	Fields          []string `json:"fields,omitempty" jsonschema:"Entry fields to return, e.g. [\"date\", \"content\"], defaults to the server's configured set"`
+	ContentEncoding string   `json:"contentEncoding,omitempty" jsonschema:"plain (default) or base64 to base64 encode content for byte-faithful transport"`
+	Scope           string   `json:"scope,omitempty" jsonschema:"Vault subfolder to read entries from, e.g. daily, in place of the server's THEMIS_SCOPE, . for the whole vault"`
+}
+
+// EntriesByDatesOutput returns its entries in the order the dates were requested
+type EntriesByDatesOutput struct {
+	EntriesOutput
+	Missing []string `json:"missing" jsonschema:"Requested dates without an entry, in request order"`
 }
 
 type EntriesOutput struct {
-	Entries []Entry `json:"entries" jsonschema:"List of diary entries, sorted newest first"`
+	Entries []Entry `json:"entries" jsonschema:"List of diary entries, newest first unless the tool says otherwise"`
 	Count   int     `json:"count" jsonschema:"Total number of entries returned"`
+
+	TotalEstimatedTokens int      `json:"totalEstimatedTokens" jsonschema:"Sum of the estimated tokens of the returned entries"`
+	Dropped              []string `json:"dropped,omitempty" jsonschema:"Dates left out to fit maxTokens"`
+	ContinuationToken    string   `json:"continuationToken,omitempty" jsonschema:"Set when the response was too large, pass it to continueResult for the next chunk"`
+
+	Partial      bool `json:"partial,omitempty" jsonschema:"The time budget ran out, so only some entry files were scanned or length percentiles are missing"`
+	ScannedFiles int  `json:"scannedFiles,omitempty" jsonschema:"Entry files scanned before the time budget ran out"`
+	TotalFiles   int  `json:"totalFiles,omitempty" jsonschema:"Entry files the query covers in total"`
+
+	Cutoff string `json:"cutoff,omitempty" jsonschema:"Earliest date the window covers in YYYY-MM-DD format"`
 }
 
 func main() {
-	server := mcp.NewServer(&mcp.Implementation{Name: "themis", Version: "V1.0.0"}, nil)
-	mcp.AddTool(server, &mcp.Tool{Name: "getRecentEntries", Description: "fetches diary entries from the latest N number of days"}, handleGetRecentEntries)
+	if len(os.Args) > 1 && os.Args[1] == "--doctor" {
+		doctor := flag.NewFlagSet("themis --doctor", flag.ExitOnError)
+		repair := doctor.Bool("repair", false, "replay interrupted writes and remove their temp files before checking, and test writing with a temp file")
+		doctor.Parse(os.Args[2:])
+		if !runDoctor(os.Stdout, *repair) {
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		if err := runExport(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	flags := flag.NewFlagSet("themis", flag.ExitOnError)
+	autoCreate := flags.Bool("auto-create-daily", autoCreateDaily, "create today's entry from the template on startup and at local midnight")
+	fakeNow := flags.String("fake-now", "", "pin the clock to this RFC 3339 time or YYYY-MM-DD date, for debugging")
+	testing := flags.Bool("testing", false, "expose the setNow tool so integration tests can pin the clock")
+	metricsAddr := flags.String("metrics-addr", "", "serve prometheus metrics on this address, e.g. :9464, off when empty")
+	webhookEvents := flags.String("webhook-events", os.Getenv("THEMIS_WEBHOOK_EVENTS"), "comma separated entry events that fire the webhook: create, modify, delete (default all)")
+	readOnly := flags.Bool("read-only", readOnlyVault, "leave out every tool that changes the vault")
+	locale := flags.String("locale", os.Getenv("THEMIS_LOCALE"), "BCP-47 language tag for weekday and month names in created entries, e.g. de")
+	flags.Parse(os.Args[1:])
+
+	names, err := lookupLocale(*locale)
+	if err != nil {
+		log.Fatalf("invalid --locale or THEMIS_LOCALE: %v", err)
+	}
+	displayLocale = names
+	if *fakeNow != "" {
+		t, err := parseFakeNow(*fakeNow)
+		if err != nil {
+			log.Fatal(err)
+		}
+		setClock(fixedClock(t))
+	}
+
+	if err := checkEntryRoot(); err != nil {
+		log.Printf("warning: %v", err)
+	}
+	recoverWAL()
+	if vaultIndex.enabled() {
+		// brought up to date in the background, the first indexed query waits for it
+		go vaultIndex.refresh()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *autoCreate && *readOnly {
+		log.Fatal("--auto-create-daily writes entries and can't be combined with --read-only")
+	}
+	if *autoCreate {
+		done := startDailyRollover(ctx)
+		defer func() {
+			stop()
+			<-done
+		}()
+	}
+
+	if *metricsAddr != "" {
+		done, err := serveMetrics(ctx, *metricsAddr)
+		if err != nil {
+			log.Fatalf("failed to serve metrics on %s: %v", *metricsAddr, err)
+		}
+		defer func() {
+			stop()
+			<-done
+		}()
+	}
+
+	if webhookURL != "" || webhookCommand != "" {
+		events, err := parseWebhookEvents(*webhookEvents)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !watchEnabled {
+			log.Printf("webhook configured but THEMIS_WATCH is off, no events will fire")
+		} else {
+			// started before the watcher so its deferred close runs after the watcher has stopped queueing
+			activeWebhooks = newWebhookDispatcher(events)
+			defer activeWebhooks.close()
+		}
+	}
+
+	if watchEnabled {
+		watcher, err := newVaultWatcher(themisPath)
+		if err != nil {
+			log.Fatalf("failed to watch %s: %v", themisPath, err)
+		}
+		defer watcher.close()
+		activeWatcher = watcher
+	}
 
-	if err := server.Run(context.Background(), &mcp.StdioTransport{}); err != nil {
+	server := newServer(*testing, *readOnly)
+	if err := server.Run(ctx, &mcp.StdioTransport{}); err != nil && ctx.Err() == nil {
 		log.Fatal(err)
 	}
 }
 
+// newServer registers every tool, leaving out writeTools when readOnly is set and adding setNow when testing is
+func newServer(testing, readOnly bool) *mcp.Server {
+	server := mcp.NewServer(&mcp.Implementation{Name: "themis", Version: "V1.0.0"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "getRecentEntries", Description: "fetches diary entries from the latest N number of days"}, handleGetRecentEntries)
+	mcp.AddTool(server, &mcp.Tool{Name: "getYearStats", Description: "aggregates a year of entries into monthly counts, top tags, streaks and longest entries for a year in review"}, handleGetYearStats)
+	mcp.AddTool(server, &mcp.Tool{Name: "getLengthTrend", Description: "returns the average entry word count per month, to see whether entries are getting longer over time"}, handleGetLengthTrend)
+	mcp.AddTool(server, &mcp.Tool{Name: "getEntryStats", Description: "returns word, character, line, tag, link and task counts for a single entry"}, handleGetEntryStats)
+	mcp.AddTool(server, &mcp.Tool{Name: "getOutline", Description: "returns the heading hierarchy and per-section word counts of entries in a date range, without their content"}, handleGetOutline)
+	mcp.AddTool(server, &mcp.Tool{Name: "getEntryAttachments", Description: "lists the images and media embedded in an entry and flags references to files that are missing"}, handleGetEntryAttachments)
+	mcp.AddTool(server, &mcp.Tool{Name: "auditAttachments", Description: "finds attachment files no entry references and entry references to attachments that do not exist"}, handleAuditAttachments)
+	mcp.AddTool(server, &mcp.Tool{Name: "getHighlights", Description: "collects ==highlighted== text and blockquotes from entries in a date range, with their source date and surrounding sentence"}, handleGetHighlights)
+	mcp.AddTool(server, &mcp.Tool{Name: "getMentions", Description: "finds when a person was mentioned (e.g. @Alice or [[People/Alice]]), with dates, counts and snippets"}, handleGetMentions)
+	mcp.AddTool(server, &mcp.Tool{Name: "listPeople", Description: "lists every person mentioned in the diary with how often they come up"}, handleListPeople)
+	mcp.AddTool(server, &mcp.Tool{Name: "getMetrics", Description: "reports tool call counts, the rate limiter state and content cache statistics"}, handleGetMetrics)
+	mcp.AddTool(server, &mcp.Tool{Name: "findSimilar", Description: "finds the entries most similar in wording to a given entry using tf-idf cosine similarity"}, handleFindSimilar)
+	mcp.AddTool(server, &mcp.Tool{Name: "getMoodTrend", Description: "returns a rolling n-day average of the numeric mood frontmatter field"}, handleGetMoodTrend)
+	mcp.AddTool(server, &mcp.Tool{Name: "getLongestGap", Description: "finds the longest stretch of days without an entry and how long it has been since the last one"}, handleGetLongestGap)
+	mcp.AddTool(server, &mcp.Tool{Name: "continueResult", Description: "returns the next chunk of a response that was too large to send at once"}, handleContinueResult)
+	mcp.AddTool(server, &mcp.Tool{Name: "getEntriesModifiedToday", Description: "returns entries whose files were modified today in the configured timezone, whatever their entry date"}, handleGetEntriesModifiedToday)
+	mcp.AddTool(server, &mcp.Tool{Name: "getRecentActivity", Description: "lists the most recently modified entry files, newest first, flagging past entries that were edited later"}, handleGetRecentActivity)
+	mcp.AddTool(server, &mcp.Tool{Name: "getMorningBriefing", Description: "returns recent entries, the open todos in them and the top tags of the last week in one call, for a daily review"}, handleGetMorningBriefing)
+	mcp.AddTool(server, &mcp.Tool{Name: "validateVault", Description: "reports structural problems in the vault such as sync conflict copies"}, handleValidateVault)
+	mcp.AddTool(server, &mcp.Tool{Name: "searchFrontmatter", Description: "finds entries whose frontmatter field value matches a regular expression, e.g. location matching Kyoto"}, handleSearchFrontmatter)
+	mcp.AddTool(server, &mcp.Tool{Name: "filterEntriesWithField", Description: "returns entries whose frontmatter has the given key with any value, e.g. every day a mood was recorded"}, handleFilterEntriesWithField)
+	mcp.AddTool(server, &mcp.Tool{Name: "getTimeline", Description: "splits an entry into its ## HH:MM timestamped sub-entries, or for a date range returns the sub-entry times per day and per hour"}, handleGetTimeline)
+	mcp.AddTool(server, &mcp.Tool{Name: "getEntryVersions", Description: "lists the git commits that changed an entry, following renames, when the vault is a git repository"}, handleGetEntryVersions)
+	mcp.AddTool(server, &mcp.Tool{Name: "getEntryAtCommit", Description: "returns the content of an entry as it was at a given git commit"}, handleGetEntryAtCommit)
+	mcp.AddTool(server, &mcp.Tool{Name: "getTimeOfDayDistribution", Description: "counts entries by the hour of day in their created frontmatter timestamp, to show when journaling usually happens"}, handleGetTimeOfDayDistribution)
+	mcp.AddTool(server, &mcp.Tool{Name: "diagnose", Description: "checks the vault path, entry naming, write access, file watching, the length index and timezone, with a fix for each problem"}, handleDiagnose)
+	mcp.AddTool(server, &mcp.Tool{Name: "getEntryNeighborhood", Description: "returns the entry on a date together with the existing entries up to N days before and after it, oldest first"}, handleGetEntryNeighborhood)
+	mcp.AddTool(server, &mcp.Tool{Name: "getRelatedEntries", Description: "ranks other entries by shared tags, wikilinks and mentions with an explanation of the overlap, without a semantic index, leaving out private entries"}, handleGetRelatedEntries)
+	mcp.AddTool(server, &mcp.Tool{Name: "getTagStats", Description: "aggregates entry counts, words written and first and last use per tag, with tags merged case-insensitively"}, handleGetTagStats)
+	mcp.AddTool(server, &mcp.Tool{Name: "getEntriesByYear", Description: "fetches every entry written in a given year, newest first"}, handleGetEntriesByYear)
+	mcp.AddTool(server, &mcp.Tool{Name: "getPinned", Description: "returns pinned entries newest first together with their pin notes"}, handleGetPinned)
+	mcp.AddTool(server, &mcp.Tool{Name: "listThreads", Description: "lists storylines marked with a thread:: inline field, with entry counts and the dates each thread spans"}, handleListThreads)
+	mcp.AddTool(server, &mcp.Tool{Name: "getThread", Description: "returns every entry of one thread:: storyline oldest first, or just their outlines, for narrative summaries"}, handleGetThread)
+	mcp.AddTool(server, &mcp.Tool{Name: "getVocabularyGrowth", Description: "returns, per month, how many distinct words have been used in entries so far"}, handleGetVocabularyGrowth)
+	mcp.AddTool(server, &mcp.Tool{Name: "getEntriesByDates", Description: "fetches entries for a list of specific dates in the order given, reporting dates without an entry as missing"}, handleGetEntriesByDates)
+	mcp.AddTool(server, &mcp.Tool{Name: "findNonConforming", Description: "finds entries missing any of the given headings, e.g. ones written before a daily template was adopted"}, handleFindNonConforming)
+	mcp.AddTool(server, &mcp.Tool{Name: "getEntryByOffset", Description: "returns the nth most recent entry, 0 being the latest, for \"the entry before last\" style navigation"}, handleGetEntryByOffset)
+	if !readOnly {
+		mcp.AddTool(server, &mcp.Tool{Name: "addTagToRange", Description: "adds a tag to the frontmatter of every entry in a date range, skipping entries that already have it"}, handleAddTagToRange)
+		mcp.AddTool(server, &mcp.Tool{Name: "createEntry", Description: "creates a new diary entry, filled from the weekday's template unless content is given"}, handleCreateEntry)
+		mcp.AddTool(server, &mcp.Tool{Name: "backfillEntries", Description: "creates template-only skeleton entries for a list of missed dates in one call"}, handleBackfillEntries)
+		mcp.AddTool(server, &mcp.Tool{Name: "exportHTML", Description: "renders entries in a date range to html, either a static site under exports/html with chronological and per-tag indexes or a single document with a table of contents"}, handleExportHTML)
+		mcp.AddTool(server, &mcp.Tool{Name: "exportToDirectory", Description: "writes each entry in a date range as its own markdown or json file under the vault's exports folder and returns a manifest of the files written"}, handleExportToDirectory)
+		mcp.AddTool(server, &mcp.Tool{Name: "resolveConflict", Description: "shows the diff between an entry and its sync conflict copy and can keep one side, keep both or merge in the lines only the copy added"}, handleResolveConflict)
+		mcp.AddTool(server, &mcp.Tool{Name: "resolveConflicts", Description: "finds every sync conflict copy in the vault and either merges the lines only the copy has into its dated entry or moves the copy to the trash"}, handleResolveConflicts)
+		mcp.AddTool(server, &mcp.Tool{Name: "replaceText", Description: "finds and replaces literal text or a regex across entries in a date range, leaving frontmatter alone unless asked, with a dry run"}, handleReplaceText)
+		mcp.AddTool(server, &mcp.Tool{Name: "pinEntry", Description: "pins an entry you return to often, with an optional note on why"}, handlePinEntry)
+		mcp.AddTool(server, &mcp.Tool{Name: "unpinEntry", Description: "removes the pin from an entry"}, handleUnpinEntry)
+		mcp.AddTool(server, &mcp.Tool{Name: "splitEntry", Description: "splits an entry made of several dated sections into one entry per date heading, optionally archiving the original"}, handleSplitEntry)
+	}
+	if testing {
+		mcp.AddTool(server, &mcp.Tool{Name: "setNow", Description: "pins the server clock to a given moment, or resets it to real time, for tests"}, handleSetNow)
+	}
+
+	server.AddReceivingMiddleware(receivingMiddleware...)
+	return server
+}
+
 // handlers
 func handleGetRecentEntries(ctx context.Context, req *mcp.CallToolRequest, input GetRecentEntriesInput) (
 	*mcp.CallToolResult,
 	EntriesOutput,
 	error,
 ) {
-	cutoff := time.Now().AddDate(0, 0, -input.Days)
-	entries, err := getEntries(func(date time.Time) bool {
-		return date.After(cutoff) || date.Equal(cutoff)
+	if input.Days < 0 || input.Months < 0 || input.Years < 0 || input.Days+input.Months+input.Years == 0 {
+		return nil, EntriesOutput{}, fmt.Errorf("days, months and years must not be negative and at least one must be positive")
+	}
+	reference, err := asOfTime(input.AsOf)
+	if err != nil {
+		return nil, EntriesOutput{}, err
+	}
+
+	// a window in days alone counts back from the current moment, months and years go back from the start of the day
+	cutoff := reference.AddDate(0, 0, -input.Days)
+	if input.Months > 0 || input.Years > 0 {
+		// asOf is already a plain date, today is taken in the configured timezone
+		day := reference
+		if input.AsOf == "" {
+			day = reference.In(location)
+		}
+		cutoff = time.Date(day.Year()-input.Years, day.Month()-time.Month(input.Months), day.Day()-input.Days, 0, 0, 0, 0, time.UTC)
+	}
+	if cutoff.Before(reference.AddDate(-maxRecentYears, 0, 0)) {
+		return nil, EntriesOutput{}, fmt.Errorf("the window can span at most %d years", maxRecentYears)
+	}
+	weekdays, err := parseWeekdays(input.Weekdays)
+	if err != nil {
+		return nil, EntriesOutput{}, err
+	}
+	filter := query.Filter[Entry]{Start: firstDayFrom(cutoff), Weekdays: weekdays, Tags: input.Tags, Text: input.Text, Fields: input.Fields}
+	if input.AsOf != "" {
+		filter.End = time.Date(reference.Year(), reference.Month(), reference.Day(), 0, 0, 0, 0, time.UTC)
+	}
+
+	output, err := runEntryQuery(ctx, entryQuery{
+		filter: filter,
+		shape: contentShape{
+			stripFrontmatter: input.StripFrontmatter,
+			previewChars:     input.PreviewChars,
+			omitContent:      input.OmitContent,
+			maxContentLength: input.MaxContentLength,
+			truncateStrategy: input.TruncateStrategy,
+			encoding:         input.ContentEncoding,
+		},
+		relativeLabels: input.IncludeRelativeLabels,
+		maxTokens:      input.MaxTokens,
+		timeoutMs:      input.TimeoutMs,
+		scope:          input.Scope,
 	})
 	if err != nil {
-		return nil, EntriesOutput{}, fmt.Errorf("failed to get entries: %w", err)
+		return nil, EntriesOutput{}, err
+	}
+	output.Cutoff = firstDayFrom(cutoff).Format("2006-01-02")
+	return entryBlocks(input.ContentBlocks, output.Entries), output, nil
+}
+
+func handleGetEntriesByYear(ctx context.Context, req *mcp.CallToolRequest, input GetEntriesByYearInput) (
+	*mcp.CallToolResult,
+	EntriesOutput,
+	error,
+) {
+	if input.Year < 1 || input.Year > 9999 {
+		return nil, EntriesOutput{}, fmt.Errorf("invalid year %d", input.Year)
+	}
+	weekdays, err := parseWeekdays(input.Weekdays)
+	if err != nil {
+		return nil, EntriesOutput{}, err
 	}
 
-	return nil, EntriesOutput{Entries: entries, Count: len(entries)}, nil
+	output, err := runEntryQuery(ctx, entryQuery{
+		filter: query.Filter[Entry]{
+			Start:    time.Date(input.Year, time.January, 1, 0, 0, 0, 0, time.UTC),
+			End:      time.Date(input.Year, time.December, 31, 0, 0, 0, 0, time.UTC),
+			Weekdays: weekdays,
+			Tags:     input.Tags,
+			Text:     input.Text,
+			Fields:   input.Fields,
+		},
+		shape: contentShape{
+			stripFrontmatter: input.StripFrontmatter,
+			previewChars:     input.PreviewChars,
+			omitContent:      input.OmitContent,
+			maxContentLength: input.MaxContentLength,
+			truncateStrategy: input.TruncateStrategy,
+			encoding:         input.ContentEncoding,
+		},
+		relativeLabels: input.IncludeRelativeLabels,
+		maxTokens:      input.MaxTokens,
+		timeoutMs:      input.TimeoutMs,
+		scope:          input.Scope,
+	})
+	if err != nil {
+		return nil, EntriesOutput{}, err
+	}
+	return entryBlocks(input.ContentBlocks, output.Entries), output, nil
+}
+
+func handleGetEntriesByDates(ctx context.Context, req *mcp.CallToolRequest, input GetEntriesByDatesInput) (
+	*mcp.CallToolResult,
+	EntriesByDatesOutput,
+	error,
+) {
+	if len(input.Dates) == 0 {
+		return nil, EntriesByDatesOutput{}, fmt.Errorf("at least one date is required")
+	}
+	if len(input.Dates) > maxBatchDates {
+		return nil, EntriesByDatesOutput{}, fmt.Errorf("at most %d dates can be fetched at once, got %d", maxBatchDates, len(input.Dates))
+	}
+
+	// every date is checked before anything is read so a typo doesn't return a silently short result
+	var dates []time.Time
+	var invalid []string
+	for _, dateStr := range input.Dates {
+		date, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			invalid = append(invalid, fmt.Sprintf("%q", dateStr))
+			continue
+		}
+		dates = append(dates, date)
+	}
+	if len(invalid) > 0 {
+		return nil, EntriesByDatesOutput{}, fmt.Errorf("invalid dates %s, expected YYYY-MM-DD", strings.Join(invalid, ", "))
+	}
+
+	q := entryQuery{
+		filter: query.Filter[Entry]{Dates: dates, Order: query.OldestFirst, Fields: input.Fields},
+		shape: contentShape{
+			stripFrontmatter: input.StripFrontmatter,
+			previewChars:     input.PreviewChars,
+			omitContent:      input.OmitContent,
+			maxContentLength: input.MaxContentLength,
+			truncateStrategy: input.TruncateStrategy,
+			encoding:         input.ContentEncoding,
+		},
+		relativeLabels: input.IncludeRelativeLabels,
+		maxTokens:      input.MaxTokens,
+		scope:          input.Scope,
+	}
+	result, err := findEntries(ctx, q)
+	if err != nil {
+		return nil, EntriesByDatesOutput{}, err
+	}
+
+	// one walk reads each distinct date once, repeated dates reuse the same entry
+	byDate := map[string]Entry{}
+	for _, entry := range result.Items {
+		if _, ok := byDate[entry.Date]; !ok {
+			byDate[entry.Date] = entry
+		}
+	}
+	var ordered []Entry
+	missing := []string{}
+	for _, dateStr := range input.Dates {
+		if entry, ok := byDate[dateStr]; ok {
+			ordered = append(ordered, entry)
+		} else {
+			missing = append(missing, dateStr)
+		}
+	}
+	result.Items = ordered
+
+	output := EntriesByDatesOutput{EntriesOutput: entriesOutput(ctx, q, result), Missing: missing}
+	return entryBlocks(input.ContentBlocks, output.Entries), output, nil
 }
 
 // helpers
-func getEntries(filter func(date time.Time) bool) ([]Entry, error) {
-	var entries []Entry
+
+// firstDayFrom is the earliest entry date at or after t, entry dates being midnight utc
+func firstDayFrom(t time.Time) time.Time {
+	day := time.Date(t.UTC().Year(), t.UTC().Month(), t.UTC().Day(), 0, 0, 0, 0, time.UTC)
+	if day.Before(t) {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+// asOfTime resolves an optional YYYY-MM-DD override to the last moment of that day, defaulting to now
+func asOfTime(asOf string) (time.Time, error) {
+	if asOf == "" {
+		return now(), nil
+	}
+
+	date, err := time.Parse("2006-01-02", asOf)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid asOf date %q, expected YYYY-MM-DD", asOf)
+	}
+	return date.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+}
+
+// getEntryByDate returns the entry for a single YYYY-MM-DD date
+func getEntryByDate(dateStr string) (Entry, error) {
+	date, err := time.Parse("2006-01-02", dateStr)
+	if err != nil {
+		return Entry{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", dateStr)
+	}
+
+	var found *Entry
+	err = walkEntries(func(d time.Time) bool {
+		return d.Equal(date)
+	}, func(entry Entry) error {
+		found = &entry
+		return filepath.SkipAll
+	})
+	if err != nil {
+		return Entry{}, fmt.Errorf("failed to get entries: %w", err)
+	}
+	if found == nil {
+		return Entry{}, fmt.Errorf("no entry found for %s", dateStr)
+	}
+
+	return *found, nil
+}
+
+// listEntryDates returns the distinct entry dates in the vault, oldest first, without reading any content
+func listEntryDates() ([]time.Time, error) {
+	all, err := entryFileDates(func(date time.Time) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[time.Time]bool{}
+	var dates []time.Time
+	for _, date := range all {
+		if !seen[date] {
+			seen[date] = true
+			dates = append(dates, date)
+		}
+	}
+	return dates, nil
+}
+
+// entryFileDates returns the date of every entry file the filter accepts, oldest first, from the entry index
+// when one is configured and from the file names otherwise
+func entryFileDates(filter func(date time.Time) bool) ([]time.Time, error) {
+	var dates []time.Time
+	records, indexed, err := vaultIndex.entries(filter)
+	if indexed {
+		for _, record := range records {
+			if date, err := time.Parse("2006-01-02", record.Date); err == nil {
+				dates = append(dates, date)
+			}
+		}
+		return dates, err
+	}
+	return entryFileDatesIn(entryRoot, filter)
+}
+
+// entryFileDatesIn is entryFileDates from the file names under root, without the index
+func entryFileDatesIn(root string, filter func(date time.Time) bool) ([]time.Time, error) {
+	var dates []time.Time
+	_, err := walkEntriesIn(context.Background(), root, func(date time.Time) bool {
+		if filter(date) {
+			dates = append(dates, date)
+		}
+		return false
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	return dates, nil
+}
+
+// parseDateRange reads optional YYYY-MM-DD bounds, a missing bound comes back as the zero time
+func parseDateRange(start, end string) (time.Time, time.Time, error) {
+	var from, to time.Time
+	var err error
+	if start != "" {
+		if from, err = time.Parse("2006-01-02", start); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid start date %q, expected YYYY-MM-DD", start)
+		}
+	}
+	if end != "" {
+		if to, err = time.Parse("2006-01-02", end); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid end date %q, expected YYYY-MM-DD", end)
+		}
+	}
+	if start != "" && end != "" && to.Before(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("end date %s is before start date %s", end, start)
+	}
+	return from, to, nil
+}
+
+// sortEntries orders entries by date, breaking ties by path
+func sortEntries(entries []Entry, newestFirst bool) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if newestFirst {
+			a, b = b, a
+		}
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		return a.FilePath < b.FilePath
+	})
+}
+
+// hasTag reports whether the entry carries tag, compared case-insensitively
+func hasTag(entry Entry, tag string) bool {
+	tag = strings.TrimPrefix(tag, "#")
+	_, body, _ := splitFrontmatter(entry.Content)
+	for _, t := range extractTags(entry.Frontmatter, body) {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkEntryRoot explains a vault that can't be walked, which filepath.WalkDir would otherwise turn into an
+// empty result. a missing vault is left alone and reads as one without entries.
+func checkEntryRoot() error {
+	info, err := os.Stat(entryRoot)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil
+	case errors.Is(err, fs.ErrPermission):
+		return fmt.Errorf("vault path %s is not readable, give the server user read permission on it", entryRoot)
+	case err != nil:
+		return fmt.Errorf("vault path %s is not accessible: %w", entryRoot, err)
+	case !info.IsDir():
+		return fmt.Errorf("vault path %s is a file, expected a directory", entryRoot)
+	}
+
+	dir, err := os.Open(entryRoot)
+	if err == nil {
+		_, err = dir.Readdirnames(1)
+		dir.Close()
+	}
+	if errors.Is(err, fs.ErrPermission) {
+		return fmt.Errorf("vault path %s is not readable, give the server user read permission on it", entryRoot)
+	}
+	return nil
+}
+
+// walkEntries streams every entry passing filter to fn without holding the whole vault in memory
+func walkEntries(filter func(date time.Time) bool, fn func(entry Entry) error) error {
+	_, err := walkEntriesContext(context.Background(), filter, fn)
+	return err
+}
+
+// walkEntriesContext is walkEntries stopping early once ctx is done, progress says how far it got
+func walkEntriesContext(ctx context.Context, filter func(date time.Time) bool, fn func(entry Entry) error) (walkProgress, error) {
+	return walkEntriesIn(ctx, entryRoot, filter, fn)
+}
+
+// walkEntriesIn is walkEntriesContext over the entries under root instead of the configured scope
+func walkEntriesIn(ctx context.Context, root string, filter func(date time.Time) bool, fn func(entry Entry) error) (walkProgress, error) {
+	var progress walkProgress
+	if err := checkEntryRoot(); err != nil {
+		return progress, err
+	}
+	start := wallNow()
+	defer func() { promWalkDuration.Observe(wallNow().Sub(start).Seconds()) }()
+
+	// conflict copies are looked up once per directory, the first time an entry in it is visited
+	conflictsByDir := map[string]map[string][]string{}
 
 	// recursively walk through themis folder
-	err := filepath.WalkDir(themisPath, func(path string, d fs.DirEntry, err error) error {
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			log.Printf("error accessing %s: %v", path, err)
+			promReadErrors.Inc()
 			return nil
 		}
+		if d.IsDir() && isExportsDir(path) {
+			return filepath.SkipDir
+		}
 
-		if d.IsDir() || !strings.HasSuffix(path, ".md") {
+		encrypted := strings.HasSuffix(d.Name(), encryptedSuffix)
+		if d.IsDir() || !(strings.HasSuffix(d.Name(), ".md") || encrypted) || isTempFile(d.Name()) {
 			return nil
 		}
 
-		dateStr := strings.TrimSuffix(d.Name(), ".md")
+		dateStr := strings.TrimSuffix(strings.TrimSuffix(d.Name(), ".age"), ".md")
 		date, err := time.Parse("2006-01-02", dateStr)
 		if err != nil {
 			return nil
@@ -85,21 +757,93 @@ func getEntries(filter func(date time.Time) bool) ([]Entry, error) {
 		if !filter(date) {
 			return nil
 		}
+		if ctx.Err() != nil {
+			progress.Partial = true
+			return filepath.SkipAll
+		}
+		progress.Scanned++
+		promFilesScanned.Inc()
+
+		// symlinked entries are only followed while they stay inside the vault
+		if d.Type()&fs.ModeSymlink != 0 {
+			if _, err := resolveInVault(path); err != nil {
+				log.Printf("skipping %s: %v", path, err)
+				return nil
+			}
+		}
 
-		content, err := os.ReadFile(path)
+		content, err := cache.read(path)
 		if err != nil {
 			log.Printf("error reading %s: %v", path, err)
+			promReadErrors.Inc()
 			return nil
 		}
 
-		entries = append(entries, Entry{
-			Date:     dateStr,
-			FilePath: path,
-			Content:  string(content),
-		})
+		var stamp fileStamp
+		if info, err := d.Info(); err == nil {
+			stamp = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		}
+		modTime := stamp.modTime
 
-		return nil
+		dir := filepath.Dir(path)
+		if _, ok := conflictsByDir[dir]; !ok {
+			conflictsByDir[dir] = findConflicts(dir)
+		}
+		conflicts := conflictsByDir[dir][dateStr]
+
+		if encrypted {
+			content, err = decryptEntry(content)
+			if err != nil {
+				// never hand out ciphertext, locked entries are metadata only
+				entry := Entry{Date: dateStr, FilePath: path, Encrypted: true, Locked: true, Conflicts: conflicts}
+				entry.setModTime(modTime)
+				if !errors.Is(err, errNoIdentity) {
+					entry.Warnings = append(entry.Warnings, fmt.Sprintf("failed to decrypt: %v", err))
+				}
+				return fn(entry)
+			}
+		}
+
+		text, warning := decodeContent(content)
+		entry := Entry{
+			Date:            dateStr,
+			FilePath:        path,
+			Content:         text,
+			EstimatedTokens: estimateTokens(text),
+			Encrypted:       encrypted,
+			Conflicts:       conflicts,
+		}
+		entry.WordCount = lengths.words(path, stamp, text)
+		entry.ReadingTimeMinutes = readingTime(entry.WordCount)
+		entry.setModTime(modTime)
+		if warning != "" {
+			entry.Warnings = append(entry.Warnings, warning)
+		}
+
+		fm, body, err := parseFrontmatter(entry.Content)
+		if err != nil {
+			entry.Warnings = append(entry.Warnings, fmt.Sprintf("invalid frontmatter: %v", err))
+		}
+		entry.Frontmatter = fm
+		entry.InlineFields = parseInlineFields(body)
+		if warning := checkDateConsistency(dateStr, fm); warning != "" {
+			entry.Warnings = append(entry.Warnings, warning)
+		}
+
+		return fn(entry)
 	})
+	return progress, err
+}
+
+func (e *Entry) setModTime(t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	e.modTime = t
+	e.ModTime = t.In(location).Format(time.RFC3339)
+}
 
-	return entries, err
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
 }
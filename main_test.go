@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetRecentEntriesWindow(t *testing.T) {
+	newTestVault(t, map[string]string{
+		"2023-03-15.md": "a year ago\n",
+		"2024-01-15.md": "january\n",
+		"2024-02-14.md": "valentine's\n",
+		"2024-02-15.md": "a month ago\n",
+		"2024-03-14.md": "yesterday\n",
+		"2024-03-15.md": "today\n",
+	})
+	oldLocation := location
+	location = time.UTC
+	defer func() { location = oldLocation }()
+	setTestNow(t, "2024-03-15T12:00:00Z")
+
+	tests := []struct {
+		input  GetRecentEntriesInput
+		cutoff string
+		want   []string
+	}{
+		// days alone count back from now and start at the first whole day
+		{GetRecentEntriesInput{Days: 1}, "2024-03-15", []string{"2024-03-15"}},
+		{GetRecentEntriesInput{Months: 1}, "2024-02-15", []string{"2024-03-15", "2024-03-14", "2024-02-15"}},
+		{GetRecentEntriesInput{Months: 1, Days: 1}, "2024-02-14", []string{"2024-03-15", "2024-03-14", "2024-02-15", "2024-02-14"}},
+		{GetRecentEntriesInput{Years: 1}, "2023-03-15", []string{"2024-03-15", "2024-03-14", "2024-02-15", "2024-02-14", "2024-01-15", "2023-03-15"}},
+		// asOf ends the window on that day
+		{GetRecentEntriesInput{Months: 1, AsOf: "2024-03-14"}, "2024-02-14", []string{"2024-03-14", "2024-02-15", "2024-02-14"}},
+		{GetRecentEntriesInput{Days: 30, AsOf: "2024-02-15"}, "2024-01-17", []string{"2024-02-15", "2024-02-14"}},
+	}
+	for _, tt := range tests {
+		_, output, err := handleGetRecentEntries(context.Background(), nil, tt.input)
+		if err != nil {
+			t.Errorf("%+v: %v", tt.input, err)
+			continue
+		}
+		if output.Cutoff != tt.cutoff || !reflect.DeepEqual(entryDates(output.Entries), tt.want) {
+			t.Errorf("%+v: cutoff %s with %v, want %s with %v", tt.input, output.Cutoff, entryDates(output.Entries), tt.cutoff, tt.want)
+		}
+	}
+
+	for _, bad := range []GetRecentEntriesInput{{}, {Days: -1}, {Days: 1, AsOf: "15/03/2024"}, {Years: maxRecentYears + 1}} {
+		if _, _, err := handleGetRecentEntries(context.Background(), nil, bad); err == nil {
+			t.Errorf("%+v: want an error", bad)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+type Heading struct {
+	Level int    `json:"level" jsonschema:"Heading level from 1 to 6"`
+	Text  string `json:"text" jsonschema:"Heading text without markdown markers"`
+	Words int    `json:"words" jsonschema:"Words in the section up to the next heading"`
+
+	line      int // index of the heading text line
+	bodyStart int // index of the first line after the heading
+}
+
+var (
+	atxHeadingPattern      = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	setextUnderlinePattern = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	listOrQuotePattern     = regexp.MustCompile(`^ {0,3}([-*+>]|\d+[.)])(\s|$)`)
+)
+
+// parseHeadings finds atx and setext headings in a markdown body, skipping fenced code.
+// preamble is the number of words before the first heading.
+func parseHeadings(body string) (headings []Heading, preamble int) {
+	lines := strings.Split(body, "\n")
+	inFence := false
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if isFenceLine(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		if match := atxHeadingPattern.FindStringSubmatch(line); match != nil {
+			headings = append(headings, Heading{Level: len(match[1]), Text: strings.TrimSpace(match[2]), line: i, bodyStart: i + 1})
+			continue
+		}
+
+		if i == 0 || !setextUnderlinePattern.MatchString(line) {
+			continue
+		}
+		prev := strings.TrimRight(lines[i-1], "\r")
+		if strings.TrimSpace(prev) == "" || isFenceLine(prev) || atxHeadingPattern.MatchString(prev) || listOrQuotePattern.MatchString(prev) {
+			continue
+		}
+		if n := len(headings); n > 0 && headings[n-1].bodyStart == i {
+			// the previous line is itself a heading
+			continue
+		}
+
+		level := 2
+		if strings.TrimSpace(line)[0] == '=' {
+			level = 1
+		}
+		headings = append(headings, Heading{Level: level, Text: strings.TrimSpace(prev), line: i - 1, bodyStart: i + 1})
+	}
+
+	wordsIn := func(from, to int) int {
+		count := 0
+		for _, line := range lines[from:to] {
+			count += len(strings.Fields(line))
+		}
+		return count
+	}
+
+	end := len(lines)
+	for i := len(headings) - 1; i >= 0; i-- {
+		headings[i].Words = wordsIn(min(headings[i].bodyStart, end), end)
+		end = headings[i].line
+	}
+	preamble = wordsIn(0, end)
+
+	return headings, preamble
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// default mention conventions: @Name preceded by a word boundary (so emails don't match) and [[People/Name]] links
+var defaultMentionPatterns = []string{
+	`(?:^|[^\p{L}\p{N}_.@/])@([\p{L}\p{N}_][\p{L}\p{N}_.-]*)`,
+	`\[\[(?:[^\]|#]*/)?People/([^\]|#]+)(?:[#|][^\]]*)?\]\]`,
+}
+
+// mention patterns can be overridden with a json array of regexes, each capturing the name in its first group
+var mentionPatterns = getMentionPatterns()
+
+const defaultMentionsLimit = 50
+
+type GetMentionsInput struct {
+	Name  string `json:"name" jsonschema:"Person to look up, with or without the leading @"`
+	Start string `json:"start,omitempty" jsonschema:"First date to include in YYYY-MM-DD format"`
+	End   string `json:"end,omitempty" jsonschema:"Last date to include in YYYY-MM-DD format"`
+	Limit int    `json:"limit,omitempty" jsonschema:"Maximum number of mentions to return (default 50)"`
+}
+
+type Mention struct {
+	Date    string `json:"date" jsonschema:"Date of the entry with the mention"`
+	Snippet string `json:"snippet" jsonschema:"Sentence around the mention"`
+}
+
+type MentionsOutput struct {
+	Name      string    `json:"name" jsonschema:"The person looked up"`
+	Count     int       `json:"count" jsonschema:"Total number of mentions"`
+	Entries   int       `json:"entries" jsonschema:"Number of entries mentioning the person"`
+	FirstDate string    `json:"firstDate,omitempty" jsonschema:"Date of the earliest mention"`
+	LastDate  string    `json:"lastDate,omitempty" jsonschema:"Date of the latest mention"`
+	Mentions  []Mention `json:"mentions" jsonschema:"Mentions sorted newest first"`
+}
+
+type ListPeopleInput struct {
+	Start string `json:"start,omitempty" jsonschema:"First date to include in YYYY-MM-DD format"`
+	End   string `json:"end,omitempty" jsonschema:"Last date to include in YYYY-MM-DD format"`
+}
+
+type PersonCount struct {
+	Name     string `json:"name" jsonschema:"Person as first written"`
+	Count    int    `json:"count" jsonschema:"Total number of mentions"`
+	Entries  int    `json:"entries" jsonschema:"Number of entries mentioning the person"`
+	LastDate string `json:"lastDate" jsonschema:"Date of the latest mention"`
+}
+
+type ListPeopleOutput struct {
+	People []PersonCount `json:"people" jsonschema:"Mentioned people, most mentioned first"`
+	Count  int           `json:"count" jsonschema:"Total number of people returned"`
+}
+
+// handlers
+func handleGetMentions(ctx context.Context, req *mcp.CallToolRequest, input GetMentionsInput) (
+	*mcp.CallToolResult,
+	MentionsOutput,
+	error,
+) {
+	name := normalizeMention(input.Name)
+	if name == "" {
+		return nil, MentionsOutput{}, fmt.Errorf("name is required")
+	}
+	filter, err := rangeFilter(input.Start, input.End)
+	if err != nil {
+		return nil, MentionsOutput{}, err
+	}
+	limit := input.Limit
+	if limit <= 0 {
+		limit = defaultMentionsLimit
+	}
+
+	result, err := entryEngine.Execute(ctx, filter)
+	if err != nil {
+		return nil, MentionsOutput{}, fmt.Errorf("failed to get entries: %w", err)
+	}
+
+	output := MentionsOutput{Name: input.Name, Mentions: []Mention{}}
+	for _, entry := range result.Items {
+		found := false
+		for _, mention := range findMentions(entry.Content) {
+			if !strings.EqualFold(mention.name, name) {
+				continue
+			}
+
+			found = true
+			output.Count++
+			if len(output.Mentions) < limit {
+				output.Mentions = append(output.Mentions, Mention{Date: entry.Date, Snippet: mention.snippet})
+			}
+		}
+
+		if found {
+			output.Entries++
+			if output.LastDate == "" {
+				output.LastDate = entry.Date
+			}
+			output.FirstDate = entry.Date
+		}
+	}
+
+	return nil, output, nil
+}
+
+func handleListPeople(ctx context.Context, req *mcp.CallToolRequest, input ListPeopleInput) (
+	*mcp.CallToolResult,
+	ListPeopleOutput,
+	error,
+) {
+	filter, err := rangeFilter(input.Start, input.End)
+	if err != nil {
+		return nil, ListPeopleOutput{}, err
+	}
+
+	people := map[string]*PersonCount{}
+	err = eachEntry(ctx, filter, func(entry Entry) error {
+		seen := map[string]bool{}
+		for _, mention := range findMentions(entry.Content) {
+			key := strings.ToLower(mention.name)
+			person, ok := people[key]
+			if !ok {
+				person = &PersonCount{Name: mention.name}
+				people[key] = person
+			}
+
+			person.Count++
+			if !seen[key] {
+				seen[key] = true
+				person.Entries++
+			}
+			if entry.Date > person.LastDate {
+				person.LastDate = entry.Date
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, ListPeopleOutput{}, fmt.Errorf("failed to get entries: %w", err)
+	}
+
+	output := ListPeopleOutput{People: []PersonCount{}}
+	for _, person := range people {
+		output.People = append(output.People, *person)
+	}
+	sort.Slice(output.People, func(i, j int) bool {
+		if output.People[i].Count != output.People[j].Count {
+			return output.People[i].Count > output.People[j].Count
+		}
+		return output.People[i].Name < output.People[j].Name
+	})
+	output.Count = len(output.People)
+
+	return nil, output, nil
+}
+
+// helpers
+func getMentionPatterns() []*regexp.Regexp {
+	sources := defaultMentionPatterns
+	if raw := os.Getenv("THEMIS_MENTION_PATTERNS"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &sources); err != nil {
+			log.Fatalf("THEMIS_MENTION_PATTERNS must be a json array of regexes: %v", err)
+		}
+	}
+
+	var patterns []*regexp.Regexp
+	for _, source := range sources {
+		pattern, err := regexp.Compile(source)
+		if err != nil {
+			log.Fatalf("invalid mention pattern %q: %v", source, err)
+		}
+		if pattern.NumSubexp() < 1 {
+			log.Fatalf("mention pattern %q must capture the name in a group", source)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns
+}
+
+type mentionMatch struct {
+	name    string
+	snippet string
+}
+
+// findMentions returns every mention in the entry body, skipping fenced code
+func findMentions(content string) []mentionMatch {
+	_, body, _ := splitFrontmatter(content)
+
+	var mentions []mentionMatch
+	inFence := false
+	for _, line := range strings.Split(body, "\n") {
+		if isFenceLine(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		for _, pattern := range mentionPatterns {
+			for _, loc := range pattern.FindAllStringSubmatchIndex(line, -1) {
+				raw := line[loc[2]:loc[3]]
+				name := normalizeMention(raw)
+				if name == "" {
+					continue
+				}
+				// a full stop ending the match belongs to the sentence, as in "lunch with @Anna."
+				end := loc[1]
+				if loc[3] == end {
+					end -= len(raw) - len(strings.TrimRight(raw, ".-"))
+				}
+				mentions = append(mentions, mentionMatch{name: name, snippet: surroundingSentence(line, loc[0], end)})
+			}
+		}
+	}
+
+	return mentions
+}
+
+func normalizeMention(name string) string {
+	name = strings.TrimPrefix(strings.TrimSpace(name), "@")
+	return strings.TrimRight(name, ".-")
+}
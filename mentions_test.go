@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// mentionVault writes people as @names and People links, next to an email address and a mention in code
+func mentionVault(t *testing.T) {
+	t.Helper()
+	newTestVault(t, map[string]string{
+		"2024-03-10.md": "Lunch with @Anna. Then mail to bob@example.com.\n",
+		"2024-03-12.md": "Called [[People/anna]] again. @Ben joined.\n```\n@Anna in code\n```\n",
+		"2024-03-14.md": "@anna and @anna-marie walked.\n",
+	})
+}
+
+func TestGetMentions(t *testing.T) {
+	mentionVault(t)
+	ctx := context.Background()
+
+	_, output, err := handleGetMentions(ctx, nil, GetMentionsInput{Name: "@anna"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := MentionsOutput{
+		Name:      "@anna",
+		Count:     3,
+		Entries:   3,
+		FirstDate: "2024-03-10",
+		LastDate:  "2024-03-14",
+		Mentions: []Mention{
+			{Date: "2024-03-14", Snippet: "@anna and @anna-marie walked."},
+			{Date: "2024-03-12", Snippet: "Called [[People/anna]] again."},
+			{Date: "2024-03-10", Snippet: "Lunch with @Anna."},
+		},
+	}
+	if !reflect.DeepEqual(output, want) {
+		t.Errorf("anna = %+v, want %+v", output, want)
+	}
+
+	// the limit caps the mentions returned, not the counts
+	_, output, err = handleGetMentions(ctx, nil, GetMentionsInput{Name: "anna", Limit: 1, End: "2024-03-12"})
+	if err != nil || len(output.Mentions) != 1 || output.Mentions[0].Date != "2024-03-12" || output.Count != 2 || output.FirstDate != "2024-03-10" {
+		t.Errorf("limited = %+v, %v", output, err)
+	}
+
+	_, output, err = handleGetMentions(ctx, nil, GetMentionsInput{Name: "bob"})
+	if err != nil || output.Count != 0 || output.Mentions == nil {
+		t.Errorf("an email address counted as a mention: %+v, %v", output, err)
+	}
+	if _, _, err := handleGetMentions(ctx, nil, GetMentionsInput{Name: " @ "}); err == nil {
+		t.Error("accepted an empty name")
+	}
+
+	// a person never mentioned is an empty list, which the output schema requires
+	session := connectTestServer(t, newServer(false, false))
+	if _, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "getMentions", Arguments: map[string]any{"name": "zoe"}}); err != nil {
+		t.Errorf("getMentions without mentions: %v", err)
+	}
+}
+
+func TestListPeople(t *testing.T) {
+	mentionVault(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		input ListPeopleInput
+		want  []PersonCount
+	}{
+		{
+			name:  "most mentioned first, then by name as first written",
+			input: ListPeopleInput{},
+			want: []PersonCount{
+				{Name: "Anna", Count: 3, Entries: 3, LastDate: "2024-03-14"},
+				{Name: "Ben", Count: 1, Entries: 1, LastDate: "2024-03-12"},
+				{Name: "anna-marie", Count: 1, Entries: 1, LastDate: "2024-03-14"},
+			},
+		},
+		{
+			name:  "range",
+			input: ListPeopleInput{Start: "2024-03-13"},
+			want: []PersonCount{
+				{Name: "anna", Count: 1, Entries: 1, LastDate: "2024-03-14"},
+				{Name: "anna-marie", Count: 1, Entries: 1, LastDate: "2024-03-14"},
+			},
+		},
+		{
+			name:  "nobody",
+			input: ListPeopleInput{End: "2024-03-01"},
+			want:  []PersonCount{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, output, err := handleListPeople(ctx, nil, tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(output.People, tt.want) || output.Count != len(tt.want) {
+				t.Errorf("people = %+v, want %+v", output.People, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var metrics = &serverMetrics{toolCalls: map[string]int{}}
+
+// unknownTool labels calls to tools the server doesn't have
+const unknownTool = "unknown"
+
+// prometheus counters, only exposed when --metrics-addr is set
+var (
+	promRegistry = prometheus.NewRegistry()
+
+	promToolCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "themis_tool_calls_total",
+		Help: "Tool calls by tool name, including rejected ones.",
+	}, []string{"tool"})
+	promToolDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "themis_tool_duration_seconds",
+		Help:    "Time spent handling tool calls by tool name.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"tool"})
+	promFilesScanned = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "themis_files_scanned_total",
+		Help: "Entry files visited by vault scans.",
+	})
+	promReadErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "themis_read_errors_total",
+		Help: "Files or directories that could not be read during vault scans.",
+	})
+	promWalkDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "themis_walk_duration_seconds",
+		Help:    "Duration of each walk over the entry files, single date lookups included.",
+		Buckets: prometheus.DefBuckets,
+	})
+	promWebhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "themis_webhook_deliveries_total",
+		Help: "Webhook deliveries by result, delivered, failed or dropped.",
+	}, []string{"result"})
+)
+
+func init() {
+	promRegistry.MustRegister(promToolCalls, promToolDuration, promFilesScanned, promReadErrors, promWalkDuration, promWebhookDeliveries)
+}
+
+type serverMetrics struct {
+	mu        sync.Mutex
+	toolCalls map[string]int
+}
+
+type GetMetricsInput struct{}
+
+type MetricsOutput struct {
+	ToolCalls map[string]int `json:"toolCalls" jsonschema:"Number of calls per tool since startup"`
+	Limiter   LimiterState   `json:"limiter" jsonschema:"Current rate limiter state"`
+	Cache     CacheState     `json:"cache" jsonschema:"Content cache hits, misses and size"`
+	Webhooks  *WebhookState  `json:"webhooks,omitempty" jsonschema:"Webhook deliveries, only present when a webhook is configured"`
+	Index     *IndexState    `json:"index,omitempty" jsonschema:"Entry index size and last refresh, only present when THEMIS_INDEX_PATH is set"`
+}
+
+// handlers
+func handleGetMetrics(ctx context.Context, req *mcp.CallToolRequest, input GetMetricsInput) (
+	*mcp.CallToolResult,
+	MetricsOutput,
+	error,
+) {
+	metrics.mu.Lock()
+	calls := make(map[string]int, len(metrics.toolCalls))
+	for name, count := range metrics.toolCalls {
+		calls[name] = count
+	}
+	metrics.mu.Unlock()
+
+	output := MetricsOutput{ToolCalls: calls, Limiter: limiter.state(), Cache: cache.state()}
+	if activeWebhooks != nil {
+		state := activeWebhooks.state()
+		output.Webhooks = &state
+	}
+	if vaultIndex.enabled() {
+		state := vaultIndex.state()
+		output.Index = &state
+	}
+	return nil, output, nil
+}
+
+// helpers
+
+// metricsMiddleware counts and times every tool call by name, including rejected ones. names the server has no
+// tool for are counted as unknown, so clients can't grow the counters with made up names.
+func metricsMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		call, ok := req.(*mcp.CallToolRequest)
+		if !ok {
+			return next(ctx, method, req)
+		}
+		name := toolLabel(ctx, next, call)
+
+		metrics.mu.Lock()
+		metrics.toolCalls[name]++
+		metrics.mu.Unlock()
+		promToolCalls.WithLabelValues(name).Inc()
+
+		start := wallNow()
+		defer func() { promToolDuration.WithLabelValues(name).Observe(wallNow().Sub(start).Seconds()) }()
+		return next(ctx, method, req)
+	}
+}
+
+// toolLabel is the called tool's name when the server registers it and unknownTool otherwise
+func toolLabel(ctx context.Context, next mcp.MethodHandler, call *mcp.CallToolRequest) string {
+	session, _ := call.GetSession().(*mcp.ServerSession)
+	listed, err := next(ctx, "tools/list", &mcp.ListToolsRequest{Session: session, Params: &mcp.ListToolsParams{}})
+	if tools, ok := listed.(*mcp.ListToolsResult); err == nil && ok {
+		for _, tool := range tools.Tools {
+			if tool.Name == call.Params.Name {
+				return tool.Name
+			}
+		}
+	}
+	return unknownTool
+}
+
+// serveMetrics exposes the prometheus counters on addr under /metrics until ctx is done.
+// the listener is opened up front so a bad address fails at startup.
+func serveMetrics(ctx context.Context, addr string) (<-chan struct{}, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(promRegistry, promhttp.HandlerOpts{}))
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("metrics endpoint: %v", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	return done, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// scrapeMetric reads one unlabelled or labelled sample from the /metrics endpoint at addr, zero when absent
+func scrapeMetric(t *testing.T, addr, sample string) float64 {
+	t.Helper()
+	resp, err := http.Get("http://" + addr + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	match := regexp.MustCompile(`(?m)^` + regexp.QuoteMeta(sample) + ` (\S+)$`).FindSubmatch(body)
+	if match == nil {
+		return 0
+	}
+	value, err := strconv.ParseFloat(string(match[1]), 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+func TestMetricsEndpointCountsToolCalls(t *testing.T) {
+	newTestVault(t, map[string]string{
+		"2024-03-15.md": "friday\n",
+		"2024-03-16.md": "saturday\n",
+	})
+
+	// serveMetrics wants an address, so borrow a free port from the kernel
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done, err := serveMetrics(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	calls := `themis_tool_calls_total{tool="getEntriesByYear"}`
+	beforeCalls := scrapeMetric(t, addr, calls)
+	beforeFiles := scrapeMetric(t, addr, "themis_files_scanned_total")
+	beforeWalks := scrapeMetric(t, addr, "themis_walk_duration_seconds_count")
+
+	session := connectTestServer(t, newServer(false, false))
+	result, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: "getEntriesByYear", Arguments: map[string]any{"year": 2024}})
+	if err != nil || result.IsError {
+		t.Fatalf("getEntriesByYear = %+v, %v", result, err)
+	}
+
+	if got := scrapeMetric(t, addr, calls); got != beforeCalls+1 {
+		t.Errorf("%s = %v, want %v", calls, got, beforeCalls+1)
+	}
+	if got := scrapeMetric(t, addr, "themis_files_scanned_total"); got < beforeFiles+2 {
+		t.Errorf("files scanned = %v, want at least %v", got, beforeFiles+2)
+	}
+	if got := scrapeMetric(t, addr, "themis_walk_duration_seconds_count"); got <= beforeWalks {
+		t.Errorf("walks observed = %v, want more than %v", got, beforeWalks)
+	}
+
+	// made up tool names all land on one label
+	unknown := `themis_tool_calls_total{tool="unknown"}`
+	beforeUnknown := scrapeMetric(t, addr, unknown)
+	for _, name := range []string{"noSuchTool", "anotherMadeUpTool"} {
+		if _, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: name}); err == nil {
+			t.Errorf("%s: want an error", name)
+		}
+		if got := scrapeMetric(t, addr, `themis_tool_calls_total{tool="`+name+`"}`); got != 0 {
+			t.Errorf("%s got its own label", name)
+		}
+	}
+	if got := scrapeMetric(t, addr, unknown); got != beforeUnknown+2 {
+		t.Errorf("%s = %v, want %v", unknown, got, beforeUnknown+2)
+	}
+	metrics.mu.Lock()
+	_, tracked := metrics.toolCalls["noSuchTool"]
+	metrics.mu.Unlock()
+	if tracked {
+		t.Error("getMetrics tracks a made up tool name")
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/curator4/mcp-server-diary/query"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type GetEntriesModifiedTodayInput struct {
+	MaxTokens int `json:"maxTokens,omitempty" jsonschema:"Drop the oldest entries once the estimated token total would exceed this budget"`
+
+	PreviewChars     int  `json:"previewChars,omitempty" jsonschema:"Add a plaintext preview of this many characters to each entry"`
+	OmitContent      bool `json:"omitContent,omitempty" jsonschema:"Leave out the full content, e.g. together with previewChars for list views"`
+	StripFrontmatter bool `json:"stripFrontmatter,omitempty" jsonschema:"Remove the --- frontmatter block from content, the parsed frontmatter field is still returned"`
+
+	MaxContentLength int    `json:"maxContentLength,omitempty" jsonschema:"Cut each entry's content to this many characters, frontmatter not counted"`
+	TruncateStrategy string `json:"truncateStrategy,omitempty" jsonschema:"How maxContentLength cuts: head (default) keeps the start, tail the end, structured every heading plus the first sentence of each section"`
+
+	IncludeRelativeLabels bool `json:"includeRelativeLabels,omitempty" jsonschema:"Label each entry relative to today, e.g. yesterday, 3 days ago or last week"`
+	ContentBlocks         bool `json:"contentBlocks,omitempty" jsonschema:"Also return each entry as a formatted text block under a date heading for inline display, the structured result is unchanged"`
+
+	Fields          []string `json:"fields,omitempty" jsonschema:"Entry fields to return, e.g. [\"date\", \"content\"], defaults to the server's configured set"`
+	ContentEncoding string   `json:"contentEncoding,omitempty" jsonschema:"plain (default) or base64 to base64 encode content for byte-faithful transport"`
+	TimeoutMs       int      `json:"timeoutMs,omitempty" jsonschema:"Time budget for this call in milliseconds, partial results are returned when it runs out (default 10000)"`
+	Scope           string   `json:"scope,omitempty" jsonschema:"Vault subfolder to read entries from, e.g. daily, in place of the server's THEMIS_SCOPE, . for the whole vault"`
+}
+
+const (
+	defaultActivityLimit = 20
+	maxActivityLimit     = 200
+)
+
+type GetRecentActivityInput struct {
+	Limit int `json:"limit,omitempty" jsonschema:"Number of entries to return, most recently modified first (default 20, max 200)"`
+}
+
+type ActivityItem struct {
+	Date    string `json:"date" jsonschema:"Entry date in YYYY-MM-DD format"`
+	Path    string `json:"path" jsonschema:"Vault relative path of the entry file"`
+	ModTime string `json:"modTime" jsonschema:"When the file was last modified, RFC 3339 in the configured timezone"`
+	Edited  bool   `json:"edited" jsonschema:"The file was modified on a later day than its entry date, i.e. a past entry was edited"`
+}
+
+type RecentActivityOutput struct {
+	Activity []ActivityItem `json:"activity" jsonschema:"Entries by modification time, newest first"`
+	Count    int            `json:"count" jsonschema:"Number of entries returned"`
+}
+
+// handlers
+func handleGetEntriesModifiedToday(ctx context.Context, req *mcp.CallToolRequest, input GetEntriesModifiedTodayInput) (
+	*mcp.CallToolResult,
+	EntriesOutput,
+	error,
+) {
+	start, end := dayBounds(now())
+	modifiedToday := func(modTime time.Time) bool {
+		return !modTime.Before(start) && modTime.Before(end)
+	}
+
+	root, err := entryScope(input.Scope)
+	if err != nil {
+		return nil, EntriesOutput{}, err
+	}
+
+	// the files are statted first so only the days with a file modified today are read, match then drops
+	// other files of those days
+	dates := []time.Time{}
+	for path, stamp := range entryStampsIn(root) {
+		if !modifiedToday(stamp.modTime) {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".age"), ".md"))
+		if err == nil {
+			dates = append(dates, date)
+		}
+	}
+	match := func(entry Entry) bool { return modifiedToday(entry.modTime) }
+	output, err := runEntryQuery(ctx, entryQuery{
+		filter: query.Filter[Entry]{Dates: dates, Fields: input.Fields, Match: match},
+		shape: contentShape{
+			stripFrontmatter: input.StripFrontmatter,
+			previewChars:     input.PreviewChars,
+			omitContent:      input.OmitContent,
+			maxContentLength: input.MaxContentLength,
+			truncateStrategy: input.TruncateStrategy,
+			encoding:         input.ContentEncoding,
+		},
+		relativeLabels: input.IncludeRelativeLabels,
+		maxTokens:      input.MaxTokens,
+		timeoutMs:      input.TimeoutMs,
+		scope:          input.Scope,
+	})
+	if err != nil {
+		return nil, EntriesOutput{}, err
+	}
+	return entryBlocks(input.ContentBlocks, output.Entries), output, nil
+}
+
+func handleGetRecentActivity(ctx context.Context, req *mcp.CallToolRequest, input GetRecentActivityInput) (
+	*mcp.CallToolResult,
+	RecentActivityOutput,
+	error,
+) {
+	limit := input.Limit
+	if limit <= 0 {
+		limit = defaultActivityLimit
+	}
+	limit = min(limit, maxActivityLimit)
+
+	// file stamps are enough here, no entry is read
+	output := RecentActivityOutput{Activity: []ActivityItem{}}
+	modTimes := map[string]time.Time{}
+	for path, stamp := range entryStamps() {
+		if stamp.modTime.IsZero() {
+			continue
+		}
+		dateStr := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".age"), ".md")
+		modTime := stamp.modTime.In(location)
+		modTimes[path] = modTime
+		output.Activity = append(output.Activity, ActivityItem{
+			Date:    dateStr,
+			Path:    path,
+			ModTime: modTime.Format(time.RFC3339),
+			Edited:  modTime.Format("2006-01-02") > dateStr,
+		})
+	}
+
+	sort.Slice(output.Activity, func(i, j int) bool {
+		a, b := modTimes[output.Activity[i].Path], modTimes[output.Activity[j].Path]
+		if !a.Equal(b) {
+			return a.After(b)
+		}
+		return output.Activity[i].Path < output.Activity[j].Path
+	})
+	if len(output.Activity) > limit {
+		output.Activity = output.Activity[:limit]
+	}
+	for i := range output.Activity {
+		output.Activity[i].Path = vaultRelative(output.Activity[i].Path)
+	}
+	output.Count = len(output.Activity)
+
+	return nil, output, nil
+}
+
+// helpers
+
+// dayBounds returns the start of t's calendar day in the configured timezone and the start of the next one
+func dayBounds(t time.Time) (time.Time, time.Time) {
+	t = t.In(location)
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, location)
+	return start, start.AddDate(0, 0, 1)
+}
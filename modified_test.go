@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetRecentActivity(t *testing.T) {
+	dir := newTestVault(t, map[string]string{
+		"2024-03-10.md":         "# Sunday\n",
+		"2024-03-12.md":         "# Tuesday\n",
+		"2024-03-15.md":         "# Friday\n",
+		"2024/2024-03-14.md":    "# Thursday\n",
+		"notes.md":              "not an entry\n",
+		".2024-03-15.md.tmp-77": "# Fri",
+	})
+	oldLocation := location
+	location = time.FixedZone("UTC+2", 2*60*60)
+	defer func() { location = oldLocation }()
+
+	at := func(value string) time.Time {
+		moment, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return moment
+	}
+	touch(t, filepath.Join(dir, "2024-03-10.md"), at("2024-03-10T18:00:00Z"))
+	// the 12th was edited on the 15th
+	touch(t, filepath.Join(dir, "2024-03-12.md"), at("2024-03-15T07:00:00Z"))
+	touch(t, filepath.Join(dir, "2024-03-15.md"), at("2024-03-15T06:00:00Z"))
+	// written late on the 14th in utc, already the 15th in the configured timezone
+	touch(t, filepath.Join(dir, "2024", "2024-03-14.md"), at("2024-03-14T23:30:00Z"))
+	touch(t, filepath.Join(dir, ".2024-03-15.md.tmp-77"), at("2024-03-15T08:00:00Z"))
+
+	_, output, err := handleGetRecentActivity(context.Background(), nil, GetRecentActivityInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ActivityItem{
+		{Date: "2024-03-12", Path: "2024-03-12.md", ModTime: "2024-03-15T09:00:00+02:00", Edited: true},
+		{Date: "2024-03-15", Path: "2024-03-15.md", ModTime: "2024-03-15T08:00:00+02:00", Edited: false},
+		{Date: "2024-03-14", Path: "2024/2024-03-14.md", ModTime: "2024-03-15T01:30:00+02:00", Edited: true},
+		{Date: "2024-03-10", Path: "2024-03-10.md", ModTime: "2024-03-10T20:00:00+02:00", Edited: false},
+	}
+	if !reflect.DeepEqual(output.Activity, want) || output.Count != 4 {
+		t.Errorf("activity = %+v, want %+v", output.Activity, want)
+	}
+
+	_, output, err = handleGetRecentActivity(context.Background(), nil, GetRecentActivityInput{Limit: 2})
+	if err != nil || !reflect.DeepEqual(output.Activity, want[:2]) {
+		t.Errorf("limit 2 = %+v, %v", output.Activity, err)
+	}
+}
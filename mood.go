@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	defaultMoodWindow = 7
+	minMood           = 1
+	maxMood           = 5
+)
+
+type GetMoodTrendInput struct {
+	Start  string `json:"start,omitempty" jsonschema:"First date to include in YYYY-MM-DD format"`
+	End    string `json:"end,omitempty" jsonschema:"Last date to include in YYYY-MM-DD format"`
+	Window int    `json:"window,omitempty" jsonschema:"Size of the rolling window in days (default 7)"`
+	Mode   string `json:"mode,omitempty" jsonschema:"trailing (default) averages the window ending on each day, centered averages the window around it"`
+}
+
+type MoodPoint struct {
+	Date string  `json:"date" jsonschema:"Day with a recorded mood in YYYY-MM-DD format"`
+	Avg  float64 `json:"avg" jsonschema:"Average mood over the window for that day"`
+}
+
+type MoodTrendOutput struct {
+	Points   []MoodPoint `json:"points" jsonschema:"Rolling averages for each day with a mood, oldest first"`
+	Window   int         `json:"window" jsonschema:"Window size used in days"`
+	Mode     string      `json:"mode" jsonschema:"Averaging mode used"`
+	Warnings []string    `json:"warnings,omitempty" jsonschema:"Entries whose mood value was skipped and why"`
+}
+
+// handlers
+func handleGetMoodTrend(ctx context.Context, req *mcp.CallToolRequest, input GetMoodTrendInput) (
+	*mcp.CallToolResult,
+	MoodTrendOutput,
+	error,
+) {
+	filter, err := rangeFilter(input.Start, input.End)
+	if err != nil {
+		return nil, MoodTrendOutput{}, err
+	}
+	window := input.Window
+	if window <= 0 {
+		window = defaultMoodWindow
+	}
+	mode := input.Mode
+	if mode == "" {
+		mode = "trailing"
+	}
+	if mode != "trailing" && mode != "centered" {
+		return nil, MoodTrendOutput{}, fmt.Errorf("unknown mode %q, expected trailing or centered", input.Mode)
+	}
+
+	output := MoodTrendOutput{Points: []MoodPoint{}, Window: window, Mode: mode}
+
+	// several files can share a date, their moods are averaged into one daily value
+	type daily struct{ sum, count float64 }
+	days := map[string]*daily{}
+	err = eachEntry(ctx, filter, func(entry Entry) error {
+		value, ok := entry.Frontmatter["mood"]
+		if !ok {
+			return nil
+		}
+
+		mood, err := parseMood(value)
+		if err != nil {
+			output.Warnings = append(output.Warnings, fmt.Sprintf("%s: %v", entry.Date, err))
+			return nil
+		}
+		if days[entry.Date] == nil {
+			days[entry.Date] = &daily{}
+		}
+		days[entry.Date].sum += mood
+		days[entry.Date].count++
+		return nil
+	})
+	if err != nil {
+		return nil, MoodTrendOutput{}, fmt.Errorf("failed to get entries: %w", err)
+	}
+
+	dates := make([]time.Time, 0, len(days))
+	for dateStr := range days {
+		date, _ := time.Parse("2006-01-02", dateStr)
+		dates = append(dates, date)
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+
+	before, after := window-1, 0
+	if mode == "centered" {
+		before, after = (window-1)/2, window/2
+	}
+
+	for _, date := range dates {
+		from, to := date.AddDate(0, 0, -before), date.AddDate(0, 0, after)
+		sum, count := 0.0, 0.0
+		for _, other := range dates {
+			if other.Before(from) || other.After(to) {
+				continue
+			}
+			day := days[other.Format("2006-01-02")]
+			sum += day.sum / day.count
+			count++
+		}
+		output.Points = append(output.Points, MoodPoint{Date: date.Format("2006-01-02"), Avg: math.Round(sum/count*100) / 100})
+	}
+
+	sort.Strings(output.Warnings)
+	return nil, output, nil
+}
+
+// helpers
+func parseMood(value any) (float64, error) {
+	var mood float64
+	switch v := value.(type) {
+	case int:
+		mood = float64(v)
+	case float64:
+		mood = v
+	case string:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, fmt.Errorf("mood %q is not numeric", v)
+		}
+		mood = parsed
+	default:
+		return 0, fmt.Errorf("mood %v is not numeric", v)
+	}
+
+	if mood < minMood || mood > maxMood {
+		return 0, fmt.Errorf("mood %v is outside %d-%d", mood, minMood, maxMood)
+	}
+	return mood, nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestGetMoodTrend(t *testing.T) {
+	newTestVault(t, map[string]string{
+		"2024-03-10.md":      "---\nmood: 2\n---\n",
+		"2024-03-11.md":      "---\nmood: 4\n---\n",
+		"2024/2024-03-11.md": "---\nmood: 5\n---\nsecond file the same day\n",
+		"2024-03-13.md":      "---\nmood: \"3\"\n---\n",
+		"2024-03-14.md":      "---\nmood: 9\n---\n",
+		"2024-03-15.md":      "---\nmood: great\n---\n",
+		"2024-03-16.md":      "no mood recorded\n",
+	})
+	warnings := []string{"2024-03-14: mood 9 is outside 1-5", `2024-03-15: mood "great" is not numeric`}
+
+	tests := []struct {
+		mode string
+		want []MoodPoint
+	}{
+		{"", []MoodPoint{{"2024-03-10", 2}, {"2024-03-11", 3.25}, {"2024-03-13", 3.75}}},
+		{"centered", []MoodPoint{{"2024-03-10", 3.25}, {"2024-03-11", 3.25}, {"2024-03-13", 3}}},
+	}
+	for _, tt := range tests {
+		_, output, err := handleGetMoodTrend(context.Background(), nil, GetMoodTrendInput{Window: 3, Mode: tt.mode})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(output.Points, tt.want) || !reflect.DeepEqual(output.Warnings, warnings) {
+			t.Errorf("mode %q: points = %v with warnings %q, want %v with %q", tt.mode, output.Points, output.Warnings, tt.want, warnings)
+		}
+	}
+
+	_, output, err := handleGetMoodTrend(context.Background(), nil, GetMoodTrendInput{Start: "2024-03-16"})
+	if err != nil || output.Points == nil || len(output.Points) != 0 || output.Window != defaultMoodWindow || output.Mode != "trailing" {
+		t.Errorf("empty range = %+v, %v", output, err)
+	}
+	if _, _, err := handleGetMoodTrend(context.Background(), nil, GetMoodTrendInput{Mode: "weekly"}); err == nil {
+		t.Error("want an error for an unknown mode")
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/curator4/mcp-server-diary/query"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const maxNeighborhoodDays = 31
+
+type GetEntryNeighborhoodInput struct {
+	Date      string `json:"date" jsonschema:"Entry date in YYYY-MM-DD format"`
+	Before    int    `json:"before,omitempty" jsonschema:"Days before the date to include (max 31)"`
+	After     int    `json:"after,omitempty" jsonschema:"Days after the date to include (max 31)"`
+	MaxTokens int    `json:"maxTokens,omitempty" jsonschema:"Drop the oldest entries once the estimated token total would exceed this budget"`
+
+	PreviewChars     int  `json:"previewChars,omitempty" jsonschema:"Add a plaintext preview of this many characters to each entry"`
+	OmitContent      bool `json:"omitContent,omitempty" jsonschema:"Leave out the full content, e.g. together with previewChars for list views"`
+	StripFrontmatter bool `json:"stripFrontmatter,omitempty" jsonschema:"Remove the --- frontmatter block from content, the parsed frontmatter field is still returned"`
+
+	IncludeRelativeLabels bool `json:"includeRelativeLabels,omitempty" jsonschema:"Label each entry relative to today, e.g. yesterday, 3 days ago or last week"`
+	ContentBlocks         bool `json:"contentBlocks,omitempty" jsonschema:"Also return each entry as a formatted text block under a date heading for inline display, the structured result is unchanged"`
+
+	Fields          []string `json:"fields,omitempty" jsonschema:"Entry fields to return, e.g. [\"date\", \"content\"], defaults to the server's configured set"`
+	ContentEncoding string   `json:"contentEncoding,omitempty" jsonschema:"plain (default) or base64 to base64 encode content for byte-faithful transport"`
+	Scope           string   `json:"scope,omitempty" jsonschema:"Vault subfolder to read entries from, e.g. daily, in place of the server's THEMIS_SCOPE, . for the whole vault"`
+}
+
+// NeighborhoodOutput returns the existing entries in the window oldest first, days without an entry are left out
+type NeighborhoodOutput struct {
+	Date string `json:"date" jsonschema:"The requested date"`
+	EntriesOutput
+}
+
+type GetEntryByOffsetInput struct {
+	Offset int `json:"offset,omitempty" jsonschema:"How many entries to step back, 0 is the most recent entry, 1 the one before it"`
+
+	PreviewChars     int  `json:"previewChars,omitempty" jsonschema:"Add a plaintext preview of this many characters to each entry"`
+	OmitContent      bool `json:"omitContent,omitempty" jsonschema:"Leave out the full content, e.g. together with previewChars for list views"`
+	StripFrontmatter bool `json:"stripFrontmatter,omitempty" jsonschema:"Remove the --- frontmatter block from content, the parsed frontmatter field is still returned"`
+
+	Fields          []string `json:"fields,omitempty" jsonschema:"Entry fields to return, e.g. [\"date\", \"content\"], defaults to the server's configured set"`
+	ContentEncoding string   `json:"contentEncoding,omitempty" jsonschema:"plain (default) or base64 to base64 encode content for byte-faithful transport"`
+	Scope           string   `json:"scope,omitempty" jsonschema:"Vault subfolder to read entries from, e.g. daily, in place of the server's THEMIS_SCOPE, . for the whole vault"`
+}
+
+type EntryByOffsetOutput struct {
+	Offset int   `json:"offset" jsonschema:"The requested offset"`
+	Total  int   `json:"total" jsonschema:"Number of entry dates in the vault, valid offsets run up to total - 1"`
+	Entry  Entry `json:"entry" jsonschema:"The entry at that offset"`
+
+	ContinuationToken string `json:"continuationToken,omitempty" jsonschema:"Set when the entry was too large to send at once, pass it to continueResult for the rest"`
+}
+
+// handlers
+func handleGetEntryNeighborhood(ctx context.Context, req *mcp.CallToolRequest, input GetEntryNeighborhoodInput) (
+	*mcp.CallToolResult,
+	NeighborhoodOutput,
+	error,
+) {
+	date, err := time.Parse("2006-01-02", input.Date)
+	if err != nil {
+		return nil, NeighborhoodOutput{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", input.Date)
+	}
+	if input.Before < 0 || input.After < 0 || input.Before > maxNeighborhoodDays || input.After > maxNeighborhoodDays {
+		return nil, NeighborhoodOutput{}, fmt.Errorf("before and after must be between 0 and %d", maxNeighborhoodDays)
+	}
+
+	output, err := runEntryQuery(ctx, entryQuery{
+		filter: query.Filter[Entry]{
+			Start:  date.AddDate(0, 0, -input.Before),
+			End:    date.AddDate(0, 0, input.After),
+			Order:  query.OldestFirst,
+			Fields: input.Fields,
+		},
+		shape: contentShape{
+			stripFrontmatter: input.StripFrontmatter,
+			previewChars:     input.PreviewChars,
+			omitContent:      input.OmitContent,
+			encoding:         input.ContentEncoding,
+		},
+		relativeLabels: input.IncludeRelativeLabels,
+		maxTokens:      input.MaxTokens,
+		scope:          input.Scope,
+	})
+	if err != nil {
+		return nil, NeighborhoodOutput{}, err
+	}
+
+	return entryBlocks(input.ContentBlocks, output.Entries), NeighborhoodOutput{Date: input.Date, EntriesOutput: output}, nil
+}
+
+func handleGetEntryByOffset(ctx context.Context, req *mcp.CallToolRequest, input GetEntryByOffsetInput) (
+	*mcp.CallToolResult,
+	EntryByOffsetOutput,
+	error,
+) {
+	if input.Offset < 0 {
+		return nil, EntryByOffsetOutput{}, fmt.Errorf("offset must not be negative, got %d", input.Offset)
+	}
+
+	// paging by date picks the day from file names, only the entry at the offset is read
+	q := entryQuery{
+		filter: query.Filter[Entry]{Offset: input.Offset, Limit: 1, Fields: input.Fields},
+		shape: contentShape{
+			stripFrontmatter: input.StripFrontmatter,
+			previewChars:     input.PreviewChars,
+			omitContent:      input.OmitContent,
+			encoding:         input.ContentEncoding,
+		},
+		scope: input.Scope,
+	}
+	result, err := findEntries(ctx, q)
+	if err != nil {
+		return nil, EntryByOffsetOutput{}, err
+	}
+	if result.Total == 0 {
+		return nil, EntryByOffsetOutput{}, fmt.Errorf("the vault has no entries")
+	}
+	if input.Offset >= result.Total {
+		return nil, EntryByOffsetOutput{}, fmt.Errorf("offset %d is out of range, the vault has %d entries so the largest offset is %d", input.Offset, result.Total, result.Total-1)
+	}
+	if len(result.Items) == 0 {
+		return nil, EntryByOffsetOutput{}, fmt.Errorf("the entry at offset %d could not be read", input.Offset)
+	}
+
+	output := entriesOutput(ctx, q, result)
+	return nil, EntryByOffsetOutput{Offset: input.Offset, Total: result.Total, Entry: output.Entries[0], ContinuationToken: output.ContinuationToken}, nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type GetOutlineInput struct {
+	Start string `json:"start,omitempty" jsonschema:"First date to include in YYYY-MM-DD format"`
+	End   string `json:"end,omitempty" jsonschema:"Last date to include in YYYY-MM-DD format"`
+}
+
+type EntryOutline struct {
+	Date          string    `json:"date" jsonschema:"Entry date in YYYY-MM-DD format"`
+	FilePath      string    `json:"path" jsonschema:"Full path to the diary entry file"`
+	PreambleWords int       `json:"preambleWords" jsonschema:"Words before the first heading"`
+	Headings      []Heading `json:"headings" jsonschema:"Headings in document order"`
+}
+
+type OutlineOutput struct {
+	Outlines []EntryOutline `json:"outlines" jsonschema:"Entry outlines, sorted oldest first"`
+	Count    int            `json:"count" jsonschema:"Total number of outlines returned"`
+}
+
+type FindNonConformingInput struct {
+	RequiredHeadings []string `json:"requiredHeadings" jsonschema:"Headings every entry should have, matched case-insensitively on their text"`
+	Start            string   `json:"start,omitempty" jsonschema:"First date to include in YYYY-MM-DD format"`
+	End              string   `json:"end,omitempty" jsonschema:"Last date to include in YYYY-MM-DD format"`
+}
+
+type NonConformingEntry struct {
+	Date     string   `json:"date" jsonschema:"Entry date in YYYY-MM-DD format"`
+	FilePath string   `json:"path" jsonschema:"Full path to the diary entry file"`
+	Missing  []string `json:"missing" jsonschema:"Required headings the entry lacks, in the order they were given"`
+}
+
+type NonConformingOutput struct {
+	Entries []NonConformingEntry `json:"entries" jsonschema:"Entries missing at least one required heading, oldest first"`
+	Count   int                  `json:"count" jsonschema:"Total number of non-conforming entries"`
+	Checked int                  `json:"checked" jsonschema:"Entries checked, encrypted entries that could not be decrypted are left out"`
+}
+
+// handlers
+func handleGetOutline(ctx context.Context, req *mcp.CallToolRequest, input GetOutlineInput) (
+	*mcp.CallToolResult,
+	OutlineOutput,
+	error,
+) {
+	filter, err := rangeFilter(input.Start, input.End)
+	if err != nil {
+		return nil, OutlineOutput{}, err
+	}
+
+	outlines := []EntryOutline{}
+	err = eachEntry(ctx, filter, func(entry Entry) error {
+		outlines = append(outlines, entryOutline(entry))
+		return nil
+	})
+	if err != nil {
+		return nil, OutlineOutput{}, fmt.Errorf("failed to get entries: %w", err)
+	}
+
+	sort.Slice(outlines, func(i, j int) bool { return outlines[i].Date < outlines[j].Date })
+
+	return nil, OutlineOutput{Outlines: outlines, Count: len(outlines)}, nil
+}
+
+func handleFindNonConforming(ctx context.Context, req *mcp.CallToolRequest, input FindNonConformingInput) (
+	*mcp.CallToolResult,
+	NonConformingOutput,
+	error,
+) {
+	filter, err := rangeFilter(input.Start, input.End)
+	if err != nil {
+		return nil, NonConformingOutput{}, err
+	}
+
+	var required []string
+	for _, heading := range input.RequiredHeadings {
+		if heading = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(heading), "#")); heading != "" {
+			required = append(required, heading)
+		}
+	}
+	output := NonConformingOutput{Entries: []NonConformingEntry{}}
+	if len(required) == 0 {
+		return nil, output, nil
+	}
+
+	err = eachEntry(ctx, filter, func(entry Entry) error {
+		if entry.Locked {
+			return nil
+		}
+		output.Checked++
+
+		_, body, _ := splitFrontmatter(entry.Content)
+		headings, _ := parseHeadings(body)
+		present := map[string]bool{}
+		for _, heading := range headings {
+			present[strings.ToLower(heading.Text)] = true
+		}
+
+		var missing []string
+		for _, heading := range required {
+			if !present[strings.ToLower(heading)] {
+				missing = append(missing, heading)
+			}
+		}
+		if len(missing) > 0 {
+			output.Entries = append(output.Entries, NonConformingEntry{Date: entry.Date, FilePath: entry.FilePath, Missing: missing})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, NonConformingOutput{}, fmt.Errorf("failed to get entries: %w", err)
+	}
+
+	sort.Slice(output.Entries, func(i, j int) bool { return output.Entries[i].Date < output.Entries[j].Date })
+	output.Count = len(output.Entries)
+
+	return nil, output, nil
+}
+
+// helpers
+func entryOutline(entry Entry) EntryOutline {
+	_, body, _ := splitFrontmatter(entry.Content)
+	headings, preamble := parseHeadings(body)
+	if headings == nil {
+		headings = []Heading{}
+	}
+	return EntryOutline{
+		Date:          entry.Date,
+		FilePath:      entry.FilePath,
+		PreambleWords: preamble,
+		Headings:      headings,
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestFindNonConforming(t *testing.T) {
+	newTestVault(t, map[string]string{
+		"2023-06-01.md": "dear diary, no structure yet\n",
+		"2023-06-02.md": "# Gratitude\nsun\n```\n## Tasks\n```\n",
+		"2024-01-01.md": "---\nmood: 4\n---\n## gratitude\ncoffee\n### TASKS\n- [ ] run\n",
+		"2024-01-02.md": "# Gratitude\n## Tasks\n## Notes\n",
+	})
+	ctx := context.Background()
+
+	// headings match on their text at any level and case, a heading inside a code fence does not count
+	_, output, err := handleFindNonConforming(ctx, nil, FindNonConformingInput{RequiredHeadings: []string{"## Gratitude", "Tasks"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string][]string{}
+	for _, entry := range output.Entries {
+		got[entry.Date] = entry.Missing
+	}
+	want := map[string][]string{
+		"2023-06-01": {"Gratitude", "Tasks"},
+		"2023-06-02": {"Tasks"},
+	}
+	if !reflect.DeepEqual(got, want) || output.Count != 2 || output.Checked != 4 {
+		t.Errorf("output = %+v, want %v missing out of 4 checked", output, want)
+	}
+	if output.Entries[0].Date != "2023-06-01" {
+		t.Errorf("entries not oldest first: %+v", output.Entries)
+	}
+
+	// the range limits what is checked
+	_, output, err = handleFindNonConforming(ctx, nil, FindNonConformingInput{RequiredHeadings: []string{"Notes"}, Start: "2024-01-01"})
+	if err != nil || output.Checked != 2 || output.Count != 1 || output.Entries[0].Date != "2024-01-01" {
+		t.Errorf("2024 only = %+v, %v", output, err)
+	}
+
+	// no required headings is a no-op that reads nothing
+	for _, required := range [][]string{nil, {"", " # "}} {
+		_, output, err := handleFindNonConforming(ctx, nil, FindNonConformingInput{RequiredHeadings: required})
+		if err != nil || output.Entries == nil || output.Count != 0 || output.Checked != 0 {
+			t.Errorf("required %q = %+v, %v, want an empty no-op", required, output, err)
+		}
+	}
+}
+
+func TestGetOutline(t *testing.T) {
+	newTestVault(t, map[string]string{
+		"2023-12-30.md": "no headings at all\n",
+		"2024-03-10.md": "---\nmood: 3\n---\nwoke up early\n# Morning\ncoffee and a walk\n## Walk\nthe long way round\n# Evening\n",
+		"2024-03-12.md": "Evening\n=======\ntea\n```\n# not a heading\n```\n",
+	})
+	ctx := context.Background()
+
+	type heading struct {
+		level int
+		text  string
+		words int
+	}
+	tests := []struct {
+		date     string
+		preamble int
+		headings []heading
+	}{
+		{"2023-12-30", 4, nil},
+		// frontmatter is not preamble, a section's words stop at the next heading of any level
+		{"2024-03-10", 3, []heading{{1, "Morning", 4}, {2, "Walk", 4}, {1, "Evening", 0}}},
+		// setext headings count, a heading inside a code fence does not
+		{"2024-03-12", 0, []heading{{1, "Evening", 7}}},
+	}
+
+	_, output, err := handleGetOutline(ctx, nil, GetOutlineInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Count != len(tests) || len(output.Outlines) != len(tests) {
+		t.Fatalf("got %d outlines, want %d", len(output.Outlines), len(tests))
+	}
+	for i, tt := range tests {
+		outline := output.Outlines[i]
+		var got []heading
+		for _, h := range outline.Headings {
+			got = append(got, heading{h.Level, h.Text, h.Words})
+		}
+		if outline.Date != tt.date || outline.PreambleWords != tt.preamble || !reflect.DeepEqual(got, tt.headings) {
+			t.Errorf("outline %d = %s, preamble %d, %v, want %s, preamble %d, %v", i, outline.Date, outline.PreambleWords, got, tt.date, tt.preamble, tt.headings)
+		}
+	}
+
+	_, output, err = handleGetOutline(ctx, nil, GetOutlineInput{Start: "2024-03-11"})
+	if err != nil || output.Count != 1 || output.Outlines[0].Date != "2024-03-12" {
+		t.Errorf("from 2024-03-11 = %+v, %v", output, err)
+	}
+	if _, _, err := handleGetOutline(ctx, nil, GetOutlineInput{Start: "2024-03-12", End: "2024-03-10"}); err == nil {
+		t.Error("accepted an end before the start")
+	}
+
+	// an entry without headings and an empty range are empty lists, which the output schema requires
+	session := connectTestServer(t, newServer(false, false))
+	for _, args := range []map[string]any{{"end": "2023-12-31"}, {"start": "2025-01-01"}} {
+		if _, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "getOutline", Arguments: args}); err != nil {
+			t.Errorf("getOutline %v: %v", args, err)
+		}
+	}
+}
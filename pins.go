@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/curator4/mcp-server-diary/query"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const pinsFile = ".themis/pins.json"
+
+// pinsMu serialises read-modify-write cycles of the pins file
+var pinsMu sync.Mutex
+
+type Pin struct {
+	Date     string `json:"date" jsonschema:"Pinned entry date in YYYY-MM-DD format"`
+	Note     string `json:"note,omitempty" jsonschema:"Why the entry is pinned"`
+	PinnedAt string `json:"pinnedAt" jsonschema:"When the pin was made, RFC 3339"`
+}
+
+type PinEntryInput struct {
+	Date string `json:"date" jsonschema:"Entry date in YYYY-MM-DD format"`
+	Note string `json:"note,omitempty" jsonschema:"Why the entry is pinned, e.g. big decision about moving"`
+}
+
+type UnpinEntryInput struct {
+	Date string `json:"date" jsonschema:"Entry date in YYYY-MM-DD format"`
+}
+
+type PinOutput struct {
+	Pin     Pin  `json:"pin" jsonschema:"The pin that was added, updated or removed"`
+	Changed bool `json:"changed" jsonschema:"False when the entry was already pinned with that note, or was not pinned"`
+}
+
+type GetPinnedInput struct {
+	MaxTokens int `json:"maxTokens,omitempty" jsonschema:"Drop the oldest entries once the estimated token total would exceed this budget"`
+
+	PreviewChars     int  `json:"previewChars,omitempty" jsonschema:"Add a plaintext preview of this many characters to each entry"`
+	OmitContent      bool `json:"omitContent,omitempty" jsonschema:"Leave out the full content, e.g. together with previewChars for list views"`
+	StripFrontmatter bool `json:"stripFrontmatter,omitempty" jsonschema:"Remove the --- frontmatter block from content, the parsed frontmatter field is still returned"`
+
+	Fields          []string `json:"fields,omitempty" jsonschema:"Entry fields to return, e.g. [\"date\", \"content\"], defaults to the server's configured set"`
+	ContentEncoding string   `json:"contentEncoding,omitempty" jsonschema:"plain (default) or base64 to base64 encode content for byte-faithful transport"`
+	Scope           string   `json:"scope,omitempty" jsonschema:"Vault subfolder to read entries from, e.g. daily, in place of the server's THEMIS_SCOPE, . for the whole vault"`
+}
+
+// PinnedOutput lists every pin and returns the pinned entries that still exist, newest first
+type PinnedOutput struct {
+	Pins []Pin `json:"pins" jsonschema:"Every pin, newest entry date first"`
+	EntriesOutput
+	Missing []string `json:"missing,omitempty" jsonschema:"Pinned dates whose entry no longer exists"`
+}
+
+// handlers
+func handlePinEntry(ctx context.Context, req *mcp.CallToolRequest, input PinEntryInput) (
+	*mcp.CallToolResult,
+	PinOutput,
+	error,
+) {
+	entry, err := getEntryByDate(input.Date)
+	if err != nil {
+		return nil, PinOutput{}, err
+	}
+
+	pinsMu.Lock()
+	defer pinsMu.Unlock()
+	pins, err := loadPins()
+	if err != nil {
+		return nil, PinOutput{}, err
+	}
+
+	pin := Pin{Date: entry.Date, Note: input.Note, PinnedAt: now().In(location).Format(time.RFC3339)}
+	for i, existing := range pins {
+		if existing.Date != entry.Date {
+			continue
+		}
+		if existing.Note == input.Note {
+			return nil, PinOutput{Pin: existing}, nil
+		}
+		pins[i].Note = input.Note
+		if err := savePins(pins); err != nil {
+			return nil, PinOutput{}, err
+		}
+		return nil, PinOutput{Pin: pins[i], Changed: true}, nil
+	}
+
+	if err := savePins(append(pins, pin)); err != nil {
+		return nil, PinOutput{}, err
+	}
+	return nil, PinOutput{Pin: pin, Changed: true}, nil
+}
+
+func handleUnpinEntry(ctx context.Context, req *mcp.CallToolRequest, input UnpinEntryInput) (
+	*mcp.CallToolResult,
+	PinOutput,
+	error,
+) {
+	// dangling pins can be removed too, so the entry itself is not looked up
+	if _, err := time.Parse("2006-01-02", input.Date); err != nil {
+		return nil, PinOutput{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", input.Date)
+	}
+
+	pinsMu.Lock()
+	defer pinsMu.Unlock()
+	pins, err := loadPins()
+	if err != nil {
+		return nil, PinOutput{}, err
+	}
+
+	for i, pin := range pins {
+		if pin.Date == input.Date {
+			if err := savePins(append(pins[:i], pins[i+1:]...)); err != nil {
+				return nil, PinOutput{}, err
+			}
+			return nil, PinOutput{Pin: pin, Changed: true}, nil
+		}
+	}
+	return nil, PinOutput{Pin: Pin{Date: input.Date}}, nil
+}
+
+func handleGetPinned(ctx context.Context, req *mcp.CallToolRequest, input GetPinnedInput) (
+	*mcp.CallToolResult,
+	PinnedOutput,
+	error,
+) {
+	pinsMu.Lock()
+	pins, err := loadPins()
+	pinsMu.Unlock()
+	if err != nil {
+		return nil, PinnedOutput{}, err
+	}
+	sort.Slice(pins, func(i, j int) bool { return pins[i].Date > pins[j].Date })
+
+	dates := make([]time.Time, 0, len(pins))
+	for _, pin := range pins {
+		if date, err := time.Parse("2006-01-02", pin.Date); err == nil {
+			dates = append(dates, date)
+		}
+	}
+	q := entryQuery{
+		filter: query.Filter[Entry]{Dates: dates, Fields: input.Fields},
+		shape: contentShape{
+			stripFrontmatter: input.StripFrontmatter,
+			previewChars:     input.PreviewChars,
+			omitContent:      input.OmitContent,
+			encoding:         input.ContentEncoding,
+		},
+		maxTokens: input.MaxTokens,
+		scope:     input.Scope,
+	}
+	result, err := findEntries(ctx, q)
+	if err != nil {
+		return nil, PinnedOutput{}, err
+	}
+
+	found := map[string]bool{}
+	for _, entry := range result.Items {
+		found[entry.Date] = true
+	}
+	pinned := PinnedOutput{Pins: pins, EntriesOutput: entriesOutput(ctx, q, result)}
+	if pinned.Pins == nil {
+		pinned.Pins = []Pin{}
+	}
+	for _, pin := range pins {
+		if !found[pin.Date] && !result.Partial {
+			pinned.Missing = append(pinned.Missing, pin.Date)
+		}
+	}
+
+	return nil, pinned, nil
+}
+
+// helpers
+
+// loadPins reads the pins file, a missing file means nothing is pinned. callers hold pinsMu.
+func loadPins() ([]Pin, error) {
+	path, err := resolveInVault(pinsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", pinsFile, err)
+	}
+
+	var file struct {
+		Pins []Pin `json:"pins"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", pinsFile, err)
+	}
+	return file.Pins, nil
+}
+
+// savePins replaces the pins file, callers hold pinsMu
+func savePins(pins []Pin) error {
+	path, err := resolveInVault(pinsFile)
+	if err != nil {
+		return err
+	}
+	if pins == nil {
+		pins = []Pin{}
+	}
+
+	data, err := json.MarshalIndent(struct {
+		Pins []Pin `json:"pins"`
+	}{pins}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := writeFileAtomic(path, append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write %s: %w", pinsFile, err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestPinEntry(t *testing.T) {
+	newTestVault(t, map[string]string{
+		"2024-03-10.md": "# Sunday\n",
+		"2024-03-12.md": "# Tuesday\n",
+	})
+	oldLocation := location
+	location = time.UTC
+	defer func() { location = oldLocation }()
+	setTestNow(t, "2024-03-15T08:00:00Z")
+	ctx := context.Background()
+	pinnedAt := "2024-03-15T08:00:00Z"
+
+	steps := []struct {
+		name    string
+		pin     *PinEntryInput
+		unpin   *UnpinEntryInput
+		want    Pin
+		changed bool
+		pins    []Pin
+	}{
+		{
+			name:    "pin",
+			pin:     &PinEntryInput{Date: "2024-03-10", Note: "moving"},
+			want:    Pin{Date: "2024-03-10", Note: "moving", PinnedAt: pinnedAt},
+			changed: true,
+			pins:    []Pin{{Date: "2024-03-10", Note: "moving", PinnedAt: pinnedAt}},
+		},
+		{
+			name: "pin again with the same note",
+			pin:  &PinEntryInput{Date: "2024-03-10", Note: "moving"},
+			want: Pin{Date: "2024-03-10", Note: "moving", PinnedAt: pinnedAt},
+			pins: []Pin{{Date: "2024-03-10", Note: "moving", PinnedAt: pinnedAt}},
+		},
+		{
+			name:    "a new note updates the pin in place",
+			pin:     &PinEntryInput{Date: "2024-03-10", Note: "signed"},
+			want:    Pin{Date: "2024-03-10", Note: "signed", PinnedAt: pinnedAt},
+			changed: true,
+			pins:    []Pin{{Date: "2024-03-10", Note: "signed", PinnedAt: pinnedAt}},
+		},
+		{
+			name:    "pin another",
+			pin:     &PinEntryInput{Date: "2024-03-12"},
+			want:    Pin{Date: "2024-03-12", PinnedAt: pinnedAt},
+			changed: true,
+			pins:    []Pin{{Date: "2024-03-10", Note: "signed", PinnedAt: pinnedAt}, {Date: "2024-03-12", PinnedAt: pinnedAt}},
+		},
+		{
+			name:    "unpin",
+			unpin:   &UnpinEntryInput{Date: "2024-03-10"},
+			want:    Pin{Date: "2024-03-10", Note: "signed", PinnedAt: pinnedAt},
+			changed: true,
+			pins:    []Pin{{Date: "2024-03-12", PinnedAt: pinnedAt}},
+		},
+		{
+			name:  "unpin what is not pinned",
+			unpin: &UnpinEntryInput{Date: "2024-03-10"},
+			want:  Pin{Date: "2024-03-10"},
+			pins:  []Pin{{Date: "2024-03-12", PinnedAt: pinnedAt}},
+		},
+	}
+	for _, step := range steps {
+		var output PinOutput
+		var err error
+		if step.pin != nil {
+			_, output, err = handlePinEntry(ctx, nil, *step.pin)
+		} else {
+			_, output, err = handleUnpinEntry(ctx, nil, *step.unpin)
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if output.Pin != step.want || output.Changed != step.changed {
+			t.Errorf("%s = %+v, want %+v, changed %v", step.name, output, step.want, step.changed)
+		}
+		if pins, err := loadPins(); err != nil || !reflect.DeepEqual(pins, step.pins) {
+			t.Errorf("%s: pins file = %+v, %v, want %+v", step.name, pins, err, step.pins)
+		}
+	}
+
+	if _, _, err := handlePinEntry(ctx, nil, PinEntryInput{Date: "2024-03-11"}); err == nil {
+		t.Error("pinned a date without an entry")
+	}
+	if _, _, err := handleUnpinEntry(ctx, nil, UnpinEntryInput{Date: "march"}); err == nil {
+		t.Error("unpinned an invalid date")
+	}
+}
+
+func TestUnpinDanglingPin(t *testing.T) {
+	newTestVault(t, map[string]string{
+		".themis/pins.json": `{"pins":[{"date":"2024-02-01","note":"deleted since","pinnedAt":"2024-02-01T00:00:00Z"}]}`,
+	})
+	ctx := context.Background()
+
+	_, output, err := handleUnpinEntry(ctx, nil, UnpinEntryInput{Date: "2024-02-01"})
+	if err != nil || !output.Changed || output.Pin.Note != "deleted since" {
+		t.Errorf("unpin = %+v, %v", output, err)
+	}
+	if pins, err := loadPins(); err != nil || len(pins) != 0 {
+		t.Errorf("pins = %+v, %v, want none", pins, err)
+	}
+
+	// without pins getPinned returns empty lists, which the output schema requires
+	session := connectTestServer(t, newServer(false, false))
+	result, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "getPinned", Arguments: map[string]any{}})
+	if err != nil || result.IsError {
+		t.Errorf("getPinned without pins = %+v, %v", result, err)
+	}
+}
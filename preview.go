@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// markdown markers dropped from previews: heading hashes, list bullets, quotes, emphasis and highlights
+var (
+	previewLinePattern   = regexp.MustCompile(`(?m)^[ \t]*(?:#{1,6}[ \t]+|[-*+][ \t]+(?:\[[ xX]\][ \t]+)?|\d+[.)][ \t]+|>[ \t]?)`)
+	previewMarkerPattern = regexp.MustCompile(`\*\*|__|==|~~|\x60`)
+)
+
+// applyPreview fills Preview with the first chars characters of each entry's plaintext body,
+// clearing Content and re-estimating tokens for what is left when omitContent is set
+func applyPreview(entries []Entry, chars int, omitContent bool) {
+	for i := range entries {
+		entry := &entries[i]
+		if chars > 0 {
+			entry.Preview = preview(entry.Content, chars)
+		}
+		if omitContent {
+			entry.Content = ""
+			entry.EstimatedTokens = estimateTokens(entry.Preview)
+		}
+	}
+}
+
+// stripFrontmatter drops the --- block from each entry's content, leaving the parsed Frontmatter in place
+func stripFrontmatter(entries []Entry) {
+	for i := range entries {
+		if _, body, ok := splitFrontmatter(entries[i].Content); ok {
+			entries[i].Content = body
+			entries[i].EstimatedTokens = estimateTokens(body)
+		}
+	}
+}
+
+// preview strips frontmatter and markdown markers, collapses whitespace and cuts to chars runes with an ellipsis
+func preview(content string, chars int) string {
+	_, body, _ := splitFrontmatter(content)
+
+	var lines []string
+	inFence := false
+	for _, line := range strings.Split(body, "\n") {
+		if isFenceLine(line) {
+			inFence = !inFence
+			continue
+		}
+		if !inFence {
+			lines = append(lines, line)
+		}
+	}
+
+	text := previewLinePattern.ReplaceAllString(strings.Join(lines, "\n"), "")
+	text = previewMarkerPattern.ReplaceAllString(text, "")
+	text = strings.Join(strings.Fields(text), " ")
+
+	if utf8.RuneCountInString(text) <= chars {
+		return text
+	}
+	runes := []rune(text)
+	return strings.TrimRight(string(runes[:chars]), " ") + "…"
+}
+
+var sentenceEndPattern = regexp.MustCompile(`[.!?…](?:["')\]]*)(?:\s|$)`)
+
+// checkTruncateStrategy validates the truncateStrategy option, empty means head
+func checkTruncateStrategy(strategy string) error {
+	switch strategy {
+	case "", "head", "tail", "structured":
+		return nil
+	}
+	return fmt.Errorf("unknown truncateStrategy %q, expected head, tail or structured", strategy)
+}
+
+// truncateContent cuts each entry's body to maxChars characters, leaving frontmatter whole and marking what was
+// left out with [...]. head keeps the start, tail the end and structured every heading plus the opening sentence
+// of each section, as far as the budget goes.
+func truncateContent(entries []Entry, maxChars int, strategy string) {
+	if maxChars <= 0 {
+		return
+	}
+	for i := range entries {
+		entry := &entries[i]
+		raw, body, hasFrontmatter := splitFrontmatter(entry.Content)
+		if utf8.RuneCountInString(body) <= maxChars {
+			continue
+		}
+
+		switch strategy {
+		case "tail":
+			body = truncateTail(body, maxChars)
+		case "structured":
+			body = truncateStructured(body, maxChars)
+		default:
+			body = truncateHead(body, maxChars)
+		}
+		if hasFrontmatter {
+			body = "---\n" + raw + "---\n" + body
+		}
+		entry.Content = body
+		entry.Truncated = true
+		entry.EstimatedTokens = estimateTokens(body)
+	}
+}
+
+func truncateHead(body string, maxChars int) string {
+	runes := []rune(body)
+	cut := string(runes[:maxChars])
+	if i := strings.LastIndexAny(cut, " \t\n"); i > len(cut)/2 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " \t\n") + " [...]\n"
+}
+
+func truncateTail(body string, maxChars int) string {
+	runes := []rune(body)
+	cut := string(runes[len(runes)-maxChars:])
+	if i := strings.IndexAny(cut, " \t\n"); i >= 0 && i < len(cut)/2 {
+		cut = cut[i:]
+	}
+	return "[...] " + strings.TrimLeft(cut, " \t\n")
+}
+
+// truncateStructured keeps every heading, then adds the first sentence of each section in document order while it
+// still fits the budget. sections that lose anything get a [...] line.
+func truncateStructured(body string, maxChars int) string {
+	lines := strings.Split(body, "\n")
+	headings, _ := parseHeadings(body)
+
+	type section struct {
+		heading  string
+		sentence string
+		omitted  bool
+	}
+	sections := []section{{}}
+	bounds := []int{0}
+	for _, heading := range headings {
+		sections = append(sections, section{heading: strings.Join(lines[heading.line:heading.bodyStart], "\n")})
+		bounds = append(bounds, heading.line)
+	}
+	bounds = append(bounds, len(lines))
+
+	used := 0
+	for i := range sections {
+		used += utf8.RuneCountInString(sections[i].heading)
+	}
+	for i := range sections {
+		start := bounds[i]
+		if i > 0 {
+			start = headings[i-1].bodyStart
+		}
+		sentence, rest := firstSentence(lines[max(start, 0):max(bounds[i+1], start)])
+		if sentence != "" && used+utf8.RuneCountInString(sentence) <= maxChars {
+			sections[i].sentence = sentence
+			used += utf8.RuneCountInString(sentence)
+		} else if sentence != "" {
+			rest = true
+		}
+		sections[i].omitted = rest
+	}
+
+	var out []string
+	for _, s := range sections {
+		if s.heading != "" {
+			out = append(out, s.heading)
+		}
+		if s.sentence != "" {
+			out = append(out, s.sentence)
+		}
+		if s.omitted {
+			out = append(out, "[...]")
+		}
+	}
+	return strings.Join(out, "\n\n") + "\n"
+}
+
+// firstSentence returns the opening sentence of the first paragraph or list item in lines, skipping fenced code,
+// and whether anything else in lines is left over
+func firstSentence(lines []string) (string, bool) {
+	start, end := -1, len(lines)
+	inFence := false
+	for i, line := range lines {
+		if isFenceLine(line) {
+			inFence = !inFence
+			if start >= 0 {
+				end = i
+				break
+			}
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		blank := strings.TrimSpace(line) == ""
+		if start < 0 {
+			if !blank {
+				start = i
+			}
+			continue
+		}
+		// a blank line ends the paragraph and every list item is a block of its own
+		if blank || listOrQuotePattern.MatchString(line) {
+			end = i
+			break
+		}
+	}
+	if start < 0 {
+		return "", hasText(lines)
+	}
+
+	block := make([]string, 0, end-start)
+	for _, line := range lines[start:end] {
+		block = append(block, strings.TrimSpace(line))
+	}
+	text := strings.Join(block, " ")
+	rest := hasText(lines[:start]) || hasText(lines[end:])
+	if loc := sentenceEndPattern.FindStringIndex(text); loc != nil {
+		rest = rest || strings.TrimSpace(text[loc[1]:]) != ""
+		text = strings.TrimSpace(text[:loc[1]])
+	}
+	return text, rest
+}
+
+func hasText(lines []string) bool {
+	return strings.TrimSpace(strings.Join(lines, "")) != ""
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPreview(t *testing.T) {
+	content := "---\nmood: 4\n---\n# Friday\nWent for a **long** run.\n\n```\ncode is skipped\n```\nCalled mum.\n"
+	tests := []struct {
+		chars int
+		want  string
+	}{
+		{8, "Friday W…"},
+		{7, "Friday…"},
+		{13, "Friday Went f…"},
+		{46, "Friday Went for a long run. Called mum."},
+		{500, "Friday Went for a long run. Called mum."},
+	}
+	for _, tt := range tests {
+		if got := preview(content, tt.chars); got != tt.want {
+			t.Errorf("preview(%d) = %q, want %q", tt.chars, got, tt.want)
+		}
+	}
+}
+
+func TestPreviewCharsOnReadTools(t *testing.T) {
+	newTestVault(t, map[string]string{
+		"2024-03-15.md":     "---\nmood: 4\nplace: Kyoto\n---\n# Friday\nthread:: move\npacked the last boxes\n",
+		".themis/pins.json": `{"pins":[{"date":"2024-03-15"}]}`,
+	})
+	ctx := context.Background()
+	const want = "Friday thread:: move packed…"
+
+	tests := []struct {
+		name string
+		run  func() ([]Entry, error)
+	}{
+		{"by year", func() ([]Entry, error) {
+			_, output, err := handleGetEntriesByYear(ctx, nil, GetEntriesByYearInput{Year: 2024, PreviewChars: 27, OmitContent: true})
+			return output.Entries, err
+		}},
+		{"by dates", func() ([]Entry, error) {
+			_, output, err := handleGetEntriesByDates(ctx, nil, GetEntriesByDatesInput{Dates: []string{"2024-03-15"}, PreviewChars: 27, OmitContent: true})
+			return output.Entries, err
+		}},
+		{"neighborhood", func() ([]Entry, error) {
+			_, output, err := handleGetEntryNeighborhood(ctx, nil, GetEntryNeighborhoodInput{Date: "2024-03-15", PreviewChars: 27, OmitContent: true})
+			return output.Entries, err
+		}},
+		{"offset", func() ([]Entry, error) {
+			_, output, err := handleGetEntryByOffset(ctx, nil, GetEntryByOffsetInput{PreviewChars: 27, OmitContent: true})
+			return []Entry{output.Entry}, err
+		}},
+		{"pinned", func() ([]Entry, error) {
+			_, output, err := handleGetPinned(ctx, nil, GetPinnedInput{PreviewChars: 27, OmitContent: true})
+			return output.Entries, err
+		}},
+		{"thread", func() ([]Entry, error) {
+			_, output, err := handleGetThread(ctx, nil, GetThreadInput{Thread: "move", PreviewChars: 27, OmitContent: true})
+			return output.Entries, err
+		}},
+		{"frontmatter search", func() ([]Entry, error) {
+			_, output, err := handleSearchFrontmatter(ctx, nil, SearchFrontmatterInput{Field: "place", Pattern: "^Kyo", PreviewChars: 27, OmitContent: true})
+			return output.Entries, err
+		}},
+		{"frontmatter field", func() ([]Entry, error) {
+			_, output, err := handleFilterEntriesWithField(ctx, nil, FilterEntriesWithFieldInput{Field: "mood", PreviewChars: 27, OmitContent: true})
+			return output.Entries, err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := tt.run()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Fatalf("got %d entries, want 1", len(entries))
+			}
+			if entries[0].Preview != want || entries[0].Content != "" {
+				t.Errorf("preview = %q with content %q, want %q and no content", entries[0].Preview, entries[0].Content, want)
+			}
+		})
+	}
+}
+
+func TestStripFrontmatterOnReadTools(t *testing.T) {
+	const content = "---\nmood: 4\n---\n# Friday\nthread:: move\npacked the last boxes\n"
+	newTestVault(t, map[string]string{
+		"2024-03-15.md":     content,
+		".themis/pins.json": `{"pins":[{"date":"2024-03-15"}]}`,
+	})
+	ctx := context.Background()
+
+	tools := []struct {
+		name string
+		run  func(strip bool) ([]Entry, error)
+	}{
+		{"by year", func(strip bool) ([]Entry, error) {
+			_, output, err := handleGetEntriesByYear(ctx, nil, GetEntriesByYearInput{Year: 2024, StripFrontmatter: strip})
+			return output.Entries, err
+		}},
+		{"by dates", func(strip bool) ([]Entry, error) {
+			_, output, err := handleGetEntriesByDates(ctx, nil, GetEntriesByDatesInput{Dates: []string{"2024-03-15"}, StripFrontmatter: strip})
+			return output.Entries, err
+		}},
+		{"neighborhood", func(strip bool) ([]Entry, error) {
+			_, output, err := handleGetEntryNeighborhood(ctx, nil, GetEntryNeighborhoodInput{Date: "2024-03-15", StripFrontmatter: strip})
+			return output.Entries, err
+		}},
+		{"offset", func(strip bool) ([]Entry, error) {
+			_, output, err := handleGetEntryByOffset(ctx, nil, GetEntryByOffsetInput{StripFrontmatter: strip})
+			return []Entry{output.Entry}, err
+		}},
+		{"pinned", func(strip bool) ([]Entry, error) {
+			_, output, err := handleGetPinned(ctx, nil, GetPinnedInput{StripFrontmatter: strip})
+			return output.Entries, err
+		}},
+		{"thread", func(strip bool) ([]Entry, error) {
+			_, output, err := handleGetThread(ctx, nil, GetThreadInput{Thread: "move", StripFrontmatter: strip})
+			return output.Entries, err
+		}},
+	}
+	for _, tool := range tools {
+		for _, strip := range []bool{false, true} {
+			want := content
+			if strip {
+				want = "# Friday\nthread:: move\npacked the last boxes\n"
+			}
+			entries, err := tool.run(strip)
+			if err != nil {
+				t.Fatalf("%s: %v", tool.name, err)
+			}
+			if len(entries) != 1 {
+				t.Fatalf("%s: got %d entries, want 1", tool.name, len(entries))
+			}
+			if entries[0].Content != want {
+				t.Errorf("%s with strip %v: content = %q, want %q", tool.name, strip, entries[0].Content, want)
+			}
+			if entries[0].Frontmatter["mood"] == nil {
+				t.Errorf("%s with strip %v: frontmatter = %v, want mood kept", tool.name, strip, entries[0].Frontmatter)
+			}
+		}
+	}
+}
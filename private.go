@@ -0,0 +1,26 @@
+package main
+
+import "strings"
+
+// isPrivate reports whether an entry is marked private, with private: true in its frontmatter or a #private tag.
+// private entries are left out of suggestions the user didn't ask for by date.
+func isPrivate(entry Entry) bool {
+	switch value := entry.Frontmatter["private"].(type) {
+	case bool:
+		if value {
+			return true
+		}
+	case string:
+		if strings.EqualFold(strings.TrimSpace(value), "true") {
+			return true
+		}
+	}
+
+	_, body, _ := splitFrontmatter(entry.Content)
+	for _, tag := range extractTags(entry.Frontmatter, body) {
+		if strings.EqualFold(tag, "private") {
+			return true
+		}
+	}
+	return false
+}
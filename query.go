@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/curator4/mcp-server-diary/query"
+)
+
+// entryEngine is what every read tool queries, handlers only map their inputs onto a filter. its time budgets are
+// measured with wallNow.
+var entryEngine = query.Engine[Entry]{Source: vaultSource{}, Fields: entryFieldNames(), DefaultFields: defaultFields, Now: wallNow}
+
+// contentShape is how a read tool trims and encodes entry content before returning it, the zero value
+// hands content out as read
+type contentShape struct {
+	stripFrontmatter bool
+	previewChars     int
+	omitContent      bool
+	maxContentLength int
+	truncateStrategy string
+	encoding         string
+}
+
+// entryQuery is a read tool's request mapped onto the engine: which entries to read, how their content is
+// shaped and how much of it fits in the response
+type entryQuery struct {
+	filter query.Filter[Entry]
+
+	shape          contentShape
+	relativeLabels bool
+	maxTokens      int
+	timeoutMs      int
+	scope          string
+}
+
+// vaultSource walks the entry files under root, entryRoot when it is empty. only entryRoot is indexed.
+type vaultSource struct {
+	root string
+}
+
+func (s vaultSource) Walk(ctx context.Context, keep func(day time.Time) bool, fn func(entry Entry) error) (query.Progress, error) {
+	progress, err := walkEntriesIn(ctx, s.dir(), keep, fn)
+	return query.Progress{Scanned: progress.Scanned, Partial: progress.Partial}, err
+}
+
+func (s vaultSource) Days(keep func(day time.Time) bool) ([]time.Time, error) {
+	if s.dir() == entryRoot {
+		return entryFileDates(keep)
+	}
+	return entryFileDatesIn(s.root, keep)
+}
+
+func (s vaultSource) dir() string {
+	if s.root == "" {
+		return entryRoot
+	}
+	return s.root
+}
+
+// Day, Path, Tags and Text make Entry a query.Item
+func (e Entry) Day() time.Time {
+	day, _ := time.Parse("2006-01-02", e.Date)
+	return day
+}
+
+func (e Entry) Path() string { return e.FilePath }
+
+func (e Entry) Tags() []string {
+	_, body, _ := splitFrontmatter(e.Content)
+	return extractTags(e.Frontmatter, body)
+}
+
+func (e Entry) Text() string { return e.Content }
+
+// check validates the shape options so a bad value fails before the vault is walked
+func (s contentShape) check() error {
+	if err := checkContentEncoding(s.encoding); err != nil {
+		return err
+	}
+	return checkTruncateStrategy(s.truncateStrategy)
+}
+
+// apply runs the shaping steps in order, encoding last so it covers whatever content is left
+func (s contentShape) apply(entries []Entry) {
+	if s.stripFrontmatter {
+		stripFrontmatter(entries)
+	}
+	applyPreview(entries, s.previewChars, s.omitContent)
+	truncateContent(entries, s.maxContentLength, s.truncateStrategy)
+	encodeContent(entries, s.encoding)
+}
+
+// runEntryQuery finds the entries q selects within the call's time budget and returns them shaped, in the
+// filter's order. a walk cut short by the budget is marked partial.
+func runEntryQuery(ctx context.Context, q entryQuery) (EntriesOutput, error) {
+	result, err := findEntries(ctx, q)
+	if err != nil {
+		return EntriesOutput{}, err
+	}
+	return entriesOutput(ctx, q, result), nil
+}
+
+// findEntries runs q's filter through the engine, for tools that rearrange the entries before entriesOutput
+func findEntries(ctx context.Context, q entryQuery) (query.Result[Entry], error) {
+	if err := q.shape.check(); err != nil {
+		return query.Result[Entry]{}, err
+	}
+	if q.filter.Timeout == 0 {
+		q.filter.Timeout = toolBudget(q.timeoutMs)
+	}
+
+	engine := entryEngine
+	if q.scope != "" {
+		root, err := entryScope(q.scope)
+		if err != nil {
+			return query.Result[Entry]{}, err
+		}
+		engine.Source = vaultSource{root: root}
+	}
+	return engine.Execute(ctx, q.filter)
+}
+
+// collectEntries returns every entry f selects, oldest first, for tools that work through a range as a whole
+func collectEntries(ctx context.Context, f query.Filter[Entry]) ([]Entry, error) {
+	f.Order = query.OldestFirst
+	result, err := entryEngine.Execute(ctx, f)
+	return result.Items, err
+}
+
+// eachEntry hands the entries f selects to fn in walk order, for tools that aggregate over the vault without
+// holding every entry at once
+func eachEntry(ctx context.Context, f query.Filter[Entry], fn func(entry Entry) error) error {
+	_, err := entryEngine.Each(ctx, f, fn)
+	return err
+}
+
+// rangeFilter maps optional YYYY-MM-DD start and end inputs onto a filter over that range
+func rangeFilter(start, end string) (query.Filter[Entry], error) {
+	from, to, err := parseDateRange(start, end)
+	if err != nil {
+		return query.Filter[Entry]{}, err
+	}
+	return query.Filter[Entry]{Start: from, End: to}, nil
+}
+
+// parseWeekdays reads weekday names such as monday, compared case-insensitively
+func parseWeekdays(names []string) ([]time.Weekday, error) {
+	var weekdays []time.Weekday
+	for _, name := range names {
+		found := false
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			if strings.EqualFold(day.String(), strings.TrimSpace(name)) {
+				weekdays, found = append(weekdays, day), true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("invalid weekday %q, expected a weekday name such as monday", name)
+		}
+	}
+	return weekdays, nil
+}
+
+// entriesOutput shapes the result's entries, fits them to q's token budget and selects their fields. length
+// percentiles are computed within what is left of the walk's time budget.
+func entriesOutput(ctx context.Context, q entryQuery, result query.Result[Entry]) EntriesOutput {
+	entries := result.Items
+	q.shape.apply(entries)
+	if q.relativeLabels {
+		applyRelativeLabels(entries)
+	}
+
+	if !result.Deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, result.Deadline)
+		defer cancel()
+	}
+	output := newEntriesOutput(ctx, entries, q.maxTokens, result.Fields)
+	if result.Partial {
+		output.Partial = true
+		output.ScannedFiles = result.Scanned
+		output.TotalFiles = result.TotalFiles
+	}
+	return output
+}
@@ -0,0 +1,328 @@
+// Package query selects, orders and pages diary entries for the read tools. It does not know how entries
+// are stored: a Source walks them and an Engine applies a Filter on top, so every tool filters, sorts,
+// limits and reports partial walks the same way.
+package query
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Item is what a Source hands the engine for each entry it reads
+type Item interface {
+	Day() time.Time // entry date at midnight utc
+	Path() string   // breaks ties between entries of the same day
+	Tags() []string
+	Text() string
+}
+
+// Source walks stored entries. keep is decided from the entry date alone, before anything is read.
+type Source[T Item] interface {
+	// Walk passes every entry whose day keep accepts to fn. once ctx is done it stops and reports the walk as partial.
+	Walk(ctx context.Context, keep func(day time.Time) bool, fn func(item T) error) (Progress, error)
+	// Days lists the day of every entry file keep accepts, from file names alone
+	Days(keep func(day time.Time) bool) ([]time.Time, error)
+}
+
+type Progress struct {
+	Scanned int  // entries passed to fn
+	Partial bool // the walk stopped early because its context was done
+}
+
+type Order int
+
+const (
+	NewestFirst Order = iota
+	OldestFirst
+)
+
+// Filter describes which entries a tool wants and how they come back. the zero value selects every entry,
+// newest first, with the engine's default fields.
+type Filter[T Item] struct {
+	Start time.Time // first day to include, zero leaves the range open
+	End   time.Time // last day to include, zero leaves the range open
+
+	Dates    []time.Time    // only these days, on top of Start and End
+	Weekdays []time.Weekday // only these weekdays
+
+	Tags  []string     // every tag must be present, compared case-insensitively, a leading # is ignored
+	Text  string       // case-insensitive substring the entry text must contain
+	Match func(T) bool // any other condition on a read entry
+
+	// Offset and Limit page the ordered matches. when only dates decide what matches they count distinct days,
+	// so a day with several files is never split.
+	Order  Order
+	Offset int // entries to skip after ordering
+	Limit  int // entries to return after Offset, 0 returns all
+
+	Fields []string // entry fields to return, empty selects the engine defaults
+
+	// Timeout bounds the walk, layered over the caller's context so its cancellation still wins. 0 or less means
+	// no budget.
+	Timeout time.Duration
+}
+
+type Result[T Item] struct {
+	Items  []T
+	Fields map[string]bool // selected fields, nil selects every field
+
+	// Total counts the entries matching the filter before Offset and Limit. when only dates decide what matches
+	// it is counted from file names, as distinct days, without reading the entries that are skipped.
+	Total int
+
+	Partial    bool // the time budget ran out and only some entries were read
+	Scanned    int  // entries read before the budget ran out
+	TotalFiles int  // entry files the filter covers, set when Partial
+
+	// Deadline is when the filter's time budget runs out, zero without one, so work a caller does on the
+	// result can stay within the same budget
+	Deadline time.Time
+}
+
+type Engine[T Item] struct {
+	Source        Source[T]
+	Fields        []string         // every selectable field name
+	DefaultFields map[string]bool  // used when a filter names no fields, nil selects every field
+	Now           func() time.Time // the clock Timeout runs on, time.Now when nil
+}
+
+// Execute walks the entries f selects and returns them ordered and paged. a walk cut short by f.Timeout comes
+// back as a partial result, one cut short by ctx fails with ctx's error.
+func (e Engine[T]) Execute(ctx context.Context, f Filter[T]) (Result[T], error) {
+	fields, err := ParseFields(f.Fields, e.Fields, e.DefaultFields)
+	if err != nil {
+		return Result[T]{}, err
+	}
+	if f.Offset < 0 || f.Limit < 0 {
+		return Result[T]{}, fmt.Errorf("offset and limit must not be negative")
+	}
+	if err := f.checkRange(); err != nil {
+		return Result[T]{}, err
+	}
+
+	keep := f.KeepDay()
+	walkKeep := keep
+	result := Result[T]{Fields: fields}
+	offset, limit := f.Offset, f.Limit
+
+	// when dates alone decide, paging picks the days up front so the skipped entries are never read
+	paged := (offset > 0 || limit > 0) && !f.readsContent()
+	if paged {
+		days, err := e.Source.Days(keep)
+		if err != nil {
+			return Result[T]{}, fmt.Errorf("failed to get entries: %w", err)
+		}
+		days = distinctDays(days, f.Order)
+		result.Total = len(days)
+		days = page(days, offset, limit)
+		selected := map[time.Time]bool{}
+		for _, day := range days {
+			selected[day] = true
+		}
+		// every file of a selected day is returned, a day with several files counts once
+		walkKeep = func(day time.Time) bool { return selected[day] }
+		offset, limit = 0, 0
+	}
+
+	walkCtx, cancel := ctx, context.CancelFunc(func() {})
+	if f.Timeout > 0 {
+		result.Deadline = e.now().Add(f.Timeout)
+		walkCtx, cancel = context.WithDeadline(ctx, result.Deadline)
+	}
+	defer cancel()
+
+	var items []T
+	progress, err := e.Source.Walk(walkCtx, walkKeep, func(item T) error {
+		if f.matches(item) {
+			items = append(items, item)
+		}
+		return nil
+	})
+	if err != nil {
+		return Result[T]{}, fmt.Errorf("failed to get entries: %w", err)
+	}
+	if progress.Partial {
+		if err := ctx.Err(); err != nil {
+			return Result[T]{}, err
+		}
+		days, err := e.Source.Days(walkKeep)
+		if err != nil {
+			return Result[T]{}, fmt.Errorf("failed to get entries: %w", err)
+		}
+		result.Partial, result.Scanned, result.TotalFiles = true, progress.Scanned, len(days)
+	}
+
+	sortItems(items, f.Order)
+	if !paged {
+		result.Total = len(items)
+	}
+	result.Items = page(items, offset, limit)
+	return result, nil
+}
+
+// Each streams the entries f selects to fn in the source's walk order without holding them, for tools that
+// aggregate over many entries. Order, Offset, Limit and Fields only make sense for collected results, so a
+// filter paging its matches is refused. errors from fn are returned as they are.
+func (e Engine[T]) Each(ctx context.Context, f Filter[T], fn func(item T) error) (Progress, error) {
+	if f.Offset != 0 || f.Limit != 0 {
+		return Progress{}, fmt.Errorf("offset and limit need ordered results, use Execute")
+	}
+	if err := f.checkRange(); err != nil {
+		return Progress{}, err
+	}
+
+	if f.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, e.now().Add(f.Timeout))
+		defer cancel()
+	}
+	return e.Source.Walk(ctx, f.KeepDay(), func(item T) error {
+		if !f.matches(item) {
+			return nil
+		}
+		return fn(item)
+	})
+}
+
+// ParseFields validates requested field names against valid, an empty list selects defaults
+func ParseFields(names, valid []string, defaults map[string]bool) (map[string]bool, error) {
+	if len(names) == 0 {
+		return defaults, nil
+	}
+	return FieldSet(names, valid)
+}
+
+// FieldSet turns field names into a set, failing on the first name that is not in valid
+func FieldSet(names, valid []string) (map[string]bool, error) {
+	known := map[string]bool{}
+	for _, name := range valid {
+		known[name] = true
+	}
+
+	fields := map[string]bool{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if !known[name] {
+			return nil, fmt.Errorf("unknown field %q, valid fields are: %s", name, strings.Join(valid, ", "))
+		}
+		fields[name] = true
+	}
+	return fields, nil
+}
+
+// KeepDay is the part of the filter decided by the day alone, for indexes that can answer without reading entries
+func (f Filter[T]) KeepDay() func(day time.Time) bool {
+	var dates map[time.Time]bool
+	if f.Dates != nil {
+		dates = make(map[time.Time]bool, len(f.Dates))
+		for _, date := range f.Dates {
+			dates[date] = true
+		}
+	}
+	var weekdays map[time.Weekday]bool
+	if len(f.Weekdays) > 0 {
+		weekdays = make(map[time.Weekday]bool, len(f.Weekdays))
+		for _, weekday := range f.Weekdays {
+			weekdays[weekday] = true
+		}
+	}
+
+	return func(day time.Time) bool {
+		switch {
+		case !f.Start.IsZero() && day.Before(f.Start):
+			return false
+		case !f.End.IsZero() && day.After(f.End):
+			return false
+		case dates != nil && !dates[day]:
+			return false
+		case weekdays != nil && !weekdays[day.Weekday()]:
+			return false
+		}
+		return true
+	}
+}
+
+// helpers
+
+func (e Engine[T]) now() time.Time {
+	if e.Now == nil {
+		return time.Now()
+	}
+	return e.Now()
+}
+
+func (f Filter[T]) checkRange() error {
+	if !f.Start.IsZero() && !f.End.IsZero() && f.End.Before(f.Start) {
+		return fmt.Errorf("end date %s is before start date %s", f.End.Format("2006-01-02"), f.Start.Format("2006-01-02"))
+	}
+	return nil
+}
+
+// readsContent reports whether matching needs the entry itself rather than just its date
+func (f Filter[T]) readsContent() bool {
+	return len(f.Tags) > 0 || f.Text != "" || f.Match != nil
+}
+
+func (f Filter[T]) matches(item T) bool {
+	if len(f.Tags) > 0 {
+		have := map[string]bool{}
+		for _, tag := range item.Tags() {
+			have[strings.ToLower(tag)] = true
+		}
+		for _, tag := range f.Tags {
+			if !have[strings.ToLower(strings.TrimPrefix(tag, "#"))] {
+				return false
+			}
+		}
+	}
+	if f.Text != "" && !strings.Contains(strings.ToLower(item.Text()), strings.ToLower(f.Text)) {
+		return false
+	}
+	return f.Match == nil || f.Match(item)
+}
+
+// sortItems orders items by day, breaking ties by path in ascending order either way
+func sortItems[T Item](items []T, order Order) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if !a.Day().Equal(b.Day()) {
+			if order == OldestFirst {
+				return a.Day().Before(b.Day())
+			}
+			return a.Day().After(b.Day())
+		}
+		return a.Path() < b.Path()
+	})
+}
+
+func distinctDays(days []time.Time, order Order) []time.Time {
+	seen := map[time.Time]bool{}
+	distinct := make([]time.Time, 0, len(days))
+	for _, day := range days {
+		if !seen[day] {
+			seen[day] = true
+			distinct = append(distinct, day)
+		}
+	}
+	sort.Slice(distinct, func(i, j int) bool {
+		if order == OldestFirst {
+			return distinct[i].Before(distinct[j])
+		}
+		return distinct[i].After(distinct[j])
+	})
+	return distinct
+}
+
+func page[S ~[]E, E any](items S, offset, limit int) S {
+	if offset >= len(items) {
+		return items[:0]
+	}
+	items = items[offset:]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
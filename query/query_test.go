@@ -0,0 +1,402 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testItem struct {
+	day  time.Time
+	path string
+	tags []string
+	text string
+}
+
+func (i testItem) Day() time.Time { return i.day }
+func (i testItem) Path() string   { return i.path }
+func (i testItem) Tags() []string { return i.tags }
+func (i testItem) Text() string   { return i.text }
+
+// memorySource serves items from memory in the order given, like a directory walk it does not sort them.
+// every read is recorded so tests can check which items were touched.
+type memorySource struct {
+	items []testItem
+	read  []string
+	delay time.Duration
+}
+
+func (s *memorySource) Walk(ctx context.Context, keep func(day time.Time) bool, fn func(item testItem) error) (Progress, error) {
+	var progress Progress
+	for _, item := range s.items {
+		if !keep(item.day) {
+			continue
+		}
+		if s.delay > 0 {
+			time.Sleep(s.delay)
+		}
+		if ctx.Err() != nil {
+			progress.Partial = true
+			return progress, nil
+		}
+		s.read = append(s.read, item.path)
+		progress.Scanned++
+		if err := fn(item); err != nil {
+			return progress, err
+		}
+	}
+	return progress, nil
+}
+
+func (s *memorySource) Days(keep func(day time.Time) bool) ([]time.Time, error) {
+	var days []time.Time
+	for _, item := range s.items {
+		if keep(item.day) {
+			days = append(days, item.day)
+		}
+	}
+	return days, nil
+}
+
+type failingSource struct{ memorySource }
+
+func (failingSource) Walk(ctx context.Context, keep func(day time.Time) bool, fn func(item testItem) error) (Progress, error) {
+	return Progress{}, errors.New("disk on fire")
+}
+
+func day(value string) time.Time {
+	parsed, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+// fixture covers a week and a bit, 2024-03-13 is a wednesday and has two files
+func fixture() *memorySource {
+	return &memorySource{items: []testItem{
+		{day: day("2024-03-13"), path: "2024/2024-03-13.md", tags: []string{"work"}, text: "Standup ran long"},
+		{day: day("2024-03-11"), path: "2024-03-11.md", tags: []string{"Work", "gym"}, text: "Leg day"},
+		{day: day("2024-03-16"), path: "2024-03-16.md", tags: []string{"weekend"}, text: "Hiked the ridge"},
+		{day: day("2024-03-13"), path: "2024-03-13.md", tags: nil, text: "Dinner with Sam"},
+		{day: day("2024-03-20"), path: "2024-03-20.md", tags: []string{"gym"}, text: "Rowed 5k"},
+		{day: day("2024-03-17"), path: "2024-03-17.md", tags: []string{"weekend", "work"}, text: "Planning the week"},
+	}}
+}
+
+func paths(items []testItem) []string {
+	out := make([]string, len(items))
+	for i, item := range items {
+		out[i] = item.path
+	}
+	return out
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter[testItem]
+		want   []string
+		total  int
+	}{
+		{
+			name:   "everything newest first, same day broken by path",
+			filter: Filter[testItem]{},
+			want:   []string{"2024-03-20.md", "2024-03-17.md", "2024-03-16.md", "2024-03-13.md", "2024/2024-03-13.md", "2024-03-11.md"},
+			total:  6,
+		},
+		{
+			name:   "oldest first keeps the path tie-break ascending",
+			filter: Filter[testItem]{Order: OldestFirst},
+			want:   []string{"2024-03-11.md", "2024-03-13.md", "2024/2024-03-13.md", "2024-03-16.md", "2024-03-17.md", "2024-03-20.md"},
+			total:  6,
+		},
+		{
+			name:   "range is inclusive at both ends",
+			filter: Filter[testItem]{Start: day("2024-03-13"), End: day("2024-03-16")},
+			want:   []string{"2024-03-16.md", "2024-03-13.md", "2024/2024-03-13.md"},
+			total:  3,
+		},
+		{
+			name:   "open ended start",
+			filter: Filter[testItem]{Start: day("2024-03-17")},
+			want:   []string{"2024-03-20.md", "2024-03-17.md"},
+			total:  2,
+		},
+		{
+			name:   "dates ignore days without an entry",
+			filter: Filter[testItem]{Dates: []time.Time{day("2024-03-11"), day("2024-03-12"), day("2024-03-20")}},
+			want:   []string{"2024-03-20.md", "2024-03-11.md"},
+			total:  2,
+		},
+		{
+			name:   "empty dates select nothing",
+			filter: Filter[testItem]{Dates: []time.Time{}},
+			want:   []string{},
+			total:  0,
+		},
+		{
+			name:   "weekdays",
+			filter: Filter[testItem]{Weekdays: []time.Weekday{time.Saturday, time.Sunday}},
+			want:   []string{"2024-03-17.md", "2024-03-16.md"},
+			total:  2,
+		},
+		{
+			name:   "tags are all required, case-insensitive and may carry a hash",
+			filter: Filter[testItem]{Tags: []string{"#WORK", "weekend"}},
+			want:   []string{"2024-03-17.md"},
+			total:  1,
+		},
+		{
+			name:   "text is a case-insensitive substring",
+			filter: Filter[testItem]{Text: "the"},
+			want:   []string{"2024-03-17.md", "2024-03-16.md"},
+			total:  2,
+		},
+		{
+			name: "match runs on top of the other conditions",
+			filter: Filter[testItem]{
+				Tags:  []string{"gym"},
+				Match: func(item testItem) bool { return strings.HasPrefix(item.text, "Leg") },
+			},
+			want:  []string{"2024-03-11.md"},
+			total: 1,
+		},
+		{
+			name:   "limit",
+			filter: Filter[testItem]{Limit: 2},
+			want:   []string{"2024-03-20.md", "2024-03-17.md"},
+			total:  5,
+		},
+		{
+			name:   "offset and limit page by distinct day",
+			filter: Filter[testItem]{Offset: 3, Limit: 1},
+			want:   []string{"2024-03-13.md", "2024/2024-03-13.md"},
+			total:  5,
+		},
+		{
+			name:   "offset past the end",
+			filter: Filter[testItem]{Offset: 10},
+			want:   []string{},
+			total:  5,
+		},
+		{
+			name:   "paging after a content condition counts matches",
+			filter: Filter[testItem]{Tags: []string{"work"}, Offset: 1, Limit: 1, Order: OldestFirst},
+			want:   []string{"2024/2024-03-13.md"},
+			total:  3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := Engine[testItem]{Source: fixture()}
+			result, err := engine.Execute(context.Background(), tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := paths(result.Items); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("items = %v, want %v", got, tt.want)
+			}
+			if result.Total != tt.total {
+				t.Errorf("total = %d, want %d", result.Total, tt.total)
+			}
+			if result.Partial {
+				t.Errorf("result is partial without a time budget")
+			}
+		})
+	}
+}
+
+func TestExecutePagingReadsOnlyTheSelectedDays(t *testing.T) {
+	source := fixture()
+	engine := Engine[testItem]{Source: source}
+	result, err := engine.Execute(context.Background(), Filter[testItem]{Offset: 1, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := paths(result.Items); !reflect.DeepEqual(got, []string{"2024-03-17.md"}) {
+		t.Errorf("items = %v", got)
+	}
+	if !reflect.DeepEqual(source.read, []string{"2024-03-17.md"}) {
+		t.Errorf("read %v, want only the paged entry", source.read)
+	}
+}
+
+func TestExecuteFields(t *testing.T) {
+	engine := Engine[testItem]{
+		Source:        fixture(),
+		Fields:        []string{"date", "content", "tags"},
+		DefaultFields: map[string]bool{"date": true},
+	}
+
+	result, err := engine.Execute(context.Background(), Filter[testItem]{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Fields, map[string]bool{"date": true}) {
+		t.Errorf("default fields = %v", result.Fields)
+	}
+
+	result, err = engine.Execute(context.Background(), Filter[testItem]{Fields: []string{" content", "tags"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Fields, map[string]bool{"content": true, "tags": true}) {
+		t.Errorf("fields = %v", result.Fields)
+	}
+
+	_, err = engine.Execute(context.Background(), Filter[testItem]{Fields: []string{"mood"}})
+	if err == nil || !strings.Contains(err.Error(), `unknown field "mood"`) {
+		t.Errorf("unknown field error = %v", err)
+	}
+}
+
+func TestExecuteRejectsBadFilters(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter[testItem]
+		want   string
+	}{
+		{"negative offset", Filter[testItem]{Offset: -1}, "must not be negative"},
+		{"negative limit", Filter[testItem]{Limit: -2}, "must not be negative"},
+		{"end before start", Filter[testItem]{Start: day("2024-03-16"), End: day("2024-03-15")}, "end date 2024-03-15 is before start date 2024-03-16"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := fixture()
+			_, err := Engine[testItem]{Source: source}.Execute(context.Background(), tt.filter)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+			if len(source.read) > 0 {
+				t.Errorf("read %v before failing", source.read)
+			}
+		})
+	}
+}
+
+func TestExecuteTimeoutReturnsPartialResult(t *testing.T) {
+	source := fixture()
+	source.delay = 20 * time.Millisecond
+	engine := Engine[testItem]{Source: source}
+
+	result, err := engine.Execute(context.Background(), Filter[testItem]{Timeout: 30 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Partial {
+		t.Fatalf("result is not partial: %+v", result)
+	}
+	if result.Scanned != len(result.Items) || result.Scanned == 0 || result.Scanned >= 6 {
+		t.Errorf("scanned %d with %d items, want some but not all", result.Scanned, len(result.Items))
+	}
+	if result.TotalFiles != 6 {
+		t.Errorf("total files = %d, want 6", result.TotalFiles)
+	}
+}
+
+func TestTimeoutRunsOnTheEngineClock(t *testing.T) {
+	// a clock an hour behind puts the deadline in the past, so the budget is spent before the first item
+	behind := func() time.Time { return time.Now().Add(-time.Hour) }
+	engine := Engine[testItem]{Source: fixture(), Now: behind}
+	filter := Filter[testItem]{Timeout: time.Minute}
+
+	result, err := engine.Execute(context.Background(), filter)
+	if err != nil || !result.Partial || result.Scanned != 0 {
+		t.Errorf("execute = %+v, %v, want partial without items", result, err)
+	}
+	if until := time.Until(result.Deadline); until > -50*time.Minute {
+		t.Errorf("deadline is %v away, want it taken from the engine clock", until)
+	}
+
+	progress, err := engine.Each(context.Background(), filter, func(testItem) error { return nil })
+	if err != nil || !progress.Partial || progress.Scanned != 0 {
+		t.Errorf("each = %+v, %v, want partial without items", progress, err)
+	}
+}
+
+func TestExecuteCancelledContextFails(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := Engine[testItem]{Source: fixture()}.Execute(ctx, Filter[testItem]{Timeout: time.Second})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want context.Canceled", err)
+	}
+}
+
+func TestExecuteWrapsSourceErrors(t *testing.T) {
+	_, err := Engine[testItem]{Source: &failingSource{}}.Execute(context.Background(), Filter[testItem]{})
+	if err == nil || err.Error() != "failed to get entries: disk on fire" {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestEach(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter[testItem]
+		want   []string
+	}{
+		{
+			name:   "everything in walk order",
+			filter: Filter[testItem]{Order: OldestFirst},
+			want:   []string{"2024/2024-03-13.md", "2024-03-11.md", "2024-03-16.md", "2024-03-13.md", "2024-03-20.md", "2024-03-17.md"},
+		},
+		{
+			name:   "range, weekdays and tags combined",
+			filter: Filter[testItem]{Start: day("2024-03-12"), Weekdays: []time.Weekday{time.Wednesday, time.Sunday}, Tags: []string{"#work"}},
+			want:   []string{"2024/2024-03-13.md", "2024-03-17.md"},
+		},
+		{
+			name:   "text and match",
+			filter: Filter[testItem]{Text: "THE", Match: func(item testItem) bool { return item.day.Day() > 16 }},
+			want:   []string{"2024-03-17.md"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			_, err := Engine[testItem]{Source: fixture()}.Each(context.Background(), tt.filter, func(item testItem) error {
+				got = append(got, item.path)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEachRejectsPagingAndPassesErrorsThrough(t *testing.T) {
+	engine := Engine[testItem]{Source: fixture()}
+	if _, err := engine.Each(context.Background(), Filter[testItem]{Limit: 1}, func(testItem) error { return nil }); err == nil {
+		t.Error("a paged filter was accepted")
+	}
+	if _, err := engine.Each(context.Background(), Filter[testItem]{Start: day("2024-03-20"), End: day("2024-03-11")}, func(testItem) error { return nil }); err == nil {
+		t.Error("an inverted range was accepted")
+	}
+
+	stop := errors.New("stop")
+	progress, err := engine.Each(context.Background(), Filter[testItem]{}, func(testItem) error { return stop })
+	if err != stop || progress.Scanned != 1 {
+		t.Errorf("each = %+v, %v, want the first item's error as is", progress, err)
+	}
+}
+
+func TestEachTimeoutIsPartial(t *testing.T) {
+	source := fixture()
+	source.delay = 20 * time.Millisecond
+
+	progress, err := Engine[testItem]{Source: source}.Each(context.Background(), Filter[testItem]{Timeout: 30 * time.Millisecond}, func(testItem) error { return nil })
+	if err != nil || !progress.Partial || progress.Scanned == 0 || progress.Scanned >= 6 {
+		t.Errorf("each = %+v, %v, want a partial walk", progress, err)
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// queryVault spans two years with a day that has two files and a thread running through march
+func queryVault(t *testing.T) string {
+	t.Helper()
+	return newTestVault(t, map[string]string{
+		"2023-12-30.md":      "# Saturday\nquiet end of the year\n",
+		"2024-03-10.md":      "---\nmood: 3\n---\n# Sunday\nthread:: house-move\nviewed a flat\n",
+		"2024-03-12.md":      "# Tuesday\nwork all day #work\n",
+		"2024-03-13.md":      "---\nmood: 4\n---\n# Wednesday\nthread:: [[house-move]]\nsigned the lease\n",
+		"2024/2024-03-13.md": "# Wednesday, later\nsecond file for the same day\n",
+		"2024-03-15.md":      "# Friday\nthread:: House-Move\npacked boxes\n",
+		".themis/pins.json":  `{"pins":[{"date":"2023-12-30","pinnedAt":"2024-01-01T00:00:00Z"},{"date":"2024-03-13","note":"lease","pinnedAt":"2024-03-13T00:00:00Z"},{"date":"2024-02-01","pinnedAt":"2024-02-01T00:00:00Z"}]}`,
+	})
+}
+
+func TestEntryQueryHandlers(t *testing.T) {
+	queryVault(t)
+	setTestNow(t, "2024-03-15")
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		run  func() (EntriesOutput, error)
+		want []string
+	}{
+		{
+			name: "recent",
+			run: func() (EntriesOutput, error) {
+				_, output, err := handleGetRecentEntries(ctx, nil, GetRecentEntriesInput{Days: 3})
+				return output, err
+			},
+			want: []string{"2024-03-15", "2024-03-13", "2024-03-13"},
+		},
+		{
+			name: "recent as of a past date",
+			run: func() (EntriesOutput, error) {
+				_, output, err := handleGetRecentEntries(ctx, nil, GetRecentEntriesInput{Days: 3, AsOf: "2024-03-12"})
+				return output, err
+			},
+			want: []string{"2024-03-12", "2024-03-10"},
+		},
+		{
+			name: "by year",
+			run: func() (EntriesOutput, error) {
+				_, output, err := handleGetEntriesByYear(ctx, nil, GetEntriesByYearInput{Year: 2023})
+				return output, err
+			},
+			want: []string{"2023-12-30"},
+		},
+		{
+			name: "recent on some weekdays",
+			run: func() (EntriesOutput, error) {
+				_, output, err := handleGetRecentEntries(ctx, nil, GetRecentEntriesInput{Days: 7, Weekdays: []string{"Sunday", "wednesday"}})
+				return output, err
+			},
+			want: []string{"2024-03-13", "2024-03-13", "2024-03-10"},
+		},
+		{
+			name: "recent containing text",
+			run: func() (EntriesOutput, error) {
+				_, output, err := handleGetRecentEntries(ctx, nil, GetRecentEntriesInput{Days: 7, Text: "LEASE"})
+				return output, err
+			},
+			want: []string{"2024-03-13"},
+		},
+		{
+			name: "by year with a tag",
+			run: func() (EntriesOutput, error) {
+				_, output, err := handleGetEntriesByYear(ctx, nil, GetEntriesByYearInput{Year: 2024, Tags: []string{"#Work"}})
+				return output, err
+			},
+			want: []string{"2024-03-12"},
+		},
+		{
+			name: "by dates keeps the requested order",
+			run: func() (EntriesOutput, error) {
+				_, output, err := handleGetEntriesByDates(ctx, nil, GetEntriesByDatesInput{Dates: []string{"2024-03-12", "2023-12-30", "2024-03-11"}})
+				return output.EntriesOutput, err
+			},
+			want: []string{"2024-03-12", "2023-12-30"},
+		},
+		{
+			name: "neighborhood oldest first",
+			run: func() (EntriesOutput, error) {
+				_, output, err := handleGetEntryNeighborhood(ctx, nil, GetEntryNeighborhoodInput{Date: "2024-03-12", Before: 2, After: 1})
+				return output.EntriesOutput, err
+			},
+			want: []string{"2024-03-10", "2024-03-12", "2024-03-13", "2024-03-13"},
+		},
+		{
+			name: "pinned newest first",
+			run: func() (EntriesOutput, error) {
+				_, output, err := handleGetPinned(ctx, nil, GetPinnedInput{})
+				return output.EntriesOutput, err
+			},
+			want: []string{"2024-03-13", "2024-03-13", "2023-12-30"},
+		},
+		{
+			name: "thread matches case-insensitively, oldest first",
+			run: func() (EntriesOutput, error) {
+				_, output, err := handleGetThread(ctx, nil, GetThreadInput{Thread: "house-move"})
+				return output.EntriesOutput, err
+			},
+			want: []string{"2024-03-10", "2024-03-13", "2024-03-15"},
+		},
+		{
+			name: "entries with a frontmatter field in a range",
+			run: func() (EntriesOutput, error) {
+				_, output, err := handleFilterEntriesWithField(ctx, nil, FilterEntriesWithFieldInput{Field: "mood", Start: "2024-03-11"})
+				return output, err
+			},
+			want: []string{"2024-03-13"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := tt.run()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := entryDates(output.Entries); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("dates = %v, want %v", got, tt.want)
+			}
+			if output.Count != len(tt.want) {
+				t.Errorf("count = %d, want %d", output.Count, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestGetEntriesByDatesReportsMissing(t *testing.T) {
+	queryVault(t)
+	_, output, err := handleGetEntriesByDates(context.Background(), nil, GetEntriesByDatesInput{Dates: []string{"2024-03-11", "2024-03-15", "2024-03-14"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(output.Missing, []string{"2024-03-11", "2024-03-14"}) {
+		t.Errorf("missing = %v", output.Missing)
+	}
+}
+
+func TestGetPinnedReportsMissingEntries(t *testing.T) {
+	queryVault(t)
+	_, output, err := handleGetPinned(context.Background(), nil, GetPinnedInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(output.Pins) != 3 || output.Pins[0].Date != "2024-03-13" || output.Pins[0].Note != "lease" {
+		t.Errorf("pins = %+v, want three newest first", output.Pins)
+	}
+	if !reflect.DeepEqual(output.Missing, []string{"2024-02-01"}) {
+		t.Errorf("missing = %v", output.Missing)
+	}
+}
+
+func TestGetThreadOutline(t *testing.T) {
+	queryVault(t)
+	_, output, err := handleGetThread(context.Background(), nil, GetThreadInput{Thread: "House-Move", Outline: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(output.Entries) != 0 || output.Count != 3 || len(output.Outlines) != 3 {
+		t.Errorf("got %d entries, count %d and %d outlines, want outlines only", len(output.Entries), output.Count, len(output.Outlines))
+	}
+}
+
+func TestGetEntriesByYear(t *testing.T) {
+	queryVault(t)
+	ctx := context.Background()
+
+	_, output, err := handleGetEntriesByYear(ctx, nil, GetEntriesByYearInput{Year: 2024, OmitContent: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"2024-03-15", "2024-03-13", "2024-03-13", "2024-03-12", "2024-03-10"}; !reflect.DeepEqual(entryDates(output.Entries), want) {
+		t.Errorf("2024 = %v, want %v", entryDates(output.Entries), want)
+	}
+	for _, entry := range output.Entries {
+		if entry.Content != "" {
+			t.Errorf("%s has content with omitContent set", entry.Date)
+		}
+	}
+
+	// a year without entries is an empty list, not an error
+	_, output, err = handleGetEntriesByYear(ctx, nil, GetEntriesByYearInput{Year: 2022})
+	if err != nil || output.Entries == nil || output.Count != 0 {
+		t.Errorf("2022 = %+v, %v, want an empty list", output, err)
+	}
+
+	for _, year := range []int{0, -2024, 10000} {
+		if _, _, err := handleGetEntriesByYear(ctx, nil, GetEntriesByYearInput{Year: year}); err == nil {
+			t.Errorf("year %d accepted", year)
+		}
+	}
+	if _, _, err := handleGetEntriesByYear(ctx, nil, GetEntriesByYearInput{Year: 2024, Weekdays: []string{"someday"}}); err == nil {
+		t.Errorf("weekday someday accepted")
+	}
+}
+
+func TestGetEntryByOffset(t *testing.T) {
+	queryVault(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		offset int
+		want   string
+	}{
+		{0, "2024-03-15"},
+		{1, "2024-03-13"},
+		{4, "2023-12-30"},
+	}
+	for _, tt := range tests {
+		_, output, err := handleGetEntryByOffset(ctx, nil, GetEntryByOffsetInput{Offset: tt.offset})
+		if err != nil {
+			t.Fatalf("offset %d: %v", tt.offset, err)
+		}
+		if output.Entry.Date != tt.want || output.Total != 5 {
+			t.Errorf("offset %d = %s of %d, want %s of 5 distinct days", tt.offset, output.Entry.Date, output.Total, tt.want)
+		}
+	}
+
+	if _, _, err := handleGetEntryByOffset(ctx, nil, GetEntryByOffsetInput{Offset: 5}); err == nil || !strings.Contains(err.Error(), "the largest offset is 4") {
+		t.Errorf("offset 5: err = %v, want out of range", err)
+	}
+	if _, _, err := handleGetEntryByOffset(ctx, nil, GetEntryByOffsetInput{Offset: -1}); err == nil {
+		t.Errorf("offset -1 did not fail")
+	}
+}
+
+func TestGetEntryByOffsetReadsOneEntry(t *testing.T) {
+	dir := queryVault(t)
+	ctx := context.Background()
+
+	// the day is picked from file names, with or without the index, and only the files of that day are read.
+	// the percentile field is left out since it needs every entry's length.
+	for _, indexed := range []bool{false, true} {
+		if indexed {
+			useTestIndex(t, dir)
+			vaultIndex.refresh()
+		}
+		before := cache.state()
+		_, output, err := handleGetEntryByOffset(ctx, nil, GetEntryByOffsetInput{Offset: 2, Fields: []string{"date", "content"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		after := cache.state()
+		if reads := after.Hits + after.Misses - before.Hits - before.Misses; output.Entry.Date != "2024-03-12" || reads != 1 {
+			t.Errorf("indexed %v: offset 2 = %s after %d reads, want 2024-03-12 after 1", indexed, output.Entry.Date, reads)
+		}
+	}
+}
+
+func TestGetEntryByOffsetEmptyVault(t *testing.T) {
+	newTestVault(t, nil)
+	if _, _, err := handleGetEntryByOffset(context.Background(), nil, GetEntryByOffsetInput{}); err == nil || err.Error() != "the vault has no entries" {
+		t.Errorf("err = %v, want no entries", err)
+	}
+}
+
+func TestGetEntriesModifiedToday(t *testing.T) {
+	dir := queryVault(t)
+	now := setTestNow(t, "2024-03-15T18:00:00Z")
+
+	// only 2023-12-30 was touched today, everything else last month
+	earlier := now.AddDate(0, -1, 0)
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		return os.Chtimes(path, earlier, earlier)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	today := now.Add(-2 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "2023-12-30.md"), today, today); err != nil {
+		t.Fatal(err)
+	}
+
+	before := cache.state()
+	_, output, err := handleGetEntriesModifiedToday(context.Background(), nil, GetEntriesModifiedTodayInput{Fields: []string{"date"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := entryDates(output.Entries); !reflect.DeepEqual(got, []string{"2023-12-30"}) {
+		t.Errorf("dates = %v", got)
+	}
+
+	// files modified on other days are only statted
+	after := cache.state()
+	if reads := after.Hits + after.Misses - before.Hits - before.Misses; reads != 1 {
+		t.Errorf("read %d files, want only the one modified today", reads)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/curator4/mcp-server-diary/query"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	defaultRelatedTopN = 5
+	maxRelatedTopN     = 50
+)
+
+// relatedWeights scores each kind of overlap, THEMIS_RELATED_WEIGHTS overrides them like "tags=2,links=1,mentions=1.5"
+var relatedWeights = getRelatedWeights()
+
+type GetRelatedEntriesInput struct {
+	Date string `json:"date" jsonschema:"Date of the entry to find related entries for in YYYY-MM-DD format"`
+	TopN int    `json:"topN,omitempty" jsonschema:"Number of related entries to return (default 5, max 50)"`
+
+	IncludePrivate bool `json:"includePrivate,omitempty" jsonschema:"Also suggest entries marked private with private: true in their frontmatter or a #private tag"`
+}
+
+type RelatedEntry struct {
+	Date        string   `json:"date" jsonschema:"Entry date in YYYY-MM-DD format"`
+	Score       float64  `json:"score" jsonschema:"Weighted number of shared tags, links and mentions"`
+	Explanation string   `json:"explanation" jsonschema:"What the entries have in common, e.g. shares #therapy, links to [[Mum]]"`
+	Tags        []string `json:"tags,omitempty" jsonschema:"Shared tags"`
+	Links       []string `json:"links,omitempty" jsonschema:"Shared wikilink targets"`
+	Mentions    []string `json:"mentions,omitempty" jsonschema:"Shared mentioned people"`
+}
+
+type RelatedEntriesOutput struct {
+	Date    string             `json:"date" jsonschema:"Date of the query entry"`
+	Weights map[string]float64 `json:"weights" jsonschema:"Weight of each kind of overlap used for scoring"`
+	Related []RelatedEntry     `json:"related" jsonschema:"Related entries, highest score first"`
+}
+
+// handlers
+func handleGetRelatedEntries(ctx context.Context, req *mcp.CallToolRequest, input GetRelatedEntriesInput) (
+	*mcp.CallToolResult,
+	RelatedEntriesOutput,
+	error,
+) {
+	target, err := getEntryByDate(input.Date)
+	if err != nil {
+		return nil, RelatedEntriesOutput{}, err
+	}
+	topN := input.TopN
+	if topN <= 0 {
+		topN = defaultRelatedTopN
+	}
+	topN = min(topN, maxRelatedTopN)
+
+	want := entryFeatures(target)
+	related := []RelatedEntry{}
+	err = eachEntry(ctx, query.Filter[Entry]{}, func(entry Entry) error {
+		if entry.Date == target.Date || entry.Locked || !input.IncludePrivate && isPrivate(entry) {
+			return nil
+		}
+
+		have := entryFeatures(entry)
+		match := RelatedEntry{
+			Date:     entry.Date,
+			Tags:     want.shared("tags", have),
+			Links:    want.shared("links", have),
+			Mentions: want.shared("mentions", have),
+		}
+		match.Score = relatedWeights["tags"]*float64(len(match.Tags)) +
+			relatedWeights["links"]*float64(len(match.Links)) +
+			relatedWeights["mentions"]*float64(len(match.Mentions))
+		if match.Score <= 0 {
+			return nil
+		}
+		match.Score = math.Round(match.Score*100) / 100
+		match.Explanation = explainOverlap(match)
+		related = append(related, match)
+		return nil
+	})
+	if err != nil {
+		return nil, RelatedEntriesOutput{}, fmt.Errorf("failed to get entries: %w", err)
+	}
+
+	sort.Slice(related, func(i, j int) bool {
+		if related[i].Score != related[j].Score {
+			return related[i].Score > related[j].Score
+		}
+		return related[i].Date > related[j].Date
+	})
+	if len(related) > topN {
+		related = related[:topN]
+	}
+
+	return nil, RelatedEntriesOutput{Date: target.Date, Weights: relatedWeights, Related: related}, nil
+}
+
+// helpers
+func getRelatedWeights() map[string]float64 {
+	weights := map[string]float64{"tags": 1, "links": 1, "mentions": 1}
+	raw := strings.TrimSpace(os.Getenv("THEMIS_RELATED_WEIGHTS"))
+	if raw == "" {
+		return weights
+	}
+
+	for _, pair := range strings.Split(raw, ",") {
+		kind, value, ok := strings.Cut(pair, "=")
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		weight, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if _, known := weights[kind]; !ok || !known || err != nil || weight < 0 {
+			log.Fatalf("invalid THEMIS_RELATED_WEIGHTS entry %q, expected tags, links or mentions=non-negative number", pair)
+		}
+		weights[kind] = weight
+	}
+	return weights
+}
+
+// features maps each kind of overlap to its lowercased keys and the spelling first seen
+type features map[string]map[string]string
+
+func entryFeatures(entry Entry) features {
+	f := features{"tags": {}, "links": {}, "mentions": {}}
+	add := func(kind, value string) {
+		key := strings.ToLower(value)
+		if _, seen := f[kind][key]; key != "" && !seen {
+			f[kind][key] = value
+		}
+	}
+
+	_, body, _ := splitFrontmatter(entry.Content)
+	for _, tag := range extractTags(entry.Frontmatter, body) {
+		add("tags", tag)
+	}
+	for _, match := range wikilinkPattern.FindAllStringSubmatch(body, -1) {
+		// embeds are attachments, not links
+		if match[1] == "" {
+			add("links", strings.TrimSuffix(strings.TrimSpace(match[2]), ".md"))
+		}
+	}
+	for _, mention := range findMentions(entry.Content) {
+		add("mentions", mention.name)
+	}
+	return f
+}
+
+// shared returns the values of kind present in both, in the query's spelling, sorted
+func (f features) shared(kind string, other features) []string {
+	var values []string
+	for key, value := range f[kind] {
+		if _, ok := other[kind][key]; ok {
+			values = append(values, value)
+		}
+	}
+	sort.Strings(values)
+	return values
+}
+
+func explainOverlap(match RelatedEntry) string {
+	var parts []string
+	if len(match.Tags) > 0 {
+		parts = append(parts, "shares #"+strings.Join(match.Tags, ", #"))
+	}
+	if len(match.Links) > 0 {
+		parts = append(parts, "links to [["+strings.Join(match.Links, "]], [[")+"]]")
+	}
+	if len(match.Mentions) > 0 {
+		parts = append(parts, "mentions @"+strings.Join(match.Mentions, ", @"))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestGetRelatedEntries(t *testing.T) {
+	newTestVault(t, map[string]string{
+		"2024-03-15.md": "#therapy went well, talked about [[Mum]] with @Sam\n",
+		"2024-03-10.md": "#therapy again, called [[mum]]\n",
+		"2024-03-11.md": "lunch with @sam\n",
+		"2024-03-12.md": "---\nprivate: true\n---\n#therapy and [[Mum]] and @Sam\n",
+		"2024-03-13.md": "#therapy #private\n",
+		"2024-03-14.md": "nothing in common\n",
+	})
+	ctx := context.Background()
+
+	_, output, err := handleGetRelatedEntries(ctx, nil, GetRelatedEntriesInput{Date: "2024-03-15"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []RelatedEntry{
+		{Date: "2024-03-10", Score: 2, Explanation: "shares #therapy, links to [[Mum]]", Tags: []string{"therapy"}, Links: []string{"Mum"}},
+		{Date: "2024-03-11", Score: 1, Explanation: "mentions @Sam", Mentions: []string{"Sam"}},
+	}
+	if !reflect.DeepEqual(output.Related, want) {
+		t.Errorf("related = %+v, want %+v", output.Related, want)
+	}
+
+	_, output, err = handleGetRelatedEntries(ctx, nil, GetRelatedEntriesInput{Date: "2024-03-15", IncludePrivate: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dates []string
+	for _, related := range output.Related {
+		dates = append(dates, related.Date)
+	}
+	if !reflect.DeepEqual(dates, []string{"2024-03-12", "2024-03-10", "2024-03-13", "2024-03-11"}) {
+		t.Errorf("related with private = %v", dates)
+	}
+}
+
+func TestIsPrivate(t *testing.T) {
+	tests := []struct {
+		content string
+		want    bool
+	}{
+		{"---\nprivate: true\n---\nbody\n", true},
+		{"---\nprivate: \"true\"\n---\nbody\n", true},
+		{"---\nprivate: false\n---\nbody\n", false},
+		{"---\ntags: [Private]\n---\nbody\n", true},
+		{"a #private thought\n", true},
+		{"privately held\n", false},
+	}
+	for _, tt := range tests {
+		entry := Entry{Content: tt.content}
+		entry.Frontmatter, _, _ = parseFrontmatter(tt.content)
+		if got := isPrivate(entry); got != tt.want {
+			t.Errorf("isPrivate(%q) = %v, want %v", tt.content, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// applyRelativeLabels sets each entry's relativeLabel from its date against today in the configured timezone
+func applyRelativeLabels(entries []Entry) {
+	t := now().In(location)
+	today := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	for i := range entries {
+		if date, err := time.Parse("2006-01-02", entries[i].Date); err == nil {
+			entries[i].RelativeLabel = relativeLabel(date, today)
+		}
+	}
+}
+
+// relativeLabel describes date as seen from today, e.g. "yesterday", "3 days ago", "last week" or "in 2 months".
+// both are midnight utc so the difference is a whole number of calendar days.
+func relativeLabel(date, today time.Time) string {
+	days := int(today.Sub(date).Hours() / 24)
+	future := days < 0
+	if future {
+		days = -days
+	}
+
+	var n int
+	var unit string
+	switch {
+	case days == 0:
+		return "today"
+	case days == 1:
+		if future {
+			return "tomorrow"
+		}
+		return "yesterday"
+	case days < 7:
+		n, unit = days, "day"
+	case days < 30:
+		n, unit = days/7, "week"
+	case days < 365:
+		n, unit = days/30, "month"
+	default:
+		n, unit = days/365, "year"
+	}
+
+	// a single unit reads as last or next, days never get here with one
+	if n == 1 {
+		if future {
+			return "next " + unit
+		}
+		return "last " + unit
+	}
+	if future {
+		return fmt.Sprintf("in %d %ss", n, unit)
+	}
+	return fmt.Sprintf("%d %ss ago", n, unit)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRelativeLabel(t *testing.T) {
+	today := time.Date(2024, time.March, 15, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		days int
+		want string
+	}{
+		{0, "today"},
+		{-1, "yesterday"},
+		{1, "tomorrow"},
+		{-2, "2 days ago"},
+		{2, "in 2 days"},
+		{-6, "6 days ago"},
+		{-7, "last week"},
+		{-13, "last week"},
+		{-14, "2 weeks ago"},
+		{7, "next week"},
+		{20, "in 2 weeks"},
+		{-29, "4 weeks ago"},
+		{-30, "last month"},
+		{60, "in 2 months"},
+		{-364, "12 months ago"},
+		{-365, "last year"},
+		{400, "next year"},
+		{-800, "2 years ago"},
+	}
+	for _, tt := range tests {
+		if got := relativeLabel(today.AddDate(0, 0, tt.days), today); got != tt.want {
+			t.Errorf("%d days: got %q, want %q", tt.days, got, tt.want)
+		}
+	}
+}
+
+func TestIncludeRelativeLabels(t *testing.T) {
+	newTestVault(t, map[string]string{
+		"2024-03-09.md": "# Saturday\n",
+		"2024-03-15.md": "# Friday\n",
+		"2024-03-16.md": "# Saturday\n",
+	})
+	oldLocation := location
+	location = time.FixedZone("UTC+2", 2*60*60)
+	defer func() { location = oldLocation }()
+	// already the 16th in the configured timezone
+	setTestNow(t, "2024-03-15T23:30:00Z")
+	ctx := context.Background()
+
+	_, output, err := handleGetEntriesByYear(ctx, nil, GetEntriesByYearInput{Year: 2024, IncludeRelativeLabels: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"2024-03-16": "today", "2024-03-15": "yesterday", "2024-03-09": "last week"}
+	for _, entry := range output.Entries {
+		if entry.RelativeLabel != want[entry.Date] {
+			t.Errorf("%s: label %q, want %q", entry.Date, entry.RelativeLabel, want[entry.Date])
+		}
+	}
+
+	_, output, err = handleGetEntriesByYear(ctx, nil, GetEntriesByYearInput{Year: 2024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range output.Entries {
+		if entry.RelativeLabel != "" {
+			t.Errorf("%s labelled %q without includeRelativeLabels", entry.Date, entry.RelativeLabel)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type ReplaceTextInput struct {
+	Find               string `json:"find" jsonschema:"Text to find, or a Go RE2 regular expression when regex is set"`
+	Replace            string `json:"replace" jsonschema:"Replacement text, with $1 or ${name} for capture groups when regex is set"`
+	Start              string `json:"start,omitempty" jsonschema:"First date to include in YYYY-MM-DD format"`
+	End                string `json:"end,omitempty" jsonschema:"Last date to include in YYYY-MM-DD format"`
+	Regex              bool   `json:"regex,omitempty" jsonschema:"Treat find as a regular expression instead of literal text"`
+	IncludeFrontmatter bool   `json:"includeFrontmatter,omitempty" jsonschema:"Also replace inside the frontmatter block, which is left alone by default"`
+	DryRun             bool   `json:"dryRun,omitempty" jsonschema:"Report what would change without writing any files"`
+}
+
+type Replacement struct {
+	Date  string `json:"date" jsonschema:"Entry date in YYYY-MM-DD format"`
+	Count int    `json:"count" jsonschema:"Number of replacements in the entry"`
+}
+
+type ReplaceTextOutput struct {
+	Replacements int           `json:"replacements" jsonschema:"Total number of replacements made (or that would be made)"`
+	Affected     []Replacement `json:"affected" jsonschema:"Entries that changed, oldest first"`
+	Locked       []string      `json:"locked,omitempty" jsonschema:"Dates of encrypted entries that could not be decrypted and were left alone"`
+	DryRun       bool          `json:"dryRun" jsonschema:"Whether this was a dry run"`
+}
+
+// handlers
+func handleReplaceText(ctx context.Context, req *mcp.CallToolRequest, input ReplaceTextInput) (
+	*mcp.CallToolResult,
+	ReplaceTextOutput,
+	error,
+) {
+	if input.Find == "" {
+		return nil, ReplaceTextOutput{}, fmt.Errorf("find must not be empty")
+	}
+	pattern := regexp.MustCompile(regexp.QuoteMeta(input.Find))
+	if input.Regex {
+		var err error
+		if pattern, err = regexp.Compile(input.Find); err != nil {
+			return nil, ReplaceTextOutput{}, fmt.Errorf("invalid pattern %q: %w", input.Find, err)
+		}
+	}
+	replace := func(text string) string {
+		if input.Regex {
+			return pattern.ReplaceAllString(text, input.Replace)
+		}
+		return pattern.ReplaceAllLiteralString(text, input.Replace)
+	}
+
+	filter, err := rangeFilter(input.Start, input.End)
+	if err != nil {
+		return nil, ReplaceTextOutput{}, err
+	}
+	entries, err := collectEntries(ctx, filter)
+	if err != nil {
+		return nil, ReplaceTextOutput{}, fmt.Errorf("failed to get entries: %w", err)
+	}
+
+	output := ReplaceTextOutput{Affected: []Replacement{}, DryRun: input.DryRun}
+	for _, entry := range entries {
+		if entry.Locked {
+			output.Locked = append(output.Locked, entry.Date)
+			continue
+		}
+
+		// the frontmatter block, delimiters included, is kept verbatim unless asked otherwise
+		head, body := "", entry.Content
+		if _, rest, ok := splitFrontmatter(entry.Content); ok && !input.IncludeFrontmatter {
+			head, body = entry.Content[:len(entry.Content)-len(rest)], rest
+		}
+
+		count := len(pattern.FindAllStringIndex(body, -1))
+		if count == 0 {
+			continue
+		}
+		updated := head + replace(body)
+		if updated == entry.Content {
+			continue
+		}
+
+		if !input.DryRun {
+			original, err := readEntryPlaintext(entry)
+			if err != nil {
+				return nil, ReplaceTextOutput{}, fmt.Errorf("failed to read %s: %w", entry.FilePath, err)
+			}
+			data, err := encodeEntryFile(entry.FilePath, string(encodeLike(original, updated)))
+			if err != nil {
+				return nil, ReplaceTextOutput{}, fmt.Errorf("failed to encrypt %s: %w", entry.Date, err)
+			}
+			if err := writeFileAtomic(entry.FilePath, data); err != nil {
+				return nil, ReplaceTextOutput{}, fmt.Errorf("failed to write %s: %w", entry.FilePath, err)
+			}
+		}
+		output.Replacements += count
+		output.Affected = append(output.Affected, Replacement{Date: entry.Date, Count: count})
+	}
+
+	return nil, output, nil
+}
+
+// readEntryPlaintext is the entry file as stored, decrypted but not yet decoded, so writes can keep its encoding
+func readEntryPlaintext(entry Entry) ([]byte, error) {
+	data, err := os.ReadFile(entry.FilePath)
+	if err != nil || !entry.Encrypted {
+		return data, err
+	}
+	return decryptEntry(data)
+}
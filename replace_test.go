@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReplaceTextLiteral(t *testing.T) {
+	dir := newTestVault(t, map[string]string{
+		"2024-03-15.md": "---\ntitle: a.b\n---\nmet a.b and a.b\n",
+		"2024-03-16.md": "axb stays\n",
+	})
+
+	_, output, err := handleReplaceText(context.Background(), nil, ReplaceTextInput{Find: "a.b", Replace: "$1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Replacements != 2 || !reflect.DeepEqual(output.Affected, []Replacement{{Date: "2024-03-15", Count: 2}}) {
+		t.Errorf("output = %+v, want 2 replacements in 2024-03-15", output)
+	}
+	// literal text is not a pattern and $1 is not expanded, the frontmatter is left alone
+	if got, want := readTestFile(t, filepath.Join(dir, "2024-03-15.md")), "---\ntitle: a.b\n---\nmet $1 and $1\n"; got != want {
+		t.Errorf("entry = %q, want %q", got, want)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "2024-03-16.md")); got != "axb stays\n" {
+		t.Errorf("unmatched entry changed to %q", got)
+	}
+}
+
+func TestReplaceTextDryRun(t *testing.T) {
+	dir := newTestVault(t, map[string]string{"2024-03-15.md": "old old\n"})
+
+	_, output, err := handleReplaceText(context.Background(), nil, ReplaceTextInput{Find: "old", Replace: "new", DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !output.DryRun || output.Replacements != 2 || len(output.Affected) != 1 {
+		t.Errorf("output = %+v, want a dry run reporting 2 replacements", output)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "2024-03-15.md")); got != "old old\n" {
+		t.Errorf("dry run changed the entry to %q", got)
+	}
+}
+
+func TestReplaceTextRegexCaptureGroups(t *testing.T) {
+	dir := newTestVault(t, map[string]string{"2024-03-15.md": "ran 5km, then 10km\n"})
+
+	_, output, err := handleReplaceText(context.Background(), nil, ReplaceTextInput{Find: `(\d+)km`, Replace: "${1} km", Regex: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Replacements != 2 {
+		t.Errorf("replacements = %d, want 2", output.Replacements)
+	}
+	if got, want := readTestFile(t, filepath.Join(dir, "2024-03-15.md")), "ran 5 km, then 10 km\n"; got != want {
+		t.Errorf("entry = %q, want %q", got, want)
+	}
+}
+
+func TestReplaceTextKeepsEncoding(t *testing.T) {
+	tests := []struct {
+		name           string
+		find, replace  string
+		original, want []byte
+	}{
+		{
+			name:     "utf-8 bom",
+			find:     "old",
+			replace:  "new",
+			original: append([]byte{0xEF, 0xBB, 0xBF}, "Größe old\n"...),
+			want:     append([]byte{0xEF, 0xBB, 0xBF}, "Größe new\n"...),
+		},
+		{
+			name:     "utf-16le",
+			find:     "öld",
+			replace:  "öw",
+			original: []byte{0xFF, 0xFE, 0xF6, 0, 'l', 0, 'd', 0, '\n', 0},
+			want:     []byte{0xFF, 0xFE, 0xF6, 0, 'w', 0, '\n', 0},
+		},
+		{
+			name:     "utf-16be",
+			find:     "öld",
+			replace:  "öw",
+			original: []byte{0xFE, 0xFF, 0, 0xF6, 0, 'l', 0, 'd', 0, '\n'},
+			want:     []byte{0xFE, 0xFF, 0, 0xF6, 0, 'w', 0, '\n'},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := newTestVault(t, map[string]string{"2024-03-15.md": string(tt.original)})
+
+			_, output, err := handleReplaceText(context.Background(), nil, ReplaceTextInput{Find: tt.find, Replace: tt.replace})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if output.Replacements != 1 {
+				t.Fatalf("replacements = %d, want 1", output.Replacements)
+			}
+			if got := readTestFile(t, filepath.Join(dir, "2024-03-15.md")); got != string(tt.want) {
+				t.Errorf("file = % x, want % x", got, tt.want)
+			}
+		})
+	}
+}
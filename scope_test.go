@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// scopedVault keeps entries in three folders with THEMIS_SCOPE set to daily
+func scopedVault(t *testing.T) {
+	t.Helper()
+	dir := newTestVault(t, map[string]string{
+		"daily/2024-03-14.md":   "# Thursday\n",
+		"daily/2024-03-15.md":   "# Friday\n",
+		"ideas/2024-03-15.md":   "# garden shed\n",
+		"reviews/2024-03-31.md": "# march\n",
+		"notes.md":              "not an entry\n",
+	})
+	entryRoot = filepath.Join(dir, "daily")
+}
+
+func TestScopeInput(t *testing.T) {
+	scopedVault(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		scope string
+		want  []string
+	}{
+		{"", []string{"2024-03-15", "2024-03-14"}},
+		{"ideas", []string{"2024-03-15"}},
+		{"reviews/", []string{"2024-03-31"}},
+		{".", []string{"2024-03-31", "2024-03-15", "2024-03-15", "2024-03-14"}},
+	}
+	for _, tt := range tests {
+		_, output, err := handleGetEntriesByYear(ctx, nil, GetEntriesByYearInput{Year: 2024, Scope: tt.scope})
+		if err != nil {
+			t.Fatalf("scope %q: %v", tt.scope, err)
+		}
+		if got := entryDates(output.Entries); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("scope %q: dates = %v, want %v", tt.scope, got, tt.want)
+		}
+	}
+
+	// the paged path lists days from file names instead of the index, within the scope too
+	_, offset, err := handleGetEntryByOffset(ctx, nil, GetEntryByOffsetInput{Offset: 0, Scope: "reviews"})
+	if err != nil || offset.Entry.Date != "2024-03-31" {
+		t.Errorf("offset in reviews = %q, %v", offset.Entry.Date, err)
+	}
+	_, neighborhood, err := handleGetEntryNeighborhood(ctx, nil, GetEntryNeighborhoodInput{Date: "2024-03-15", Before: 1, Scope: "ideas"})
+	if err != nil || !reflect.DeepEqual(entryDates(neighborhood.Entries), []string{"2024-03-15"}) {
+		t.Errorf("neighborhood in ideas = %v, %v", entryDates(neighborhood.Entries), err)
+	}
+}
+
+func TestScopeInputModifiedToday(t *testing.T) {
+	scopedVault(t)
+	setTestNow(t, "2024-03-15")
+	for _, path := range []string{"daily/2024-03-15.md", "ideas/2024-03-15.md"} {
+		touch(t, filepath.Join(themisPath, path), now())
+	}
+
+	_, output, err := handleGetEntriesModifiedToday(context.Background(), nil, GetEntriesModifiedTodayInput{Scope: "ideas"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(output.Entries) != 1 || !strings.Contains(output.Entries[0].Content, "garden shed") {
+		t.Errorf("entries = %+v, want only the ideas entry", output.Entries)
+	}
+}
+
+func TestScopeInputInvalid(t *testing.T) {
+	scopedVault(t)
+
+	for _, scope := range []string{"../elsewhere", "missing", "notes.md"} {
+		_, _, err := handleGetEntriesByYear(context.Background(), nil, GetEntriesByYearInput{Year: 2024, Scope: scope})
+		if err == nil || !strings.HasPrefix(err.Error(), "invalid scope") {
+			t.Errorf("scope %q: err = %v, want invalid scope", scope, err)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/curator4/mcp-server-diary/query"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type SearchFrontmatterInput struct {
+	Field     string `json:"field" jsonschema:"Frontmatter key to match, e.g. location"`
+	Pattern   string `json:"pattern" jsonschema:"Regular expression (Go RE2 syntax) the field value must match"`
+	Start     string `json:"start,omitempty" jsonschema:"First date to include in YYYY-MM-DD format"`
+	End       string `json:"end,omitempty" jsonschema:"Last date to include in YYYY-MM-DD format"`
+	MaxTokens int    `json:"maxTokens,omitempty" jsonschema:"Drop the oldest entries once the estimated token total would exceed this budget"`
+
+	PreviewChars     int  `json:"previewChars,omitempty" jsonschema:"Add a plaintext preview of this many characters to each entry"`
+	OmitContent      bool `json:"omitContent,omitempty" jsonschema:"Leave out the full content, e.g. together with previewChars for list views"`
+	StripFrontmatter bool `json:"stripFrontmatter,omitempty" jsonschema:"Remove the --- frontmatter block from content, the parsed frontmatter field is still returned"`
+
+	MaxContentLength int    `json:"maxContentLength,omitempty" jsonschema:"Cut each entry's content to this many characters, frontmatter not counted"`
+	TruncateStrategy string `json:"truncateStrategy,omitempty" jsonschema:"How maxContentLength cuts: head (default) keeps the start, tail the end, structured every heading plus the first sentence of each section"`
+
+	IncludeRelativeLabels bool `json:"includeRelativeLabels,omitempty" jsonschema:"Label each entry relative to today, e.g. yesterday, 3 days ago or last week"`
+	ContentBlocks         bool `json:"contentBlocks,omitempty" jsonschema:"Also return each entry as a formatted text block under a date heading for inline display, the structured result is unchanged"`
+
+	Fields          []string `json:"fields,omitempty" jsonschema:"Entry fields to return, e.g. [\"date\", \"content\"], defaults to the server's configured set"`
+	ContentEncoding string   `json:"contentEncoding,omitempty" jsonschema:"plain (default) or base64 to base64 encode content for byte-faithful transport"`
+	TimeoutMs       int      `json:"timeoutMs,omitempty" jsonschema:"Time budget for this call in milliseconds, partial results are returned when it runs out (default 10000)"`
+	Scope           string   `json:"scope,omitempty" jsonschema:"Vault subfolder to read entries from, e.g. daily, in place of the server's THEMIS_SCOPE, . for the whole vault"`
+}
+
+type FilterEntriesWithFieldInput struct {
+	Field     string `json:"field" jsonschema:"Frontmatter key the entry must have, with any value, e.g. mood"`
+	Start     string `json:"start,omitempty" jsonschema:"First date to include in YYYY-MM-DD format"`
+	End       string `json:"end,omitempty" jsonschema:"Last date to include in YYYY-MM-DD format"`
+	MaxTokens int    `json:"maxTokens,omitempty" jsonschema:"Drop the oldest entries once the estimated token total would exceed this budget"`
+
+	PreviewChars     int  `json:"previewChars,omitempty" jsonschema:"Add a plaintext preview of this many characters to each entry"`
+	OmitContent      bool `json:"omitContent,omitempty" jsonschema:"Leave out the full content, e.g. together with previewChars for list views"`
+	StripFrontmatter bool `json:"stripFrontmatter,omitempty" jsonschema:"Remove the --- frontmatter block from content, the parsed frontmatter field is still returned"`
+
+	MaxContentLength int    `json:"maxContentLength,omitempty" jsonschema:"Cut each entry's content to this many characters, frontmatter not counted"`
+	TruncateStrategy string `json:"truncateStrategy,omitempty" jsonschema:"How maxContentLength cuts: head (default) keeps the start, tail the end, structured every heading plus the first sentence of each section"`
+
+	IncludeRelativeLabels bool `json:"includeRelativeLabels,omitempty" jsonschema:"Label each entry relative to today, e.g. yesterday, 3 days ago or last week"`
+	ContentBlocks         bool `json:"contentBlocks,omitempty" jsonschema:"Also return each entry as a formatted text block under a date heading for inline display, the structured result is unchanged"`
+
+	Fields          []string `json:"fields,omitempty" jsonschema:"Entry fields to return, e.g. [\"date\", \"content\"], defaults to the server's configured set"`
+	ContentEncoding string   `json:"contentEncoding,omitempty" jsonschema:"plain (default) or base64 to base64 encode content for byte-faithful transport"`
+	TimeoutMs       int      `json:"timeoutMs,omitempty" jsonschema:"Time budget for this call in milliseconds, partial results are returned when it runs out (default 10000)"`
+	Scope           string   `json:"scope,omitempty" jsonschema:"Vault subfolder to read entries from, e.g. daily, in place of the server's THEMIS_SCOPE, . for the whole vault"`
+}
+
+// handlers
+func handleSearchFrontmatter(ctx context.Context, req *mcp.CallToolRequest, input SearchFrontmatterInput) (
+	*mcp.CallToolResult,
+	EntriesOutput,
+	error,
+) {
+	if input.Field == "" {
+		return nil, EntriesOutput{}, fmt.Errorf("field is required")
+	}
+	pattern, err := regexp.Compile(input.Pattern)
+	if err != nil {
+		return nil, EntriesOutput{}, fmt.Errorf("invalid pattern %q: %w", input.Pattern, err)
+	}
+	start, end, err := parseDateRange(input.Start, input.End)
+	if err != nil {
+		return nil, EntriesOutput{}, err
+	}
+
+	match := func(entry Entry) bool {
+		// only string values are matched, lists and numbers are skipped
+		value, ok := entry.Frontmatter[input.Field].(string)
+		return ok && pattern.MatchString(value)
+	}
+	output, err := runEntryQuery(ctx, entryQuery{
+		filter: query.Filter[Entry]{Start: start, End: end, Fields: input.Fields, Match: match},
+		shape: contentShape{
+			stripFrontmatter: input.StripFrontmatter,
+			previewChars:     input.PreviewChars,
+			omitContent:      input.OmitContent,
+			maxContentLength: input.MaxContentLength,
+			truncateStrategy: input.TruncateStrategy,
+			encoding:         input.ContentEncoding,
+		},
+		relativeLabels: input.IncludeRelativeLabels,
+		maxTokens:      input.MaxTokens,
+		timeoutMs:      input.TimeoutMs,
+		scope:          input.Scope,
+	})
+	if err != nil {
+		return nil, EntriesOutput{}, err
+	}
+	return entryBlocks(input.ContentBlocks, output.Entries), output, nil
+}
+
+func handleFilterEntriesWithField(ctx context.Context, req *mcp.CallToolRequest, input FilterEntriesWithFieldInput) (
+	*mcp.CallToolResult,
+	EntriesOutput,
+	error,
+) {
+	if input.Field == "" {
+		return nil, EntriesOutput{}, fmt.Errorf("field is required")
+	}
+	start, end, err := parseDateRange(input.Start, input.End)
+	if err != nil {
+		return nil, EntriesOutput{}, err
+	}
+
+	match := func(entry Entry) bool {
+		// an empty value such as "mood:" still counts as recorded
+		_, ok := entry.Frontmatter[input.Field]
+		return ok
+	}
+	output, err := runEntryQuery(ctx, entryQuery{
+		filter: query.Filter[Entry]{Start: start, End: end, Fields: input.Fields, Match: match},
+		shape: contentShape{
+			stripFrontmatter: input.StripFrontmatter,
+			previewChars:     input.PreviewChars,
+			omitContent:      input.OmitContent,
+			maxContentLength: input.MaxContentLength,
+			truncateStrategy: input.TruncateStrategy,
+			encoding:         input.ContentEncoding,
+		},
+		relativeLabels: input.IncludeRelativeLabels,
+		maxTokens:      input.MaxTokens,
+		timeoutMs:      input.TimeoutMs,
+		scope:          input.Scope,
+	})
+	if err != nil {
+		return nil, EntriesOutput{}, err
+	}
+	return entryBlocks(input.ContentBlocks, output.Entries), output, nil
+}
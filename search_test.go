@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// frontmatterVault records a place as a string, a list or a number, or leaves it out
+func frontmatterVault(t *testing.T) {
+	t.Helper()
+	newTestVault(t, map[string]string{
+		"2024-03-10.md": "---\nplace: Kyoto\nmood: 3\n---\n",
+		"2024-03-11.md": "---\nplace: kyoto station\n---\n",
+		"2024-03-12.md": "---\nplace: [Kyoto, Osaka]\n---\n",
+		"2024-03-13.md": "---\nplace: 42\nmood:\n---\n",
+		"2024-03-14.md": "---\nplace: Tokyo\n---\nKyoto in the body only\n",
+		"2024-03-15.md": "no frontmatter, place: Kyoto\n",
+	})
+}
+
+func TestSearchFrontmatter(t *testing.T) {
+	frontmatterVault(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		input SearchFrontmatterInput
+		want  []string
+	}{
+		{SearchFrontmatterInput{Field: "place", Pattern: "Kyoto"}, []string{"2024-03-10"}},
+		{SearchFrontmatterInput{Field: "place", Pattern: "(?i)^kyoto"}, []string{"2024-03-11", "2024-03-10"}},
+		{SearchFrontmatterInput{Field: "place", Pattern: "(?i)kyoto", End: "2024-03-10"}, []string{"2024-03-10"}},
+		{SearchFrontmatterInput{Field: "place", Pattern: ""}, []string{"2024-03-14", "2024-03-11", "2024-03-10"}},
+		{SearchFrontmatterInput{Field: "weather", Pattern: ".*"}, []string{}},
+	}
+	for _, tt := range tests {
+		_, output, err := handleSearchFrontmatter(ctx, nil, tt.input)
+		if err != nil {
+			t.Fatalf("%s %q: %v", tt.input.Field, tt.input.Pattern, err)
+		}
+		if got := entryDates(output.Entries); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s %q: dates = %v, want %v", tt.input.Field, tt.input.Pattern, got, tt.want)
+		}
+	}
+
+	for _, bad := range []SearchFrontmatterInput{{Pattern: "Kyoto"}, {Field: "place", Pattern: "("}, {Field: "place", Start: "March"}} {
+		if _, _, err := handleSearchFrontmatter(ctx, nil, bad); err == nil {
+			t.Errorf("%+v: want an error", bad)
+		}
+	}
+}
+
+func TestFilterEntriesWithField(t *testing.T) {
+	frontmatterVault(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		input FilterEntriesWithFieldInput
+		want  []string
+	}{
+		// an empty value still counts as recorded
+		{FilterEntriesWithFieldInput{Field: "mood"}, []string{"2024-03-13", "2024-03-10"}},
+		// a key: value line in the body is not frontmatter
+		{FilterEntriesWithFieldInput{Field: "place"}, []string{"2024-03-14", "2024-03-13", "2024-03-12", "2024-03-11", "2024-03-10"}},
+		{FilterEntriesWithFieldInput{Field: "place", Start: "2024-03-11", End: "2024-03-12"}, []string{"2024-03-12", "2024-03-11"}},
+		{FilterEntriesWithFieldInput{Field: "weather"}, []string{}},
+	}
+	for _, tt := range tests {
+		_, output, err := handleFilterEntriesWithField(ctx, nil, tt.input)
+		if err != nil {
+			t.Fatalf("%+v: %v", tt.input, err)
+		}
+		if got := entryDates(output.Entries); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%+v: dates = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+
+	for _, input := range []FilterEntriesWithFieldInput{{}, {Field: "mood", Start: "yesterday"}} {
+		if _, _, err := handleFilterEntriesWithField(ctx, nil, input); err == nil {
+			t.Errorf("%+v accepted", input)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/curator4/mcp-server-diary/query"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	defaultSimilarTopN = 5
+	maxSimilarTopN     = 50
+)
+
+type FindSimilarInput struct {
+	Date string `json:"date" jsonschema:"Date of the entry to compare against in YYYY-MM-DD format"`
+	TopN int    `json:"topN,omitempty" jsonschema:"Number of similar entries to return (default 5, max 50)"`
+}
+
+type SimilarEntry struct {
+	Date  string  `json:"date" jsonschema:"Entry date in YYYY-MM-DD format"`
+	Score float64 `json:"score" jsonschema:"Cosine similarity between 0 and 1"`
+}
+
+type FindSimilarOutput struct {
+	Date    string         `json:"date" jsonschema:"Date of the query entry"`
+	Similar []SimilarEntry `json:"similar" jsonschema:"Most similar entries, most similar first"`
+}
+
+// handlers
+func handleFindSimilar(ctx context.Context, req *mcp.CallToolRequest, input FindSimilarInput) (
+	*mcp.CallToolResult,
+	FindSimilarOutput,
+	error,
+) {
+	target, err := getEntryByDate(input.Date)
+	if err != nil {
+		return nil, FindSimilarOutput{}, err
+	}
+	topN := input.TopN
+	if topN <= 0 {
+		topN = defaultSimilarTopN
+	}
+	topN = min(topN, maxSimilarTopN)
+
+	type document struct {
+		date  string
+		path  string
+		terms map[string]float64
+	}
+
+	var docs []document
+	docFreq := map[string]int{}
+	err = eachEntry(ctx, query.Filter[Entry]{}, func(entry Entry) error {
+		terms := termFrequencies(entry.Content)
+		for term := range terms {
+			docFreq[term]++
+		}
+		docs = append(docs, document{date: entry.Date, path: entry.FilePath, terms: terms})
+		return nil
+	})
+	if err != nil {
+		return nil, FindSimilarOutput{}, fmt.Errorf("failed to get entries: %w", err)
+	}
+
+	// smoothed idf so terms present in every entry still carry a little weight
+	idf := func(term string) float64 {
+		return math.Log(float64(1+len(docs))/float64(1+docFreq[term])) + 1
+	}
+	vectorize := func(terms map[string]float64) (map[string]float64, float64) {
+		vector := make(map[string]float64, len(terms))
+		norm := 0.0
+		for term, tf := range terms {
+			weight := tf * idf(term)
+			vector[term] = weight
+			norm += weight * weight
+		}
+		return vector, math.Sqrt(norm)
+	}
+
+	targetVector, targetNorm := vectorize(termFrequencies(target.Content))
+
+	output := FindSimilarOutput{Date: target.Date, Similar: []SimilarEntry{}}
+	for _, doc := range docs {
+		if doc.path == target.FilePath {
+			continue
+		}
+
+		vector, norm := vectorize(doc.terms)
+		if norm == 0 || targetNorm == 0 {
+			continue
+		}
+		dot := 0.0
+		for term, weight := range targetVector {
+			dot += weight * vector[term]
+		}
+		if dot == 0 {
+			continue
+		}
+
+		output.Similar = append(output.Similar, SimilarEntry{Date: doc.date, Score: math.Round(dot/(norm*targetNorm)*1e4) / 1e4})
+	}
+
+	sort.SliceStable(output.Similar, func(i, j int) bool { return output.Similar[i].Score > output.Similar[j].Score })
+	if len(output.Similar) > topN {
+		output.Similar = output.Similar[:topN]
+	}
+
+	return nil, output, nil
+}
+
+// helpers
+
+// tokenize lowercases the entry body and splits it into words of letters and digits
+func tokenize(content string) []string {
+	_, body, _ := splitFrontmatter(content)
+	return strings.FieldsFunc(strings.ToLower(body), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+}
+
+// termFrequencies returns each term's share of the entry's words
+func termFrequencies(content string) map[string]float64 {
+	tokens := tokenize(content)
+	terms := map[string]float64{}
+	for _, token := range tokens {
+		token = strings.Trim(token, "'")
+		if token != "" {
+			terms[token]++
+		}
+	}
+	for term := range terms {
+		terms[term] /= float64(len(tokens))
+	}
+	return terms
+}
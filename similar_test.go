@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFindSimilar(t *testing.T) {
+	newTestVault(t, map[string]string{
+		"2024-03-15.md": "long run along the river, legs tired after the run\n",
+		"2024-03-10.md": "another long run by the river\n",
+		"2024-03-11.md": "short run at the gym\n",
+		"2024-03-12.md": "baked bread and read a book\n",
+		"2024-03-13.md": "---\ntitle: run river\n---\nquiet evening\n",
+	})
+
+	_, output, err := handleFindSimilar(context.Background(), nil, FindSimilarInput{Date: "2024-03-15"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Date != "2024-03-15" {
+		t.Errorf("date = %q", output.Date)
+	}
+
+	var dates []string
+	for _, similar := range output.Similar {
+		dates = append(dates, similar.Date)
+		if similar.Date == "2024-03-15" {
+			t.Errorf("the query entry is listed as similar to itself")
+		}
+		if similar.Score <= 0 || similar.Score > 1 {
+			t.Errorf("%s score = %v, want between 0 and 1", similar.Date, similar.Score)
+		}
+	}
+	// more shared vocabulary ranks higher, words only in the frontmatter don't count
+	if len(dates) < 2 || dates[0] != "2024-03-10" || dates[1] != "2024-03-11" {
+		t.Errorf("similar = %+v, want 2024-03-10 then 2024-03-11 first", output.Similar)
+	}
+	for _, date := range dates {
+		if date == "2024-03-12" || date == "2024-03-13" {
+			t.Errorf("%s shares no words with the query but is listed: %+v", date, output.Similar)
+		}
+	}
+
+	_, output, err = handleFindSimilar(context.Background(), nil, FindSimilarInput{Date: "2024-03-15", TopN: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(output.Similar) != 1 || output.Similar[0].Date != "2024-03-10" {
+		t.Errorf("top 1 = %+v, want only 2024-03-10", output.Similar)
+	}
+}
+
+func TestFindSimilarUnknownDate(t *testing.T) {
+	newTestVault(t, map[string]string{"2024-03-15.md": "run\n"})
+
+	if _, _, err := handleFindSimilar(context.Background(), nil, FindSimilarInput{Date: "2024-03-16"}); err == nil {
+		t.Error("want an error for a date without an entry")
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// originals of split entries are kept here when archived, renamed so they are not read as entries anymore
+const archiveDir = ".themis/archive"
+
+// headingDateLayouts are the date headings splitEntry understands, a leading weekday is tried with and without
+var headingDateLayouts = []string{
+	"2006-01-02",
+	"2006/01/02",
+	"January 2, 2006",
+	"January 2 2006",
+	"Jan 2, 2006",
+	"Jan 2 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+}
+
+type SplitEntryInput struct {
+	Date    string `json:"date" jsonschema:"Date of the entry to split in YYYY-MM-DD format"`
+	Archive bool   `json:"archive,omitempty" jsonschema:"Keep the original file under .themis/archive, otherwise it is replaced by its own section or removed"`
+	DryRun  bool   `json:"dryRun,omitempty" jsonschema:"Report what would be created without writing any files"`
+}
+
+type SplitEntryOutput struct {
+	Date     string   `json:"date" jsonschema:"The split entry"`
+	Created  []string `json:"created" jsonschema:"Dates of the entries written from the sections, in document order"`
+	Archived string   `json:"archived,omitempty" jsonschema:"Vault relative path the original was archived to"`
+	Removed  bool     `json:"removed" jsonschema:"Whether the original file was removed because none of its sections carry its date"`
+	DryRun   bool     `json:"dryRun" jsonschema:"Whether this was a dry run"`
+}
+
+type entrySection struct {
+	date    time.Time
+	content string
+}
+
+// handlers
+func handleSplitEntry(ctx context.Context, req *mcp.CallToolRequest, input SplitEntryInput) (
+	*mcp.CallToolResult,
+	SplitEntryOutput,
+	error,
+) {
+	entry, err := getEntryByDate(input.Date)
+	if err != nil {
+		return nil, SplitEntryOutput{}, err
+	}
+	if entry.Locked {
+		return nil, SplitEntryOutput{}, fmt.Errorf("entry %s is encrypted and could not be decrypted", entry.Date)
+	}
+
+	sections, err := splitSections(entry)
+	if err != nil {
+		return nil, SplitEntryOutput{}, err
+	}
+
+	// every section other than the entry's own date becomes a new file next to the original, none of which may exist yet
+	output := SplitEntryOutput{Date: entry.Date, Created: []string{}, Removed: true, DryRun: input.DryRun}
+	dir, suffix := filepath.Dir(entry.FilePath), strings.TrimPrefix(filepath.Base(entry.FilePath), entry.Date)
+	paths := make([]string, len(sections))
+	var taken []string
+	for i, section := range sections {
+		dateStr := section.date.Format("2006-01-02")
+		output.Created = append(output.Created, dateStr)
+		if dateStr == entry.Date {
+			paths[i] = entry.FilePath
+			output.Removed = false
+			continue
+		}
+
+		if _, err := getEntryByDate(dateStr); err == nil {
+			taken = append(taken, dateStr)
+			continue
+		}
+		path, err := resolveInVault(filepath.Join(dir, dateStr+suffix))
+		if err != nil {
+			return nil, SplitEntryOutput{}, err
+		}
+		paths[i] = path
+	}
+	if len(taken) > 0 {
+		return nil, SplitEntryOutput{}, fmt.Errorf("entries already exist for %s", strings.Join(taken, ", "))
+	}
+
+	archivePath := ""
+	if input.Archive {
+		archivePath, err = resolveInVault(filepath.Join(archiveDir, entry.Date+".pre-split"+suffix))
+		if err != nil {
+			return nil, SplitEntryOutput{}, err
+		}
+		if fileExists(archivePath) {
+			return nil, SplitEntryOutput{}, fmt.Errorf("%s is already archived", vaultRelative(archivePath))
+		}
+		output.Archived = vaultRelative(archivePath)
+	}
+
+	if input.DryRun {
+		return nil, output, nil
+	}
+
+	// the original is archived before anything is written so no content is lost if a later step fails
+	if archivePath != "" {
+		original, err := os.ReadFile(entry.FilePath)
+		if err != nil {
+			return nil, SplitEntryOutput{}, fmt.Errorf("failed to read %s: %w", entry.FilePath, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(archivePath), 0o755); err != nil {
+			return nil, SplitEntryOutput{}, fmt.Errorf("failed to create %s: %w", filepath.Dir(archivePath), err)
+		}
+		if err := writeFileAtomic(archivePath, original); err != nil {
+			return nil, SplitEntryOutput{}, fmt.Errorf("failed to archive %s: %w", entry.FilePath, err)
+		}
+	}
+
+	for i, section := range sections {
+		if paths[i] != entry.FilePath {
+			if err := writeNewEntry(paths[i], section.content); err != nil {
+				return nil, SplitEntryOutput{}, err
+			}
+			continue
+		}
+
+		data, err := encodeEntryFile(entry.FilePath, section.content)
+		if err != nil {
+			return nil, SplitEntryOutput{}, fmt.Errorf("failed to encrypt %s: %w", entry.Date, err)
+		}
+		if err := writeFileAtomic(entry.FilePath, data); err != nil {
+			return nil, SplitEntryOutput{}, fmt.Errorf("failed to write %s: %w", entry.FilePath, err)
+		}
+	}
+
+	if output.Removed {
+		if err := journal("remove", entry.FilePath, nil, func() error { return os.Remove(entry.FilePath) }); err != nil {
+			return nil, SplitEntryOutput{}, fmt.Errorf("failed to remove %s: %w", entry.FilePath, err)
+		}
+		cache.invalidate(entry.FilePath)
+	}
+
+	return nil, output, nil
+}
+
+// helpers
+
+// splitSections cuts the body at its top level headings, each of which must be a date. the date heading itself
+// is dropped since the file name carries it. frontmatter and any text before the first heading stay with the
+// section of the entry's own date, or the first section when there is none.
+func splitSections(entry Entry) ([]entrySection, error) {
+	raw, body, hasFrontmatter := splitFrontmatter(entry.Content)
+	headings, _ := parseHeadings(body)
+	if len(headings) == 0 {
+		return nil, fmt.Errorf("entry %s has no headings to split on", entry.Date)
+	}
+
+	top := headings[0].Level
+	for _, heading := range headings {
+		top = min(top, heading.Level)
+	}
+
+	lines := strings.SplitAfter(body, "\n")
+	var sections []entrySection
+	var invalid []string
+	seen := map[string]bool{}
+	var starts []Heading
+	for _, heading := range headings {
+		if heading.Level != top {
+			continue
+		}
+		date, ok := parseHeadingDate(heading.Text)
+		if !ok {
+			invalid = append(invalid, fmt.Sprintf("%q", heading.Text))
+			continue
+		}
+		if seen[date.Format("2006-01-02")] {
+			return nil, fmt.Errorf("date %s appears in more than one heading", date.Format("2006-01-02"))
+		}
+		seen[date.Format("2006-01-02")] = true
+		sections = append(sections, entrySection{date: date})
+		starts = append(starts, heading)
+	}
+	if len(invalid) > 0 {
+		return nil, fmt.Errorf("top level headings are not dates: %s", strings.Join(invalid, ", "))
+	}
+
+	for i, heading := range starts {
+		end := len(lines)
+		if i+1 < len(starts) {
+			end = starts[i+1].line
+		}
+		sections[i].content = strings.Trim(strings.Join(lines[min(heading.bodyStart, end):end], ""), "\n") + "\n"
+	}
+
+	home := 0
+	for i, section := range sections {
+		if section.date.Format("2006-01-02") == entry.Date {
+			home = i
+		}
+	}
+	lead := strings.TrimSpace(strings.Join(lines[:starts[0].line], ""))
+	if lead != "" {
+		sections[home].content = lead + "\n\n" + sections[home].content
+	}
+	if hasFrontmatter {
+		sections[home].content = "---\n" + raw + "---\n" + sections[home].content
+	}
+
+	return sections, nil
+}
+
+// parseHeadingDate reads a heading such as "2021-03-04", "March 4, 2021" or "Thursday, 4 March 2021"
+func parseHeadingDate(text string) (time.Time, bool) {
+	text = strings.TrimSpace(strings.Trim(text, "*_"))
+	candidates := []string{text}
+	if weekday, rest, ok := strings.Cut(text, " "); ok && isWeekdayName(strings.TrimSuffix(strings.ToLower(weekday), ",")) {
+		candidates = append(candidates, strings.TrimSpace(rest))
+	}
+
+	for _, candidate := range candidates {
+		for _, layout := range headingDateLayouts {
+			if date, err := time.Parse(layout, candidate); err == nil {
+				return date, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const monthDump = "---\ntags: [dump]\n---\nnotes from early march\n\n# 2021-03-01\nmonday\n\n# March 2, 2021\ntuesday\n## evening\nfilm\n\n# Wednesday, 3 March 2021\nwednesday\n"
+
+func TestSplitEntry(t *testing.T) {
+	dir := newTestVault(t, map[string]string{"2021/2021-03-01.md": monthDump})
+	ctx := context.Background()
+
+	// a dry run reports the sections without touching the vault
+	_, output, err := handleSplitEntry(ctx, nil, SplitEntryInput{Date: "2021-03-01", Archive: true, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"2021-03-01", "2021-03-02", "2021-03-03"}
+	if !reflect.DeepEqual(output.Created, want) || output.Removed || output.Archived != ".themis/archive/2021-03-01.pre-split.md" {
+		t.Errorf("dry run = %+v", output)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2021", "2021-03-02.md")); !os.IsNotExist(err) {
+		t.Fatalf("dry run wrote a section: %v", err)
+	}
+
+	_, output, err = handleSplitEntry(ctx, nil, SplitEntryInput{Date: "2021-03-01", Archive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(output.Created, want) {
+		t.Errorf("created = %v, want %v", output.Created, want)
+	}
+
+	// sections land next to the original, frontmatter and lead text stay with the entry's own date
+	files := map[string]string{
+		"2021/2021-03-01.md":                      "---\ntags: [dump]\n---\nnotes from early march\n\nmonday\n",
+		"2021/2021-03-02.md":                      "tuesday\n## evening\nfilm\n",
+		"2021/2021-03-03.md":                      "wednesday\n",
+		".themis/archive/2021-03-01.pre-split.md": monthDump,
+	}
+	for rel, content := range files {
+		if got := readTestFile(t, filepath.Join(dir, filepath.FromSlash(rel))); got != content {
+			t.Errorf("%s = %q, want %q", rel, got, content)
+		}
+	}
+
+	// the split sections are entries now, the archive is not
+	_, year, err := handleGetEntriesByYear(ctx, nil, GetEntriesByYearInput{Year: 2021})
+	if err != nil || !reflect.DeepEqual(entryDates(year.Entries), []string{"2021-03-03", "2021-03-02", "2021-03-01"}) {
+		t.Errorf("2021 = %v, %v", entryDates(year.Entries), err)
+	}
+}
+
+func TestSplitEntryRemovesOriginalWithoutItsDate(t *testing.T) {
+	dir := newTestVault(t, map[string]string{"2021-03-31.md": "# 2021-03-01\nfirst\n# 2021-03-02\nsecond\n"})
+
+	_, output, err := handleSplitEntry(context.Background(), nil, SplitEntryInput{Date: "2021-03-31"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !output.Removed || !reflect.DeepEqual(output.Created, []string{"2021-03-01", "2021-03-02"}) {
+		t.Errorf("output = %+v", output)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2021-03-31.md")); !os.IsNotExist(err) {
+		t.Errorf("original still there: %v", err)
+	}
+}
+
+func TestSplitEntryRefuses(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		problem string
+	}{
+		{"no headings", map[string]string{"2021-03-01.md": "just text\n"}, "no headings"},
+		{"heading not a date", map[string]string{"2021-03-01.md": "# 2021-03-01\na\n# Groceries\nb\n"}, `"Groceries"`},
+		{"repeated date", map[string]string{"2021-03-01.md": "# 2021-03-02\na\n# March 2, 2021\nb\n"}, "more than one heading"},
+		{"target exists", map[string]string{"2021-03-01.md": "# 2021-03-01\na\n# 2021-03-02\nb\n", "2021-03-02.md": "kept\n"}, "already exist for 2021-03-02"},
+	}
+	for _, tt := range tests {
+		dir := newTestVault(t, tt.files)
+		_, _, err := handleSplitEntry(context.Background(), nil, SplitEntryInput{Date: "2021-03-01"})
+		if err == nil || !strings.Contains(err.Error(), tt.problem) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.problem)
+		}
+		if got := readTestFile(t, filepath.Join(dir, "2021-03-01.md")); got != tt.files["2021-03-01.md"] {
+			t.Errorf("%s: original changed to %q", tt.name, got)
+		}
+	}
+}
+
+func TestParseHeadingDate(t *testing.T) {
+	for _, text := range []string{"2021-03-04", "2021/03/04", "March 4, 2021", "Mar 4 2021", "4 March 2021", "Thursday, 4 March 2021", "thursday March 4, 2021", "**2021-03-04**"} {
+		if date, ok := parseHeadingDate(text); !ok || date.Format("2006-01-02") != "2021-03-04" {
+			t.Errorf("parseHeadingDate(%q) = %v, %v", text, date, ok)
+		}
+	}
+	for _, text := range []string{"Groceries", "2021-13-01", "Someday, 4 March 2021"} {
+		if _, ok := parseHeadingDate(text); ok {
+			t.Errorf("parseHeadingDate(%q) accepted", text)
+		}
+	}
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/curator4/mcp-server-diary/query"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	topTagsLimit        = 10
+	longestEntriesLimit = 5
+	defaultSnippetWords = 50
+)
+
+type GetYearStatsInput struct {
+	Year            int  `json:"year" jsonschema:"Year to aggregate (e.g., 2024)"`
+	IncludeSnippets bool `json:"includeSnippets,omitempty" jsonschema:"Also return the opening words of the longest entry of each month"`
+	SnippetWords    int  `json:"snippetWords,omitempty" jsonschema:"Number of words per monthly snippet (default 50)"`
+}
+
+type MonthStats struct {
+	Month   string `json:"month" jsonschema:"Month in YYYY-MM format"`
+	Entries int    `json:"entries" jsonschema:"Number of entries written that month"`
+	Words   int    `json:"words" jsonschema:"Total words written that month"`
+	Snippet string `json:"snippet,omitempty" jsonschema:"Opening words of the longest entry that month"`
+}
+
+type TagCount struct {
+	Tag   string `json:"tag" jsonschema:"Tag name without the leading #"`
+	Count int    `json:"count" jsonschema:"Number of entries using the tag"`
+}
+
+type Streak struct {
+	Days int    `json:"days" jsonschema:"Number of consecutive days with an entry"`
+	From string `json:"from,omitempty" jsonschema:"First day of the streak in YYYY-MM-DD format"`
+	To   string `json:"to,omitempty" jsonschema:"Last day of the streak in YYYY-MM-DD format"`
+}
+
+type EntrySize struct {
+	Date  string `json:"date" jsonschema:"Entry date in YYYY-MM-DD format"`
+	Words int    `json:"words" jsonschema:"Word count of the entry"`
+}
+
+type YearStatsOutput struct {
+	Year           int          `json:"year" jsonschema:"The aggregated year"`
+	TotalEntries   int          `json:"totalEntries" jsonschema:"Number of entries written in the year"`
+	TotalWords     int          `json:"totalWords" jsonschema:"Total words written in the year"`
+	Months         []MonthStats `json:"months" jsonschema:"Per month breakdown, January first"`
+	TopTags        []TagCount   `json:"topTags" jsonschema:"Most used tags, most frequent first"`
+	LongestStreak  Streak       `json:"longestStreak" jsonschema:"Longest run of consecutive days with entries"`
+	LongestEntry   *EntrySize   `json:"longestEntry,omitempty" jsonschema:"The single longest entry of the year"`
+	LongestEntries []EntrySize  `json:"longestEntries" jsonschema:"The five longest entries, longest first"`
+}
+
+type GetLengthTrendInput struct {
+	Start string `json:"start,omitempty" jsonschema:"First date to include in YYYY-MM-DD format"`
+	End   string `json:"end,omitempty" jsonschema:"Last date to include in YYYY-MM-DD format"`
+}
+
+type MonthLength struct {
+	Month    string  `json:"month" jsonschema:"Month in YYYY-MM format"`
+	AvgWords float64 `json:"avgWords" jsonschema:"Average words per entry that month"`
+}
+
+type LengthTrendOutput struct {
+	Months []MonthLength `json:"months" jsonschema:"Months with at least one entry, oldest first"`
+}
+
+type GetVocabularyGrowthInput struct {
+	Start string `json:"start,omitempty" jsonschema:"First date to include in YYYY-MM-DD format"`
+	End   string `json:"end,omitempty" jsonschema:"Last date to include in YYYY-MM-DD format"`
+}
+
+type MonthVocabulary struct {
+	Month                 string `json:"month" jsonschema:"Month in YYYY-MM format"`
+	CumulativeUniqueWords int    `json:"cumulativeUniqueWords" jsonschema:"Distinct words used from the first included month up to and including this one"`
+}
+
+type VocabularyGrowthOutput struct {
+	Months []MonthVocabulary `json:"months" jsonschema:"Months with at least one entry, oldest first"`
+}
+
+// handlers
+func handleGetYearStats(ctx context.Context, req *mcp.CallToolRequest, input GetYearStatsInput) (
+	*mcp.CallToolResult,
+	YearStatsOutput,
+	error,
+) {
+	if input.Year < 1 || input.Year > 9999 {
+		return nil, YearStatsOutput{}, fmt.Errorf("invalid year %d", input.Year)
+	}
+	snippetWords := input.SnippetWords
+	if snippetWords <= 0 {
+		snippetWords = defaultSnippetWords
+	}
+
+	output := YearStatsOutput{Year: input.Year}
+	for month := time.January; month <= time.December; month++ {
+		output.Months = append(output.Months, MonthStats{Month: fmt.Sprintf("%04d-%02d", input.Year, month)})
+	}
+
+	// everything below is bounded by the days of the year and the number of distinct tags
+	monthLongest := make([]int, 12)
+	var written [366]bool
+	tagCounts := map[string]int{}
+
+	add := func(dateStr string, words int, tags []string) {
+		date, _ := time.Parse("2006-01-02", dateStr)
+		output.TotalEntries++
+		output.TotalWords += words
+
+		month := &output.Months[date.Month()-1]
+		month.Entries++
+		month.Words += words
+
+		written[date.YearDay()-1] = true
+		for _, tag := range tags {
+			tagCounts[tag]++
+		}
+
+		output.LongestEntries = insertLongest(output.LongestEntries, EntrySize{Date: dateStr, Words: words})
+	}
+	filter := query.Filter[Entry]{Start: time.Date(input.Year, time.January, 1, 0, 0, 0, 0, time.UTC), End: time.Date(input.Year, time.December, 31, 0, 0, 0, 0, time.UTC)}
+
+	// snippets need the content, everything else comes from the entry index when one is configured
+	var records []indexRecord
+	var indexed bool
+	var err error
+	if !input.IncludeSnippets {
+		records, indexed, err = vaultIndex.entries(filter.KeepDay())
+	}
+	if indexed {
+		for _, record := range records {
+			add(record.Date, record.WordCount, record.Tags)
+		}
+	} else {
+		err = eachEntry(ctx, filter, func(entry Entry) error {
+			date, _ := time.Parse("2006-01-02", entry.Date)
+			fm, body, _ := parseFrontmatter(entry.Content)
+			if input.IncludeSnippets && entry.WordCount > monthLongest[date.Month()-1] {
+				monthLongest[date.Month()-1] = entry.WordCount
+				output.Months[date.Month()-1].Snippet = snippet(strings.Fields(body), snippetWords)
+			}
+			add(entry.Date, entry.WordCount, extractTags(fm, body))
+			return nil
+		})
+	}
+	if err != nil {
+		return nil, YearStatsOutput{}, fmt.Errorf("failed to get entries: %w", err)
+	}
+
+	output.TopTags = topTags(tagCounts, topTagsLimit)
+	output.LongestStreak = longestStreak(input.Year, written[:])
+	if len(output.LongestEntries) > 0 {
+		output.LongestEntry = &output.LongestEntries[0]
+	}
+
+	return nil, output, nil
+}
+
+func handleGetLengthTrend(ctx context.Context, req *mcp.CallToolRequest, input GetLengthTrendInput) (
+	*mcp.CallToolResult,
+	LengthTrendOutput,
+	error,
+) {
+	filter, err := rangeFilter(input.Start, input.End)
+	if err != nil {
+		return nil, LengthTrendOutput{}, err
+	}
+
+	type totals struct{ entries, words int }
+	months := map[string]*totals{}
+	add := func(date string, words int) {
+		month := date[:7]
+		if months[month] == nil {
+			months[month] = &totals{}
+		}
+		months[month].entries++
+		months[month].words += words
+	}
+
+	// word counts come from the entry index when one is configured, without reading any content
+	records, indexed, err := vaultIndex.entries(filter.KeepDay())
+	if indexed {
+		for _, record := range records {
+			add(record.Date, record.WordCount)
+		}
+	} else {
+		err = eachEntry(ctx, filter, func(entry Entry) error {
+			add(entry.Date, entry.WordCount)
+			return nil
+		})
+	}
+	if err != nil {
+		return nil, LengthTrendOutput{}, fmt.Errorf("failed to get entries: %w", err)
+	}
+
+	output := LengthTrendOutput{Months: []MonthLength{}}
+	for month, t := range months {
+		avg := float64(t.words) / float64(t.entries)
+		output.Months = append(output.Months, MonthLength{Month: month, AvgWords: math.Round(avg*10) / 10})
+	}
+	sort.Slice(output.Months, func(i, j int) bool { return output.Months[i].Month < output.Months[j].Month })
+
+	return nil, output, nil
+}
+
+func handleGetVocabularyGrowth(ctx context.Context, req *mcp.CallToolRequest, input GetVocabularyGrowthInput) (
+	*mcp.CallToolResult,
+	VocabularyGrowthOutput,
+	error,
+) {
+	filter, err := rangeFilter(input.Start, input.End)
+	if err != nil {
+		return nil, VocabularyGrowthOutput{}, err
+	}
+
+	// words are tokenized the same way as findSimilar, entries can arrive in any order so they are grouped by month first
+	months := map[string]map[string]bool{}
+	err = eachEntry(ctx, filter, func(entry Entry) error {
+		month := entry.Date[:7]
+		if months[month] == nil {
+			months[month] = map[string]bool{}
+		}
+		for _, token := range tokenize(entry.Content) {
+			if token = strings.Trim(token, "'"); token != "" {
+				months[month][token] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, VocabularyGrowthOutput{}, fmt.Errorf("failed to get entries: %w", err)
+	}
+
+	order := make([]string, 0, len(months))
+	for month := range months {
+		order = append(order, month)
+	}
+	sort.Strings(order)
+
+	output := VocabularyGrowthOutput{Months: make([]MonthVocabulary, 0, len(order))}
+	seen := map[string]bool{}
+	for _, month := range order {
+		for word := range months[month] {
+			seen[word] = true
+		}
+		output.Months = append(output.Months, MonthVocabulary{Month: month, CumulativeUniqueWords: len(seen)})
+	}
+
+	return nil, output, nil
+}
+
+// helpers
+
+// insertLongest keeps sizes sorted longest first and capped at longestEntriesLimit
+func insertLongest(sizes []EntrySize, size EntrySize) []EntrySize {
+	i := sort.Search(len(sizes), func(i int) bool { return sizes[i].Words < size.Words })
+	if i >= longestEntriesLimit {
+		return sizes
+	}
+
+	sizes = append(sizes, EntrySize{})
+	copy(sizes[i+1:], sizes[i:])
+	sizes[i] = size
+	if len(sizes) > longestEntriesLimit {
+		sizes = sizes[:longestEntriesLimit]
+	}
+	return sizes
+}
+
+func topTags(counts map[string]int, limit int) []TagCount {
+	tags := make([]TagCount, 0, len(counts))
+	for tag, count := range counts {
+		tags = append(tags, TagCount{Tag: tag, Count: count})
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+
+	if len(tags) > limit {
+		tags = tags[:limit]
+	}
+	return tags
+}
+
+// longestStreak finds the longest run of written days, indexed by day of year
+func longestStreak(year int, written []bool) Streak {
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	var best Streak
+	run := 0
+	for day, ok := range written {
+		if !ok {
+			run = 0
+			continue
+		}
+
+		run++
+		if run > best.Days {
+			best = Streak{
+				Days: run,
+				From: start.AddDate(0, 0, day-run+1).Format("2006-01-02"),
+				To:   start.AddDate(0, 0, day).Format("2006-01-02"),
+			}
+		}
+	}
+
+	return best
+}
+
+func snippet(words []string, limit int) string {
+	if len(words) <= limit {
+		return strings.Join(words, " ")
+	}
+	return strings.Join(words[:limit], " ") + "…"
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestGetLengthTrend(t *testing.T) {
+	dir := newTestVault(t, map[string]string{
+		"2024-01-31.md": "one two three four\n",
+		"2024-02-01.md": "one two\n",
+		"2024-02-02.md": "---\nmood: 4\n---\none two three\n",
+		"2024-02-03.md": "one\n",
+		"2024-03-01.md": "one two three four five six\n",
+	})
+	want := []MonthLength{{Month: "2024-02", AvgWords: 2}, {Month: "2024-03", AvgWords: 6}}
+
+	// walking the files and reading the index give the same averages
+	for _, indexed := range []bool{false, true} {
+		if indexed {
+			useTestIndex(t, dir)
+		}
+		_, output, err := handleGetLengthTrend(context.Background(), nil, GetLengthTrendInput{Start: "2024-02-01"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(output.Months, want) {
+			t.Errorf("indexed %v: months = %+v, want %+v", indexed, output.Months, want)
+		}
+	}
+
+	_, output, err := handleGetLengthTrend(context.Background(), nil, GetLengthTrendInput{Start: "2025-01-01"})
+	if err != nil || output.Months == nil || len(output.Months) != 0 {
+		t.Errorf("empty range = %+v, %v, want an empty list", output.Months, err)
+	}
+	if _, _, err := handleGetLengthTrend(context.Background(), nil, GetLengthTrendInput{Start: "February"}); err == nil {
+		t.Error("want an error for an invalid start date")
+	}
+}
+
+func TestGetVocabularyGrowth(t *testing.T) {
+	newTestVault(t, map[string]string{
+		"2024-01-05.md": "---\nmood: 3\n---\nThe cat sat.\n",
+		"2024-01-20.md": "the CAT ran\n",
+		"2024-02-10.md": "'quoted' don't stop\n",
+		"2024-04-01.md": "the cat, the cat\n",
+	})
+	ctx := context.Background()
+
+	// words are lowercased, frontmatter is left out and months without new words still appear
+	_, output, err := handleGetVocabularyGrowth(ctx, nil, GetVocabularyGrowthInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []MonthVocabulary{{"2024-01", 4}, {"2024-02", 7}, {"2024-04", 7}}
+	if !reflect.DeepEqual(output.Months, want) {
+		t.Errorf("months = %+v, want %+v", output.Months, want)
+	}
+
+	// counting starts from the first month in range
+	_, output, err = handleGetVocabularyGrowth(ctx, nil, GetVocabularyGrowthInput{Start: "2024-02-01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []MonthVocabulary{{"2024-02", 3}, {"2024-04", 5}}; !reflect.DeepEqual(output.Months, want) {
+		t.Errorf("from february = %+v, want %+v", output.Months, want)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type AddTagToRangeInput struct {
+	Start  string `json:"start" jsonschema:"First date to tag in YYYY-MM-DD format"`
+	End    string `json:"end" jsonschema:"Last date to tag in YYYY-MM-DD format"`
+	Tag    string `json:"tag" jsonschema:"Tag to add, with or without the leading #"`
+	DryRun bool   `json:"dryRun,omitempty" jsonschema:"Report what would change without writing any files"`
+}
+
+type AddTagToRangeOutput struct {
+	Affected []string `json:"affected" jsonschema:"Dates of entries that were (or would be) tagged"`
+	Skipped  []string `json:"skipped" jsonschema:"Dates of entries that already had the tag"`
+	Locked   []string `json:"locked,omitempty" jsonschema:"Dates of encrypted entries that could not be decrypted and were left alone"`
+	DryRun   bool     `json:"dryRun" jsonschema:"Whether this was a dry run"`
+}
+
+// handlers
+func handleAddTagToRange(ctx context.Context, req *mcp.CallToolRequest, input AddTagToRangeInput) (
+	*mcp.CallToolResult,
+	AddTagToRangeOutput,
+	error,
+) {
+	tag := strings.TrimPrefix(strings.TrimSpace(input.Tag), "#")
+	if !tagNamePattern.MatchString(tag) {
+		return nil, AddTagToRangeOutput{}, fmt.Errorf("invalid tag %q", input.Tag)
+	}
+	if input.Start == "" || input.End == "" {
+		return nil, AddTagToRangeOutput{}, fmt.Errorf("start and end dates are required")
+	}
+	filter, err := rangeFilter(input.Start, input.End)
+	if err != nil {
+		return nil, AddTagToRangeOutput{}, err
+	}
+
+	entries, err := collectEntries(ctx, filter)
+	if err != nil {
+		return nil, AddTagToRangeOutput{}, fmt.Errorf("failed to get entries: %w", err)
+	}
+
+	output := AddTagToRangeOutput{DryRun: input.DryRun}
+	for _, entry := range entries {
+		if entry.Locked {
+			output.Locked = append(output.Locked, entry.Date)
+			continue
+		}
+		if hasTag(entry, tag) {
+			output.Skipped = append(output.Skipped, entry.Date)
+			continue
+		}
+
+		updated, err := addFrontmatterTag(entry.Content, tag)
+		if err != nil {
+			return nil, AddTagToRangeOutput{}, fmt.Errorf("failed to tag %s: %w", entry.Date, err)
+		}
+		if !input.DryRun {
+			original, err := readEntryPlaintext(entry)
+			if err != nil {
+				return nil, AddTagToRangeOutput{}, fmt.Errorf("failed to read %s: %w", entry.FilePath, err)
+			}
+			data, err := encodeEntryFile(entry.FilePath, string(encodeLike(original, updated)))
+			if err != nil {
+				return nil, AddTagToRangeOutput{}, fmt.Errorf("failed to encrypt %s: %w", entry.Date, err)
+			}
+			if err := writeFileAtomic(entry.FilePath, data); err != nil {
+				return nil, AddTagToRangeOutput{}, fmt.Errorf("failed to write %s: %w", entry.FilePath, err)
+			}
+		}
+		output.Affected = append(output.Affected, entry.Date)
+	}
+
+	return nil, output, nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAddFrontmatterTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"no frontmatter", "# Friday\n", "---\ntags:\n  - trip\n---\n# Friday\n"},
+		{"no tags field", "---\nmood: 4\n---\nbody\n", "---\nmood: 4\ntags:\n  - trip\n---\nbody\n"},
+		{"flow list", "---\ntags: [work, gym]\n---\nbody\n", "---\ntags: [work, gym, trip]\n---\nbody\n"},
+		{"empty flow list", "---\ntags: []\n---\nbody\n", "---\ntags: [trip]\n---\nbody\n"},
+		{"block list keeps its indent", "---\ntags:\n    - work\nmood: 4\n---\nbody\n", "---\ntags:\n    - work\n    - trip\nmood: 4\n---\nbody\n"},
+		{"single value", "---\ntags: work\n---\nbody\n", "---\ntags: [work, trip]\n---\nbody\n"},
+	}
+	for _, tt := range tests {
+		got, err := addFrontmatterTag(tt.content, "trip")
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAddTagToRange(t *testing.T) {
+	dir := newTestVault(t, map[string]string{
+		"2024-03-13.md": "# before the range\n",
+		"2024-03-14.md": "# Thursday\n",
+		"2024-03-15.md": "---\ntags: [trip]\n---\n# Friday\n",
+		"2024-03-16.md": "# Saturday #Trip\n",
+		"2024-03-17.md": "# Sunday\n",
+	})
+	ctx := context.Background()
+	input := AddTagToRangeInput{Start: "2024-03-14", End: "2024-03-17", Tag: "#trip", DryRun: true}
+
+	_, dry, err := handleAddTagToRange(ctx, nil, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dry.Affected, []string{"2024-03-14", "2024-03-17"}) || !reflect.DeepEqual(dry.Skipped, []string{"2024-03-15", "2024-03-16"}) {
+		t.Errorf("dry run = %+v", dry)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "2024-03-14.md")); got != "# Thursday\n" {
+		t.Errorf("the dry run wrote %q", got)
+	}
+
+	input.DryRun = false
+	_, output, err := handleAddTagToRange(ctx, nil, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(output.Affected, dry.Affected) {
+		t.Errorf("affected = %v, want %v", output.Affected, dry.Affected)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "2024-03-14.md")); got != "---\ntags:\n  - trip\n---\n# Thursday\n" {
+		t.Errorf("tagged entry = %q", got)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "2024-03-13.md")); got != "# before the range\n" {
+		t.Errorf("entry outside the range = %q", got)
+	}
+
+	for _, bad := range []AddTagToRangeInput{
+		{Start: "2024-03-14", End: "2024-03-17", Tag: "two words"},
+		{Start: "2024-03-14", Tag: "trip"},
+	} {
+		if _, _, err := handleAddTagToRange(ctx, nil, bad); err == nil {
+			t.Errorf("%+v: want an error", bad)
+		}
+	}
+}
+
+func TestAddTagToRangeKeepsEncoding(t *testing.T) {
+	bom := "\xEF\xBB\xBF"
+	utf16 := func(text string) string { return string(encodeLike(utf16BEBOM, text)) }
+	dir := newTestVault(t, map[string]string{
+		"2024-03-14.md": bom + "# Thursday\n",
+		"2024-03-15.md": utf16("# Friday\n"),
+	})
+
+	if _, _, err := handleAddTagToRange(context.Background(), nil, AddTagToRangeInput{Start: "2024-03-14", End: "2024-03-15", Tag: "trip"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := readTestFile(t, filepath.Join(dir, "2024-03-14.md")), bom+"---\ntags:\n  - trip\n---\n# Thursday\n"; got != want {
+		t.Errorf("bom entry = %q, want %q", got, want)
+	}
+	if got, want := readTestFile(t, filepath.Join(dir, "2024-03-15.md")), utf16("---\ntags:\n  - trip\n---\n# Friday\n"); got != want {
+		t.Errorf("utf-16 entry = % x, want % x", got, want)
+	}
+}
+
+func TestGetTagStats(t *testing.T) {
+	dir := newTestVault(t, map[string]string{
+		"2024-03-13.md": "---\ntags: [Work]\n---\none two\n",
+		"2024-03-14.md": "one two three #work #gym #gym\n",
+		"2024-03-15.md": "one #work #gym\n",
+		"2024-04-01.md": "one #travel\n",
+	})
+	want := []TagStats{
+		{Tag: "work", EntryCount: 3, TotalWords: 11, FirstDate: "2024-03-13", LastDate: "2024-03-15"},
+		{Tag: "gym", EntryCount: 2, TotalWords: 9, FirstDate: "2024-03-14", LastDate: "2024-03-15"},
+	}
+
+	// the walk and the entry index agree, tags merge case-insensitively under the spelling most entries use
+	// and count once per entry
+	for _, indexed := range []bool{false, true} {
+		if indexed {
+			useTestIndex(t, dir)
+		}
+		_, output, err := handleGetTagStats(context.Background(), nil, GetTagStatsInput{Start: "2024-03-01", End: "2024-03-31"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(output.Tags, want) {
+			t.Errorf("indexed %v: tags = %+v, want %+v", indexed, output.Tags, want)
+		}
+	}
+
+	if _, _, err := handleGetTagStats(context.Background(), nil, GetTagStatsInput{Start: "March"}); err == nil {
+		t.Error("invalid start date accepted")
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type GetTagStatsInput struct {
+	Start string `json:"start,omitempty" jsonschema:"First date to include in YYYY-MM-DD format"`
+	End   string `json:"end,omitempty" jsonschema:"Last date to include in YYYY-MM-DD format"`
+}
+
+type TagStats struct {
+	Tag        string `json:"tag" jsonschema:"Tag name without the leading #, in its most common spelling"`
+	EntryCount int    `json:"entryCount" jsonschema:"Number of entries using the tag"`
+	TotalWords int    `json:"totalWords" jsonschema:"Words written across those entries"`
+	FirstDate  string `json:"firstDate" jsonschema:"Earliest entry with the tag in YYYY-MM-DD format"`
+	LastDate   string `json:"lastDate" jsonschema:"Latest entry with the tag in YYYY-MM-DD format"`
+}
+
+type TagStatsOutput struct {
+	Tags []TagStats `json:"tags" jsonschema:"Per tag aggregates, most used first"`
+}
+
+// handlers
+func handleGetTagStats(ctx context.Context, req *mcp.CallToolRequest, input GetTagStatsInput) (
+	*mcp.CallToolResult,
+	TagStatsOutput,
+	error,
+) {
+	filter, err := rangeFilter(input.Start, input.End)
+	if err != nil {
+		return nil, TagStatsOutput{}, err
+	}
+
+	// tags are merged case-insensitively, the spelling used by most entries wins
+	stats := map[string]*TagStats{}
+	spellings := map[string]map[string]int{}
+	add := func(date string, words int, tags []string) {
+		seen := map[string]bool{}
+		for _, tag := range tags {
+			key := strings.ToLower(tag)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			s := stats[key]
+			if s == nil {
+				s = &TagStats{FirstDate: date, LastDate: date}
+				stats[key] = s
+				spellings[key] = map[string]int{}
+			}
+			s.EntryCount++
+			s.TotalWords += words
+			s.FirstDate = min(s.FirstDate, date)
+			s.LastDate = max(s.LastDate, date)
+			spellings[key][tag]++
+		}
+	}
+
+	// tags and word counts come from the entry index when one is configured, without reading any content
+	records, indexed, err := vaultIndex.entries(filter.KeepDay())
+	if indexed {
+		for _, record := range records {
+			add(record.Date, record.WordCount, record.Tags)
+		}
+	} else {
+		err = eachEntry(ctx, filter, func(entry Entry) error {
+			_, body, _ := splitFrontmatter(entry.Content)
+			add(entry.Date, entry.WordCount, extractTags(entry.Frontmatter, body))
+			return nil
+		})
+	}
+	if err != nil {
+		return nil, TagStatsOutput{}, fmt.Errorf("failed to get entries: %w", err)
+	}
+
+	output := TagStatsOutput{Tags: make([]TagStats, 0, len(stats))}
+	for key, s := range stats {
+		s.Tag = topTags(spellings[key], 1)[0].Tag
+		output.Tags = append(output.Tags, *s)
+	}
+	sort.Slice(output.Tags, func(i, j int) bool {
+		if output.Tags[i].EntryCount != output.Tags[j].EntryCount {
+			return output.Tags[i].EntryCount > output.Tags[j].EntryCount
+		}
+		return strings.ToLower(output.Tags[i].Tag) < strings.ToLower(output.Tags[j].Tag)
+	})
+
+	return nil, output, nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// staleTempAge is how long a temp file may sit in the vault before validateVault reports it as left behind
+const staleTempAge = time.Hour
+
+// THEMIS_TEMP_PATTERNS replaces the default globs, comma separated and matched against file names, e.g. "*.tmp,*~"
+var tempPatterns = getTempPatterns()
+
+// defaultTempPatterns covers editor backups, sync tools' partial downloads and our own atomic write temp files
+var defaultTempPatterns = []string{"*.tmp", "*.tmp-*", "*~", "*.partial", "*.swp", "*.crdownload", ".syncthing.*", ".~*", "~$*"}
+
+type TempFile struct {
+	Path    string `json:"path" jsonschema:"Vault relative path of the temp file"`
+	ModTime string `json:"modTime" jsonschema:"When the file was last modified, RFC 3339 in the configured timezone"`
+}
+
+func getTempPatterns() []string {
+	raw := strings.TrimSpace(os.Getenv("THEMIS_TEMP_PATTERNS"))
+	if raw == "" {
+		return defaultTempPatterns
+	}
+
+	var patterns []string
+	for _, pattern := range strings.Split(raw, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			log.Fatalf("invalid THEMIS_TEMP_PATTERNS pattern %q: %v", pattern, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns
+}
+
+// isTempFile reports whether name is a temporary or backup file, which is never read as an entry
+func isTempFile(name string) bool {
+	for _, pattern := range tempPatterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// staleTempFiles lists temp files older than staleTempAge, leaving out dot directories
+func staleTempFiles() []TempFile {
+	// file ages are measured against the wall clock, a pinned clock says nothing about when files were written
+	cutoff := wallNow().Add(-staleTempAge)
+	stale := []TempFile{}
+	filepath.WalkDir(themisPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != themisPath && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isTempFile(d.Name()) {
+			return nil
+		}
+
+		if info, err := d.Info(); err == nil && info.ModTime().Before(cutoff) {
+			stale = append(stale, TempFile{Path: vaultRelative(path), ModTime: info.ModTime().In(location).Format(time.RFC3339)})
+		}
+		return nil
+	})
+
+	sort.Slice(stale, func(i, j int) bool { return stale[i].Path < stale[j].Path })
+	return stale
+}
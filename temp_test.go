@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// tempMess is a vault the way obsidian, vim, office and sync tools leave it, next to two genuine entries
+func tempMess(t *testing.T) string {
+	t.Helper()
+	dir := newTestVault(t, map[string]string{
+		"2024-03-14.md":                 "# Thursday\n",
+		"2024/2024-03-15.md":            "# Friday\n",
+		".2024-03-15.md.tmp-4242":       "# Fri",
+		"2024/.~2024-03-15.md":          "lock",
+		"~$2024-03-16.md":               "office lock",
+		".syncthing.2024-03-16.md.tmp":  "partial",
+		"2024/2024-03-15.md~":           "# Friday backup\n",
+		"2024-03-14.md.partial":         "# Thurs",
+		".2024-03-14.md.swp":            "swap",
+		".obsidian/plugins/cache.tmp":   "plugin",
+		"2024/2024-03-17.md.crdownload": "download",
+	})
+	old := time.Now().Add(-3 * time.Hour)
+	for _, rel := range []string{".2024-03-15.md.tmp-4242", "2024/.~2024-03-15.md", "2024/2024-03-15.md~", ".obsidian/plugins/cache.tmp"} {
+		touch(t, filepath.Join(dir, filepath.FromSlash(rel)), old)
+	}
+	return dir
+}
+
+func TestIsTempFile(t *testing.T) {
+	for _, name := range []string{"a.tmp", ".2024-03-15.md.tmp-1", "2024-03-15.md~", "x.partial", ".2024-03-15.md.swp", "f.crdownload", ".syncthing.2024-03-15.md.tmp", ".~2024-03-15.md", "~$2024-03-15.md"} {
+		if !isTempFile(name) {
+			t.Errorf("%q is not a temp file", name)
+		}
+	}
+	for _, name := range []string{"2024-03-15.md", "2024-03-15.md.age", "tmp.md", "notes~draft.md"} {
+		if isTempFile(name) {
+			t.Errorf("%q is a temp file", name)
+		}
+	}
+}
+
+func TestGetTempPatterns(t *testing.T) {
+	t.Setenv("THEMIS_TEMP_PATTERNS", " *.bak , ,*~")
+	if got := getTempPatterns(); !reflect.DeepEqual(got, []string{"*.bak", "*~"}) {
+		t.Errorf("patterns = %q", got)
+	}
+	t.Setenv("THEMIS_TEMP_PATTERNS", "")
+	if got := getTempPatterns(); !reflect.DeepEqual(got, defaultTempPatterns) {
+		t.Errorf("patterns = %q, want the defaults", got)
+	}
+}
+
+func TestTempFilesIgnored(t *testing.T) {
+	dir := tempMess(t)
+	ctx := context.Background()
+
+	_, output, err := handleGetEntriesByYear(ctx, nil, GetEntriesByYearInput{Year: 2024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := entryDates(output.Entries); !reflect.DeepEqual(got, []string{"2024-03-15", "2024-03-14"}) {
+		t.Errorf("dates = %v", got)
+	}
+
+	// temp files are neither misnamed entries nor duplicates, only the ones older than an hour are reported
+	_, validation, err := handleValidateVault(ctx, nil, ValidateVaultInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(validation.SkippedNames) != 0 || len(validation.DuplicateDates) != 0 {
+		t.Errorf("skipped %v, duplicates %+v", validation.SkippedNames, validation.DuplicateDates)
+	}
+	var stale []string
+	for _, temp := range validation.StaleTempFiles {
+		stale = append(stale, temp.Path)
+	}
+	if want := []string{".2024-03-15.md.tmp-4242", "2024/.~2024-03-15.md", "2024/2024-03-15.md~"}; !reflect.DeepEqual(stale, want) {
+		t.Errorf("stale = %v, want %v", stale, want)
+	}
+
+	// a configured pattern hides even a file named like an entry
+	oldPatterns := tempPatterns
+	tempPatterns = append([]string{"2024-03-14.md"}, defaultTempPatterns...)
+	defer func() { tempPatterns = oldPatterns }()
+	_, output, err = handleGetEntriesByYear(ctx, nil, GetEntriesByYearInput{Year: 2024})
+	if err != nil || !reflect.DeepEqual(entryDates(output.Entries), []string{"2024-03-15"}) {
+		t.Errorf("with a custom pattern = %v, %v", entryDates(output.Entries), err)
+	}
+	useTestIndex(t, dir)
+	if dates, err := listEntryDates(); err != nil || len(dates) != 1 {
+		t.Errorf("indexed dates = %v, %v", dates, err)
+	}
+	// the stat pass the index and the length percentiles share leaves it out as well
+	if stamps := entryStamps(); len(stamps) != 1 {
+		t.Errorf("stamps = %v, want only 2024/2024-03-15.md", stamps)
+	}
+	if sorted, ok := lengths.distribution(ctx); !ok || len(sorted) != 1 {
+		t.Errorf("length distribution = %v, %v, want one entry", sorted, ok)
+	}
+}
+
+func TestStaleTempFilesIgnorePinnedClock(t *testing.T) {
+	tempMess(t)
+
+	// file ages use the wall clock, so pinning the clock decades ahead or back doesn't change what is stale
+	for _, pinned := range []string{"2099-01-01T00:00:00Z", "2001-01-01T00:00:00Z"} {
+		setTestNow(t, pinned)
+		var stale []string
+		for _, temp := range staleTempFiles() {
+			stale = append(stale, temp.Path)
+		}
+		if want := []string{".2024-03-15.md.tmp-4242", "2024/.~2024-03-15.md", "2024/2024-03-15.md~"}; !reflect.DeepEqual(stale, want) {
+			t.Errorf("with the clock at %s stale = %v, want %v", pinned, stale, want)
+		}
+	}
+}
+
+func TestVaultWatcherIgnoresTempFiles(t *testing.T) {
+	dir := newTestVault(t, nil)
+	oldWebhooks := activeWebhooks
+	activeWebhooks = nil
+	defer func() { activeWebhooks = oldWebhooks }()
+
+	watcher, err := newVaultWatcher(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.close()
+
+	temps := []string{filepath.Join(dir, ".~2024-03-15.md"), filepath.Join(dir, "~$2024-03-15.md")}
+	for _, path := range temps {
+		writeTestFile(t, path, "lock")
+	}
+	// the genuine entry written last is cached once its event is handled, the temp files before it never are
+	entry := filepath.Join(dir, "2024-03-15.md")
+	writeTestFile(t, entry, "# Friday\n")
+	eventually(t, "the entry is cached", func() bool { return cached(entry) })
+	for _, path := range temps {
+		if cached(path) {
+			t.Errorf("%s was read by the watcher", filepath.Base(path))
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/curator4/mcp-server-diary/query"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// threadField is the inline field marking an entry as part of an ongoing storyline, e.g. thread:: house-move
+const threadField = "thread"
+
+type ListThreadsInput struct {
+	Start string `json:"start,omitempty" jsonschema:"First date to include in YYYY-MM-DD format"`
+	End   string `json:"end,omitempty" jsonschema:"Last date to include in YYYY-MM-DD format"`
+}
+
+type ThreadSummary struct {
+	Thread     string `json:"thread" jsonschema:"Thread name as written in the thread:: field"`
+	EntryCount int    `json:"entryCount" jsonschema:"Number of entries in the thread"`
+	FirstDate  string `json:"firstDate" jsonschema:"Earliest entry in the thread in YYYY-MM-DD format"`
+	LastDate   string `json:"lastDate" jsonschema:"Latest entry in the thread in YYYY-MM-DD format"`
+}
+
+type ListThreadsOutput struct {
+	Threads []ThreadSummary `json:"threads" jsonschema:"Threads with at least one entry, most recently active first"`
+}
+
+type GetThreadInput struct {
+	Thread    string `json:"thread" jsonschema:"Thread name to fetch, matched case-insensitively"`
+	Outline   bool   `json:"outline,omitempty" jsonschema:"Return heading outlines instead of full entries"`
+	MaxTokens int    `json:"maxTokens,omitempty" jsonschema:"Drop the oldest entries once the estimated token total would exceed this budget"`
+
+	PreviewChars     int  `json:"previewChars,omitempty" jsonschema:"Add a plaintext preview of this many characters to each entry"`
+	OmitContent      bool `json:"omitContent,omitempty" jsonschema:"Leave out the full content, e.g. together with previewChars for list views"`
+	StripFrontmatter bool `json:"stripFrontmatter,omitempty" jsonschema:"Remove the --- frontmatter block from content, the parsed frontmatter field is still returned"`
+
+	Fields          []string `json:"fields,omitempty" jsonschema:"Entry fields to return, e.g. [\"date\", \"content\"], defaults to the server's configured set"`
+	ContentEncoding string   `json:"contentEncoding,omitempty" jsonschema:"plain (default) or base64 to base64 encode content for byte-faithful transport"`
+	Scope           string   `json:"scope,omitempty" jsonschema:"Vault subfolder to read entries from, e.g. daily, in place of the server's THEMIS_SCOPE, . for the whole vault"`
+}
+
+// ThreadOutput returns the thread's entries oldest first, or only their outlines when outline is set
+type ThreadOutput struct {
+	Thread string `json:"thread" jsonschema:"The requested thread"`
+	EntriesOutput
+	Outlines []EntryOutline `json:"outlines,omitempty" jsonschema:"Entry outlines in the thread when outline is set, oldest first"`
+}
+
+// handlers
+func handleListThreads(ctx context.Context, req *mcp.CallToolRequest, input ListThreadsInput) (
+	*mcp.CallToolResult,
+	ListThreadsOutput,
+	error,
+) {
+	filter, err := rangeFilter(input.Start, input.End)
+	if err != nil {
+		return nil, ListThreadsOutput{}, err
+	}
+
+	threads := map[string]*ThreadSummary{}
+	err = eachEntry(ctx, filter, func(entry Entry) error {
+		for _, thread := range entryThreads(entry) {
+			key := strings.ToLower(thread)
+			t := threads[key]
+			if t == nil {
+				t = &ThreadSummary{Thread: thread, FirstDate: entry.Date, LastDate: entry.Date}
+				threads[key] = t
+			}
+			t.EntryCount++
+			t.FirstDate = min(t.FirstDate, entry.Date)
+			t.LastDate = max(t.LastDate, entry.Date)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, ListThreadsOutput{}, fmt.Errorf("failed to get entries: %w", err)
+	}
+
+	output := ListThreadsOutput{Threads: make([]ThreadSummary, 0, len(threads))}
+	for _, t := range threads {
+		output.Threads = append(output.Threads, *t)
+	}
+	sort.Slice(output.Threads, func(i, j int) bool {
+		if output.Threads[i].LastDate != output.Threads[j].LastDate {
+			return output.Threads[i].LastDate > output.Threads[j].LastDate
+		}
+		return strings.ToLower(output.Threads[i].Thread) < strings.ToLower(output.Threads[j].Thread)
+	})
+
+	return nil, output, nil
+}
+
+func handleGetThread(ctx context.Context, req *mcp.CallToolRequest, input GetThreadInput) (
+	*mcp.CallToolResult,
+	ThreadOutput,
+	error,
+) {
+	thread := strings.TrimSpace(input.Thread)
+	if thread == "" {
+		return nil, ThreadOutput{}, fmt.Errorf("thread is required")
+	}
+
+	match := func(entry Entry) bool {
+		for _, t := range entryThreads(entry) {
+			if strings.EqualFold(t, thread) {
+				return true
+			}
+		}
+		return false
+	}
+	q := entryQuery{
+		filter: query.Filter[Entry]{Order: query.OldestFirst, Fields: input.Fields, Match: match},
+		shape: contentShape{
+			stripFrontmatter: input.StripFrontmatter,
+			previewChars:     input.PreviewChars,
+			omitContent:      input.OmitContent,
+			encoding:         input.ContentEncoding,
+		},
+		maxTokens: input.MaxTokens,
+		scope:     input.Scope,
+	}
+	result, err := findEntries(ctx, q)
+	if err != nil {
+		return nil, ThreadOutput{}, err
+	}
+
+	if input.Outline {
+		output := ThreadOutput{Thread: thread, EntriesOutput: EntriesOutput{Entries: []Entry{}, Count: len(result.Items)}}
+		output.Outlines = make([]EntryOutline, 0, len(result.Items))
+		for _, entry := range result.Items {
+			output.Outlines = append(output.Outlines, entryOutline(entry))
+		}
+		if result.Partial {
+			output.Partial, output.ScannedFiles, output.TotalFiles = true, result.Scanned, result.TotalFiles
+		}
+		return nil, output, nil
+	}
+
+	return nil, ThreadOutput{Thread: thread, EntriesOutput: entriesOutput(ctx, q, result)}, nil
+}
+
+// helpers
+
+// entryThreads returns the distinct thread:: values of an entry
+func entryThreads(entry Entry) []string {
+	var threads []string
+	seen := map[string]bool{}
+	for _, thread := range entry.InlineFields[threadField] {
+		thread = strings.Trim(thread, "[]\"' ")
+		if thread == "" || seen[strings.ToLower(thread)] {
+			continue
+		}
+		seen[strings.ToLower(thread)] = true
+		threads = append(threads, thread)
+	}
+	return threads
+}
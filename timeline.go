@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// headings that open a timestamped sub-entry, the first group captures HH:MM
+const defaultTimelinePattern = `^#{1,6}[ \t]+(\d{1,2}:\d{2})\b`
+
+var timelinePattern = getTimelinePattern()
+
+type GetTimelineInput struct {
+	Date  string `json:"date,omitempty" jsonschema:"Entry date in YYYY-MM-DD format to split into timestamped sub-entries"`
+	Start string `json:"start,omitempty" jsonschema:"First date of a range in YYYY-MM-DD format, returns sub-entry times per day instead of content"`
+	End   string `json:"end,omitempty" jsonschema:"Last date of a range in YYYY-MM-DD format"`
+}
+
+type TimelineBlock struct {
+	Time    string `json:"time,omitempty" jsonschema:"Time of the sub-entry in HH:MM, empty for text before the first time heading"`
+	Heading string `json:"heading,omitempty" jsonschema:"The full heading line that opened the sub-entry"`
+	Content string `json:"content" jsonschema:"Markdown content of the sub-entry without its heading"`
+	Words   int    `json:"words" jsonschema:"Word count of the sub-entry"`
+}
+
+type DayTimeline struct {
+	Date  string   `json:"date" jsonschema:"Entry date in YYYY-MM-DD format"`
+	Count int      `json:"count" jsonschema:"Number of timestamped sub-entries that day"`
+	Times []string `json:"times" jsonschema:"Times of the sub-entries in HH:MM, in order"`
+}
+
+type TimelineOutput struct {
+	Date   string          `json:"date,omitempty" jsonschema:"The requested date in single day mode"`
+	Blocks []TimelineBlock `json:"blocks,omitempty" jsonschema:"Sub-entries of the day in document order"`
+	Days   []DayTimeline   `json:"days,omitempty" jsonschema:"Sub-entry times per day in range mode, oldest first"`
+	ByHour []int           `json:"byHour,omitempty" jsonschema:"Number of sub-entries started in each hour of the day (index 0 to 23) in range mode"`
+}
+
+// handlers
+func handleGetTimeline(ctx context.Context, req *mcp.CallToolRequest, input GetTimelineInput) (
+	*mcp.CallToolResult,
+	TimelineOutput,
+	error,
+) {
+	if input.Date != "" {
+		if input.Start != "" || input.End != "" {
+			return nil, TimelineOutput{}, fmt.Errorf("pass either date or start/end, not both")
+		}
+		entry, err := getEntryByDate(input.Date)
+		if err != nil {
+			return nil, TimelineOutput{}, err
+		}
+		return nil, TimelineOutput{Date: entry.Date, Blocks: splitTimeline(entry.Content)}, nil
+	}
+
+	filter, err := rangeFilter(input.Start, input.End)
+	if err != nil {
+		return nil, TimelineOutput{}, err
+	}
+
+	output := TimelineOutput{Days: []DayTimeline{}, ByHour: make([]int, 24)}
+	err = eachEntry(ctx, filter, func(entry Entry) error {
+		day := DayTimeline{Date: entry.Date, Times: []string{}}
+		for _, block := range splitTimeline(entry.Content) {
+			if block.Time == "" {
+				continue
+			}
+			day.Count++
+			day.Times = append(day.Times, block.Time)
+			hour, _ := strconv.Atoi(strings.SplitN(block.Time, ":", 2)[0])
+			output.ByHour[hour]++
+		}
+		output.Days = append(output.Days, day)
+		return nil
+	})
+	if err != nil {
+		return nil, TimelineOutput{}, fmt.Errorf("failed to get entries: %w", err)
+	}
+
+	sort.Slice(output.Days, func(i, j int) bool { return output.Days[i].Date < output.Days[j].Date })
+
+	return nil, output, nil
+}
+
+// helpers
+func getTimelinePattern() *regexp.Regexp {
+	source := os.Getenv("THEMIS_TIMELINE_PATTERN")
+	if source == "" {
+		source = defaultTimelinePattern
+	}
+
+	pattern, err := regexp.Compile(source)
+	if err != nil {
+		log.Fatalf("invalid THEMIS_TIMELINE_PATTERN %q: %v", source, err)
+	}
+	if pattern.NumSubexp() < 1 {
+		log.Fatalf("THEMIS_TIMELINE_PATTERN %q must capture the time in a group", source)
+	}
+	return pattern
+}
+
+// splitTimeline cuts an entry body at time headings, skipping fenced code.
+// text before the first time heading, or the whole body when there is none, becomes an untimed block.
+func splitTimeline(content string) []TimelineBlock {
+	_, body, _ := splitFrontmatter(content)
+
+	var blocks []TimelineBlock
+	current := TimelineBlock{}
+	var lines []string
+	flush := func() {
+		current.Content = strings.TrimSpace(strings.Join(lines, "\n"))
+		current.Words = len(strings.Fields(current.Content))
+		if current.Time != "" || current.Content != "" {
+			blocks = append(blocks, current)
+		}
+	}
+
+	inFence := false
+	for _, line := range strings.Split(body, "\n") {
+		if isFenceLine(line) {
+			inFence = !inFence
+		}
+		if !inFence {
+			if match := timelinePattern.FindStringSubmatch(strings.TrimRight(line, "\r")); match != nil {
+				if clock, ok := parseClock(match[1]); ok {
+					flush()
+					current = TimelineBlock{Time: clock, Heading: strings.TrimSpace(line)}
+					lines = nil
+					continue
+				}
+			}
+		}
+		lines = append(lines, line)
+	}
+	flush()
+
+	return blocks
+}
+
+// parseClock normalises H:MM or HH:MM to HH:MM, rejecting impossible times
+func parseClock(s string) (string, bool) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return "", false
+	}
+	return t.Format("15:04"), true
+}
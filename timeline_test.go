@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func timelineVault(t *testing.T) {
+	t.Helper()
+	newTestVault(t, map[string]string{
+		"2024-03-10.md": "---\nmood: 3\n---\nwoke up\n## 7:30 breakfast\neggs and toast\n## 25:00 not a time\nstill breakfast\n```\n## 12:00 in code\n```\n### 21:15\nbed\n",
+		"2024-03-12.md": "# 09:05 standup\nshort\n",
+		"2024-03-14.md": "no times today\n",
+	})
+}
+
+func TestGetTimelineDay(t *testing.T) {
+	timelineVault(t)
+	ctx := context.Background()
+
+	// text before the first time heading is an untimed block, impossible times and headings in code don't split
+	_, output, err := handleGetTimeline(ctx, nil, GetTimelineInput{Date: "2024-03-10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TimelineBlock{
+		{Content: "woke up", Words: 2},
+		{Time: "07:30", Heading: "## 7:30 breakfast", Content: "eggs and toast\n## 25:00 not a time\nstill breakfast\n```\n## 12:00 in code\n```", Words: 16},
+		{Time: "21:15", Heading: "### 21:15", Content: "bed", Words: 1},
+	}
+	if output.Date != "2024-03-10" || !reflect.DeepEqual(output.Blocks, want) {
+		t.Errorf("blocks = %+v, want %+v", output.Blocks, want)
+	}
+
+	_, output, err = handleGetTimeline(ctx, nil, GetTimelineInput{Date: "2024-03-14"})
+	if err != nil || !reflect.DeepEqual(output.Blocks, []TimelineBlock{{Content: "no times today", Words: 3}}) {
+		t.Errorf("without time headings = %+v, %v", output.Blocks, err)
+	}
+
+	for _, input := range []GetTimelineInput{{Date: "2024-03-11"}, {Date: "2024-03-10", Start: "2024-03-01"}, {Start: "2024-03-12", End: "2024-03-10"}} {
+		if _, _, err := handleGetTimeline(ctx, nil, input); err == nil {
+			t.Errorf("%+v did not fail", input)
+		}
+	}
+}
+
+func TestGetTimelineRange(t *testing.T) {
+	timelineVault(t)
+	ctx := context.Background()
+
+	_, output, err := handleGetTimeline(ctx, nil, GetTimelineInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DayTimeline{
+		{Date: "2024-03-10", Count: 2, Times: []string{"07:30", "21:15"}},
+		{Date: "2024-03-12", Count: 1, Times: []string{"09:05"}},
+		{Date: "2024-03-14", Count: 0, Times: []string{}},
+	}
+	if !reflect.DeepEqual(output.Days, want) {
+		t.Errorf("days = %+v, want %+v", output.Days, want)
+	}
+	byHour := make([]int, 24)
+	byHour[7], byHour[9], byHour[21] = 1, 1, 1
+	if !reflect.DeepEqual(output.ByHour, byHour) {
+		t.Errorf("byHour = %v, want %v", output.ByHour, byHour)
+	}
+
+	_, output, err = handleGetTimeline(ctx, nil, GetTimelineInput{Start: "2024-03-11", End: "2024-03-13"})
+	if err != nil || len(output.Days) != 1 || output.Days[0].Date != "2024-03-12" || output.ByHour[7] != 0 {
+		t.Errorf("2024-03-11 to 13 = %+v, %v", output, err)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// layouts accepted for the created frontmatter field, a bare date carries no time of day and is skipped
+var createdLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04"}
+
+type GetTimeOfDayDistributionInput struct {
+	Start string `json:"start,omitempty" jsonschema:"First date to include in YYYY-MM-DD format"`
+	End   string `json:"end,omitempty" jsonschema:"Last date to include in YYYY-MM-DD format"`
+}
+
+type TimeOfDayDistributionOutput struct {
+	Hours   []int `json:"hours" jsonschema:"Entries created in each hour of the day, index 0 is midnight to 1am"`
+	Counted int   `json:"counted" jsonschema:"Entries with a parseable created timestamp"`
+	Skipped int   `json:"skipped" jsonschema:"Entries without a created timestamp or with one that has no time of day"`
+}
+
+// handlers
+func handleGetTimeOfDayDistribution(ctx context.Context, req *mcp.CallToolRequest, input GetTimeOfDayDistributionInput) (
+	*mcp.CallToolResult,
+	TimeOfDayDistributionOutput,
+	error,
+) {
+	filter, err := rangeFilter(input.Start, input.End)
+	if err != nil {
+		return nil, TimeOfDayDistributionOutput{}, err
+	}
+
+	output := TimeOfDayDistributionOutput{Hours: make([]int, 24)}
+	err = eachEntry(ctx, filter, func(entry Entry) error {
+		created, ok := parseCreated(entry.Frontmatter["created"])
+		if !ok {
+			output.Skipped++
+			return nil
+		}
+		output.Hours[created.Hour()]++
+		output.Counted++
+		return nil
+	})
+	if err != nil {
+		return nil, TimeOfDayDistributionOutput{}, fmt.Errorf("failed to get entries: %w", err)
+	}
+
+	return nil, output, nil
+}
+
+// helpers
+
+// parseCreated reads a created timestamp, keeping the clock time as written rather than converting timezones
+func parseCreated(value any) (time.Time, bool) {
+	s, ok := value.(string)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	s = strings.TrimSpace(s)
+	for _, layout := range createdLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
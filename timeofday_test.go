@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGetTimeOfDayDistribution(t *testing.T) {
+	newTestVault(t, map[string]string{
+		"2024-03-10.md": "---\ncreated: 2024-03-10T08:15:00\n---\nmorning\n",
+		"2024-03-11.md": "---\ncreated: \"2024-03-11 08:45\"\n---\nmorning again\n",
+		"2024-03-12.md": "---\ncreated: 2024-03-12T23:05:00+01:00\n---\nlate\n",
+		"2024-03-13.md": "---\ncreated: 2024-03-13\n---\ndate only\n",
+		"2024-03-14.md": "no frontmatter\n",
+		"2024-03-15.md": "---\ncreated: \"sometime\"\n---\nunparseable\n",
+	})
+
+	_, output, err := handleGetTimeOfDayDistribution(context.Background(), nil, GetTimeOfDayDistributionInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(output.Hours) != 24 {
+		t.Fatalf("got %d hour buckets, want 24", len(output.Hours))
+	}
+	for hour, count := range output.Hours {
+		want := map[int]int{8: 2, 23: 1}[hour]
+		if count != want {
+			t.Errorf("hour %d = %d, want %d", hour, count, want)
+		}
+	}
+	if output.Counted != 3 || output.Skipped != 3 {
+		t.Errorf("counted %d and skipped %d, want 3 and 3", output.Counted, output.Skipped)
+	}
+}
+
+func TestGetTimeOfDayDistributionRange(t *testing.T) {
+	newTestVault(t, map[string]string{
+		"2024-03-10.md": "---\ncreated: 2024-03-10T08:15:00\n---\n",
+		"2024-03-11.md": "---\ncreated: 2024-03-11T21:00:00\n---\n",
+	})
+
+	_, output, err := handleGetTimeOfDayDistribution(context.Background(), nil, GetTimeOfDayDistributionInput{Start: "2024-03-11"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Counted != 1 || output.Hours[21] != 1 || output.Hours[8] != 0 {
+		t.Errorf("output = %+v, want only the 21:00 entry", output)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"time"
+)
+
+const defaultToolTimeoutMs = 10000
+
+// toolTimeout bounds how long a walking tool runs before returning what it has, THEMIS_TOOL_TIMEOUT_MS=0 disables it
+var toolTimeout = time.Duration(getEnvInt("THEMIS_TOOL_TIMEOUT_MS", defaultToolTimeoutMs)) * time.Millisecond
+
+type walkProgress struct {
+	Scanned int  // entry files passed to the callback
+	Partial bool // the walk stopped early because its context was done
+}
+
+// toolBudget is the time budget of one call, timeoutMs overrides the configured one. the engine layers it over
+// the request context, so a client cancelling still stops the walk. negative means no budget.
+func toolBudget(timeoutMs int) time.Duration {
+	if timeoutMs > 0 {
+		return time.Duration(timeoutMs) * time.Millisecond
+	}
+	if toolTimeout <= 0 {
+		return -1
+	}
+	return toolTimeout
+}
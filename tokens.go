@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"unicode/utf8"
+)
+
+// Tokenizer estimates how many model tokens a piece of text will cost
+type Tokenizer interface {
+	CountTokens(text string) int
+}
+
+// charTokenizer is the default heuristic of roughly four characters per token
+type charTokenizer struct{}
+
+func (charTokenizer) CountTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// tokenizer can be swapped for a real model tokenizer, every estimate goes through estimateTokens
+var tokenizer Tokenizer = charTokenizer{}
+
+func estimateTokens(text string) int {
+	return tokenizer.CountTokens(text)
+}
+
+// newEntriesOutput keeps entries in the order given and, when maxTokens > 0, drops the oldest ones that don't fit the
+// budget. kept entries are trimmed to the selected fields and responses over the size limit are chunked, see
+// chunkEntriesOutput. the output is partial when ctx ran out before length percentiles could be set.
+func newEntriesOutput(ctx context.Context, entries []Entry, maxTokens int, fields map[string]bool) EntriesOutput {
+	var output EntriesOutput
+	dropped := map[int]bool{}
+	if maxTokens > 0 {
+		// the budget goes to the newest entries whatever order they are returned in, and once an entry doesn't
+		// fit every older one is dropped too so the result stays a contiguous window
+		byAge := make([]int, len(entries))
+		for i := range byAge {
+			byAge[i] = i
+		}
+		sort.SliceStable(byAge, func(a, b int) bool {
+			x, y := entries[byAge[a]], entries[byAge[b]]
+			if x.Date != y.Date {
+				return x.Date > y.Date
+			}
+			return x.FilePath < y.FilePath
+		})
+
+		total := 0
+		for _, i := range byAge {
+			if len(output.Dropped) > 0 || total+entries[i].EstimatedTokens > maxTokens {
+				dropped[i] = true
+				output.Dropped = append(output.Dropped, entries[i].Date)
+				continue
+			}
+			total += entries[i].EstimatedTokens
+		}
+	}
+
+	kept := make([]Entry, 0, len(entries))
+	for i, entry := range entries {
+		if !dropped[i] {
+			kept = append(kept, entry)
+			output.TotalEstimatedTokens += entry.EstimatedTokens
+		}
+	}
+
+	files := stampFiles(kept)
+	if fields == nil || fields["percentile"] {
+		output.Partial = !applyLengthStats(ctx, kept)
+	}
+	selectFields(kept, fields)
+	return chunkEntriesOutput(output, kept, files)
+}
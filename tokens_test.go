@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"a", 1},
+		{"abcd", 1},
+		{"abcde", 2},
+		{"went for a run", 4},
+		{"größe", 2}, // counted in runes, not bytes
+		{strings.Repeat("x", 400), 100},
+	}
+	for _, tt := range tests {
+		if got := estimateTokens(tt.text); got != tt.want {
+			t.Errorf("estimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+// tokenVault holds entries of 100 estimated tokens each
+func tokenVault(t *testing.T) {
+	t.Helper()
+	newTestVault(t, map[string]string{
+		"2024-03-12.md": strings.Repeat("a", 400),
+		"2024-03-13.md": strings.Repeat("b", 400),
+		"2024-03-14.md": strings.Repeat("c", 400),
+		"2024-03-15.md": strings.Repeat("d", 400),
+	})
+}
+
+func TestMaxTokensDropsOldestEntries(t *testing.T) {
+	tokenVault(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		run     func() (EntriesOutput, error)
+		want    []string
+		dropped []string
+	}{
+		{
+			name: "by year",
+			run: func() (EntriesOutput, error) {
+				_, output, err := handleGetEntriesByYear(ctx, nil, GetEntriesByYearInput{Year: 2024, MaxTokens: 250})
+				return output, err
+			},
+			want:    []string{"2024-03-15", "2024-03-14"},
+			dropped: []string{"2024-03-13", "2024-03-12"},
+		},
+		{
+			name: "by dates keeps request order for what fits",
+			run: func() (EntriesOutput, error) {
+				_, output, err := handleGetEntriesByDates(ctx, nil, GetEntriesByDatesInput{Dates: []string{"2024-03-14", "2024-03-12", "2024-03-15"}, MaxTokens: 200})
+				return output.EntriesOutput, err
+			},
+			want:    []string{"2024-03-14", "2024-03-15"},
+			dropped: []string{"2024-03-12"},
+		},
+		{
+			name: "neighborhood keeps oldest first for what fits",
+			run: func() (EntriesOutput, error) {
+				_, output, err := handleGetEntryNeighborhood(ctx, nil, GetEntryNeighborhoodInput{Date: "2024-03-13", Before: 1, After: 2, MaxTokens: 300})
+				return output.EntriesOutput, err
+			},
+			want:    []string{"2024-03-13", "2024-03-14", "2024-03-15"},
+			dropped: []string{"2024-03-12"},
+		},
+		{
+			name: "a budget every entry fits in drops nothing",
+			run: func() (EntriesOutput, error) {
+				_, output, err := handleGetEntriesByYear(ctx, nil, GetEntriesByYearInput{Year: 2024, MaxTokens: 1000})
+				return output, err
+			},
+			want: []string{"2024-03-15", "2024-03-14", "2024-03-13", "2024-03-12"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := tt.run()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := entryDates(output.Entries); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("dates = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(output.Dropped, tt.dropped) {
+				t.Errorf("dropped = %v, want %v", output.Dropped, tt.dropped)
+			}
+			if output.TotalEstimatedTokens != 100*len(tt.want) {
+				t.Errorf("total estimated tokens = %d, want %d", output.TotalEstimatedTokens, 100*len(tt.want))
+			}
+		})
+	}
+}
+
+func TestGetPinnedMaxTokens(t *testing.T) {
+	tokenVault(t)
+	writeTestFile(t, filepath.Join(themisPath, ".themis", "pins.json"), `{"pins":[{"date":"2024-03-12"},{"date":"2024-03-15"}]}`)
+
+	_, output, err := handleGetPinned(context.Background(), nil, GetPinnedInput{MaxTokens: 150})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := entryDates(output.Entries); !reflect.DeepEqual(got, []string{"2024-03-15"}) {
+		t.Errorf("dates = %v", got)
+	}
+	if len(output.Pins) != 2 || len(output.Missing) != 0 {
+		t.Errorf("pins = %v and missing = %v, want both pins and nothing missing", output.Pins, output.Missing)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/curator4/mcp-server-diary/query"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type ValidateVaultInput struct{}
+
+type ConflictReport struct {
+	Date      string   `json:"date" jsonschema:"Entry date in YYYY-MM-DD format"`
+	MainPath  string   `json:"mainPath" jsonschema:"Vault relative path of the main entry"`
+	Conflicts []string `json:"conflicts" jsonschema:"Vault relative paths of the conflict copies"`
+}
+
+type DuplicateDate struct {
+	Date  string   `json:"date" jsonschema:"Date claimed by more than one file"`
+	Paths []string `json:"paths" jsonschema:"Vault relative paths of the files with that date"`
+}
+
+type EntryProblem struct {
+	Date    string `json:"date" jsonschema:"Entry date in YYYY-MM-DD format"`
+	Path    string `json:"path" jsonschema:"Vault relative path of the entry"`
+	Problem string `json:"problem" jsonschema:"What is wrong"`
+}
+
+type BrokenAttachment struct {
+	Date string `json:"date" jsonschema:"Entry date in YYYY-MM-DD format"`
+	Ref  string `json:"ref" jsonschema:"Reference as written in the entry"`
+	Path string `json:"path" jsonschema:"Where the reference was expected to resolve, relative to the vault"`
+}
+
+type ValidateVaultOutput struct {
+	SkippedNames      []string           `json:"skippedNames" jsonschema:"Markdown files ignored because their name is not a YYYY-MM-DD date"`
+	DuplicateDates    []DuplicateDate    `json:"duplicateDates" jsonschema:"Dates with more than one entry file"`
+	FrontmatterErrors []EntryProblem     `json:"frontmatterErrors" jsonschema:"Entries whose frontmatter is not valid yaml"`
+	DateMismatches    []EntryProblem     `json:"dateMismatches" jsonschema:"Entries whose frontmatter date disagrees with the filename"`
+	BrokenAttachments []BrokenAttachment `json:"brokenAttachments" jsonschema:"Embedded media pointing at files that do not exist"`
+	Conflicts         []ConflictReport   `json:"conflicts" jsonschema:"Entries with sync conflict copies next to them"`
+	DanglingPins      []Pin              `json:"danglingPins" jsonschema:"Pins whose entry file no longer exists"`
+	StaleTempFiles    []TempFile         `json:"staleTempFiles" jsonschema:"Temporary, partial or backup files older than an hour, likely left behind by an editor or sync tool"`
+	Problems          int                `json:"problems" jsonschema:"Total number of problems found"`
+}
+
+// handlers
+func handleValidateVault(ctx context.Context, req *mcp.CallToolRequest, input ValidateVaultInput) (
+	*mcp.CallToolResult,
+	ValidateVaultOutput,
+	error,
+) {
+	output := ValidateVaultOutput{
+		SkippedNames:      skippedEntryNames(),
+		DuplicateDates:    []DuplicateDate{},
+		FrontmatterErrors: []EntryProblem{},
+		DateMismatches:    []EntryProblem{},
+		BrokenAttachments: []BrokenAttachment{},
+		Conflicts:         []ConflictReport{},
+		DanglingPins:      []Pin{},
+		StaleTempFiles:    staleTempFiles(),
+	}
+
+	resolver := newAttachmentResolver()
+	pathsByDate := map[string][]string{}
+	err := eachEntry(ctx, query.Filter[Entry]{}, func(entry Entry) error {
+		rel := vaultRelative(entry.FilePath)
+		pathsByDate[entry.Date] = append(pathsByDate[entry.Date], rel)
+
+		if len(entry.Conflicts) > 0 {
+			output.Conflicts = append(output.Conflicts, ConflictReport{Date: entry.Date, MainPath: rel, Conflicts: entry.Conflicts})
+		}
+		if entry.Locked {
+			return nil
+		}
+
+		if _, _, err := parseFrontmatter(entry.Content); err != nil {
+			output.FrontmatterErrors = append(output.FrontmatterErrors, EntryProblem{Date: entry.Date, Path: rel, Problem: err.Error()})
+		} else if mismatch := checkDateConsistency(entry.Date, entry.Frontmatter); mismatch != "" {
+			output.DateMismatches = append(output.DateMismatches, EntryProblem{Date: entry.Date, Path: rel, Problem: mismatch})
+		}
+
+		for _, attachment := range resolver.resolveAll(entry) {
+			if !attachment.Exists {
+				output.BrokenAttachments = append(output.BrokenAttachments, BrokenAttachment{Date: entry.Date, Ref: attachment.Ref, Path: attachment.Path})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, ValidateVaultOutput{}, fmt.Errorf("failed to get entries: %w", err)
+	}
+
+	for date, paths := range pathsByDate {
+		if len(paths) > 1 {
+			sort.Strings(paths)
+			output.DuplicateDates = append(output.DuplicateDates, DuplicateDate{Date: date, Paths: paths})
+		}
+	}
+	sort.Slice(output.DuplicateDates, func(i, j int) bool { return output.DuplicateDates[i].Date < output.DuplicateDates[j].Date })
+
+	pinsMu.Lock()
+	pins, err := loadPins()
+	pinsMu.Unlock()
+	if err != nil {
+		return nil, ValidateVaultOutput{}, err
+	}
+	for _, pin := range pins {
+		if len(pathsByDate[pin.Date]) == 0 {
+			output.DanglingPins = append(output.DanglingPins, pin)
+		}
+	}
+
+	output.Problems = len(output.SkippedNames) + len(output.DuplicateDates) + len(output.FrontmatterErrors) +
+		len(output.DateMismatches) + len(output.BrokenAttachments) + len(output.DanglingPins) + len(output.StaleTempFiles)
+	for _, conflict := range output.Conflicts {
+		output.Problems += len(conflict.Conflicts)
+	}
+
+	return nil, output, nil
+}
+
+// helpers
+
+// skippedEntryNames lists markdown files walkEntries ignores for their name, leaving out dot directories,
+// conflict copies (reported separately), temp files and the configured templates
+func skippedEntryNames() []string {
+	isTemplate := map[string]bool{}
+	for _, template := range templates {
+		isTemplate[filepath.ToSlash(filepath.Clean(template))] = true
+	}
+
+	skipped := []string{}
+	filepath.WalkDir(themisPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != themisPath && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(d.Name(), ".md") && !strings.HasSuffix(d.Name(), encryptedSuffix) {
+			return nil
+		}
+		if conflictPattern.MatchString(d.Name()) || isTemplate[vaultRelative(path)] || isTempFile(d.Name()) {
+			return nil
+		}
+
+		dateStr := strings.TrimSuffix(strings.TrimSuffix(d.Name(), ".age"), ".md")
+		if _, err := time.Parse("2006-01-02", dateStr); err != nil {
+			skipped = append(skipped, vaultRelative(path))
+		}
+		return nil
+	})
+	return skipped
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateVault(t *testing.T) {
+	dir := newTestVault(t, map[string]string{
+		"2024-03-14.md":      "---\ndate: 2024-03-14\n---\n# Thursday\n![[pier.png]]\n",
+		"2024-03-15.md":      "# Friday\n![[lost.jpg]]\n",
+		"2024/2024-03-15.md": "# Friday again\n",
+		"2024-03-16.md":      "---\ndate: 2024-03-17\n---\n# Saturday\n",
+		"2024-03-18.md":      "---\ntags: [unclosed\n---\n# Monday\n",
+		"2024-03-14.sync-conflict-20240315-101010.md": "# Thursday from the phone\n",
+		"march notes.md":         "not an entry\n",
+		"media/pier.png":         "png",
+		".obsidian/workspace.md": "ignored\n",
+		".2024-03-14.md.tmp-99":  "half written",
+		"2024-03-19.md~":         "fresh backup",
+	})
+	// only temp files older than an hour count as left behind
+	touch(t, filepath.Join(dir, ".2024-03-14.md.tmp-99"), time.Now().Add(-2*time.Hour))
+
+	_, output, err := handleValidateVault(context.Background(), nil, ValidateVaultInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(output.SkippedNames, []string{"march notes.md"}) {
+		t.Errorf("skipped names = %v", output.SkippedNames)
+	}
+	if want := []DuplicateDate{{Date: "2024-03-15", Paths: []string{"2024-03-15.md", "2024/2024-03-15.md"}}}; !reflect.DeepEqual(output.DuplicateDates, want) {
+		t.Errorf("duplicate dates = %+v", output.DuplicateDates)
+	}
+	if len(output.FrontmatterErrors) != 1 || output.FrontmatterErrors[0].Path != "2024-03-18.md" {
+		t.Errorf("frontmatter errors = %+v", output.FrontmatterErrors)
+	}
+	if len(output.DateMismatches) != 1 || output.DateMismatches[0].Path != "2024-03-16.md" || !strings.Contains(output.DateMismatches[0].Problem, `"2024-03-17"`) {
+		t.Errorf("date mismatches = %+v", output.DateMismatches)
+	}
+	if want := []BrokenAttachment{{Date: "2024-03-15", Ref: "lost.jpg", Path: "lost.jpg"}}; !reflect.DeepEqual(output.BrokenAttachments, want) {
+		t.Errorf("broken attachments = %+v", output.BrokenAttachments)
+	}
+	if len(output.Conflicts) != 1 || output.Conflicts[0].MainPath != "2024-03-14.md" || len(output.Conflicts[0].Conflicts) != 1 {
+		t.Errorf("conflicts = %+v", output.Conflicts)
+	}
+	if len(output.StaleTempFiles) != 1 || output.StaleTempFiles[0].Path != ".2024-03-14.md.tmp-99" {
+		t.Errorf("stale temp files = %+v", output.StaleTempFiles)
+	}
+	if output.Problems != 7 {
+		t.Errorf("problems = %d, want 7", output.Problems)
+	}
+}
+
+func TestValidateVaultClean(t *testing.T) {
+	newTestVault(t, map[string]string{"2024-03-14.md": "---\ndate: 2024-03-14\n---\n# Thursday\n"})
+
+	_, output, err := handleValidateVault(context.Background(), nil, ValidateVaultInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Problems != 0 || output.SkippedNames == nil || output.DuplicateDates == nil || output.StaleTempFiles == nil {
+		t.Errorf("output = %+v, want no problems and empty lists", output)
+	}
+}
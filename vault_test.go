@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// chmod changes path's mode for the rest of the test, skipping it when the mode doesn't keep this user out,
+// as for root
+func chmod(t *testing.T, path string, mode os.FileMode) {
+	t.Helper()
+	if err := os.Chmod(path, mode); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(path, 0o755) })
+	if _, err := os.ReadDir(path); err == nil {
+		t.Skip("permissions are not enforced for this user")
+	}
+}
+
+func TestVaultPathProblems(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, dir string) string
+		problem string
+		hint    string
+	}{
+		{
+			name: "file",
+			setup: func(t *testing.T, dir string) string {
+				path := filepath.Join(dir, "2024-03-15.md")
+				writeTestFile(t, path, "# Friday\n")
+				return path
+			},
+			problem: "is a file, expected a directory",
+			hint:    "at the folder holding your entries",
+		},
+		{
+			name: "unlistable directory",
+			setup: func(t *testing.T, dir string) string {
+				vault := filepath.Join(dir, "vault")
+				writeTestFile(t, filepath.Join(vault, "2024-03-15.md"), "# Friday\n")
+				chmod(t, vault, 0o311)
+				return vault
+			},
+			problem: "is not readable, give the server user read permission on it",
+			hint:    "read permission",
+		},
+		{
+			name: "inside a locked folder",
+			setup: func(t *testing.T, dir string) string {
+				locked := filepath.Join(dir, "locked")
+				writeTestFile(t, filepath.Join(locked, "vault", "2024-03-15.md"), "# Friday\n")
+				chmod(t, locked, 0o000)
+				return filepath.Join(locked, "vault")
+			},
+			problem: "is not readable, give the server user read permission on it",
+			hint:    "permission to reach the vault through its parent folders",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := newTestVault(t, nil)
+			themisPath = tt.setup(t, dir)
+			entryRoot = themisPath
+
+			_, _, err := handleGetEntriesByYear(context.Background(), nil, GetEntriesByYearInput{Year: 2024})
+			if err == nil || !strings.Contains(err.Error(), themisPath+" "+tt.problem) {
+				t.Errorf("err = %v, want %q", err, tt.problem)
+			}
+
+			output := diagnose(false)
+			if vault := output.Checks[0]; output.OK || vault.Name != "vault" || vault.Status != "fail" || !strings.Contains(vault.Hint, tt.hint) {
+				t.Errorf("diagnose = %+v, want a failed vault check hinting %q", output, tt.hint)
+			}
+		})
+	}
+}
+
+func TestMissingVaultIsEmpty(t *testing.T) {
+	dir := newTestVault(t, nil)
+	themisPath = filepath.Join(dir, "not-created-yet")
+	entryRoot = themisPath
+
+	_, output, err := handleGetEntriesByYear(context.Background(), nil, GetEntriesByYearInput{Year: 2024})
+	if err != nil || output.Count != 0 {
+		t.Errorf("missing vault = %+v, %v, want no entries", output, err)
+	}
+	if err := checkEntryRoot(); err != nil {
+		t.Errorf("checkEntryRoot: %v", err)
+	}
+}
+
+func TestDiagnoseWritesOnlyWhenProbing(t *testing.T) {
+	dir := newTestVault(t, map[string]string{"2024-03-15.md": "# Friday\n"})
+	// creating and removing the probe file moves the vault directory's mtime, nothing else would
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	touch(t, dir, old)
+	modTime := func() time.Time {
+		info, err := os.Stat(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info.ModTime()
+	}
+
+	tests := []struct {
+		probe  bool
+		status string
+		wrote  bool
+	}{
+		{false, "skip", false},
+		{true, "pass", true},
+	}
+	for _, tt := range tests {
+		var write DiagnosticCheck
+		for _, check := range diagnose(tt.probe).Checks {
+			if check.Name == "write" {
+				write = check
+			}
+		}
+		if write.Status != tt.status {
+			t.Errorf("probe %v: write check = %+v, want %s", tt.probe, write, tt.status)
+		}
+		if wrote := !modTime().Equal(old); wrote != tt.wrote {
+			t.Errorf("probe %v: vault written = %v, want %v", tt.probe, wrote, tt.wrote)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+var commitPattern = regexp.MustCompile(`^[0-9a-fA-F]{4,64}$`)
+
+type GetEntryVersionsInput struct {
+	Date string `json:"date" jsonschema:"Entry date in YYYY-MM-DD format"`
+}
+
+type EntryVersion struct {
+	Commit    string `json:"commit" jsonschema:"Full commit hash"`
+	Timestamp string `json:"timestamp" jsonschema:"Author date of the commit, RFC 3339"`
+	Message   string `json:"message" jsonschema:"Subject line of the commit message"`
+
+	path string // path of the file at that commit, relative to the repository root
+}
+
+type EntryVersionsOutput struct {
+	Date     string         `json:"date" jsonschema:"Entry date in YYYY-MM-DD format"`
+	Versions []EntryVersion `json:"versions" jsonschema:"Commits touching the entry, newest first, empty when the vault is not a git repository"`
+}
+
+type GetEntryAtCommitInput struct {
+	Date   string `json:"date" jsonschema:"Entry date in YYYY-MM-DD format"`
+	Commit string `json:"commit" jsonschema:"Commit hash from getEntryVersions, abbreviated hashes work too"`
+}
+
+type EntryAtCommitOutput struct {
+	Date    string `json:"date" jsonschema:"Entry date in YYYY-MM-DD format"`
+	Commit  string `json:"commit" jsonschema:"Full commit hash"`
+	Content string `json:"content" jsonschema:"Content of the entry as of that commit"`
+}
+
+// handlers
+func handleGetEntryVersions(ctx context.Context, req *mcp.CallToolRequest, input GetEntryVersionsInput) (
+	*mcp.CallToolResult,
+	EntryVersionsOutput,
+	error,
+) {
+	entry, err := getEntryByDate(input.Date)
+	if err != nil {
+		return nil, EntryVersionsOutput{}, err
+	}
+
+	versions, err := entryVersions(ctx, entry.FilePath)
+	if err != nil {
+		return nil, EntryVersionsOutput{}, err
+	}
+	return nil, EntryVersionsOutput{Date: entry.Date, Versions: versions}, nil
+}
+
+func handleGetEntryAtCommit(ctx context.Context, req *mcp.CallToolRequest, input GetEntryAtCommitInput) (
+	*mcp.CallToolResult,
+	EntryAtCommitOutput,
+	error,
+) {
+	if !commitPattern.MatchString(input.Commit) {
+		return nil, EntryAtCommitOutput{}, fmt.Errorf("invalid commit %q, expected a hex commit hash", input.Commit)
+	}
+	entry, err := getEntryByDate(input.Date)
+	if err != nil {
+		return nil, EntryAtCommitOutput{}, err
+	}
+
+	versions, err := entryVersions(ctx, entry.FilePath)
+	if err != nil {
+		return nil, EntryAtCommitOutput{}, err
+	}
+	for _, version := range versions {
+		if !strings.HasPrefix(version.Commit, strings.ToLower(input.Commit)) {
+			continue
+		}
+
+		dir := filepath.Dir(entry.FilePath)
+		content, err := git(ctx, dir, "show", version.Commit+":"+version.path)
+		if err != nil {
+			return nil, EntryAtCommitOutput{}, fmt.Errorf("failed to read %s at %s: %w", version.path, version.Commit, err)
+		}
+		text, _ := decodeContent([]byte(content))
+		return nil, EntryAtCommitOutput{Date: entry.Date, Commit: version.Commit, Content: text}, nil
+	}
+
+	return nil, EntryAtCommitOutput{}, fmt.Errorf("commit %s does not touch the entry for %s", input.Commit, input.Date)
+}
+
+// helpers
+
+// entryVersions lists the commits touching path across renames, an empty list when git or the repository is missing
+func entryVersions(ctx context.Context, path string) ([]EntryVersion, error) {
+	versions := []EntryVersion{}
+	dir := filepath.Dir(path)
+	if _, err := git(ctx, dir, "rev-parse", "--git-dir"); err != nil {
+		return versions, nil
+	}
+
+	// each commit starts with a record separator, followed by the path it had then
+	out, err := git(ctx, dir, "log", "--follow", "--name-only", "--format=%x1e%H%x1f%aI%x1f%s", "--", filepath.Base(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read git history: %w", err)
+	}
+
+	for _, record := range strings.Split(out, "\x1e") {
+		header, names, _ := strings.Cut(record, "\n")
+		fields := strings.SplitN(header, "\x1f", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		version := EntryVersion{Commit: fields[0], Timestamp: fields[1], Message: fields[2]}
+		for _, name := range strings.Split(names, "\n") {
+			if name = strings.TrimSpace(name); name != "" {
+				version.path = name
+			}
+		}
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	return string(out), nil
+}
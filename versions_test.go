@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// gitVault returns the vault as a git repository whose 2024-03-15 entry was moved into a folder and edited
+func gitVault(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := newTestVault(t, map[string]string{"2024-03-15.md": "# Friday\nfirst draft\n"})
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+
+	run := func(args ...string) {
+		t.Helper()
+		if out, err := git(context.Background(), dir, args...); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	run("init", "-q")
+	run("add", ".")
+	run("commit", "-q", "-m", "first draft")
+	if err := os.MkdirAll(filepath.Join(dir, "2024"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	run("mv", "2024-03-15.md", "2024/2024-03-15.md")
+	writeTestFile(t, filepath.Join(dir, "2024", "2024-03-15.md"), "# Friday\nfirst draft\nand a second thought\n")
+	run("commit", "-q", "-am", "move into the year folder")
+	return dir
+}
+
+func TestEntryVersionsFollowRenames(t *testing.T) {
+	gitVault(t)
+	ctx := context.Background()
+
+	_, output, err := handleGetEntryVersions(ctx, nil, GetEntryVersionsInput{Date: "2024-03-15"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(output.Versions) != 2 || output.Versions[0].Message != "move into the year folder" || output.Versions[1].Message != "first draft" {
+		t.Fatalf("versions = %+v", output.Versions)
+	}
+
+	// the older commit is read from the path the entry had then, abbreviated hashes work
+	first := output.Versions[1].Commit
+	_, old, err := handleGetEntryAtCommit(ctx, nil, GetEntryAtCommitInput{Date: "2024-03-15", Commit: first[:8]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old.Commit != first || old.Content != "# Friday\nfirst draft\n" {
+		t.Errorf("at %s = %+v", first[:8], old)
+	}
+
+	for _, commit := range []string{"HEAD~1", "--output=x", "0000000"} {
+		if _, _, err := handleGetEntryAtCommit(ctx, nil, GetEntryAtCommitInput{Date: "2024-03-15", Commit: commit}); err == nil {
+			t.Errorf("commit %q: want an error", commit)
+		}
+	}
+}
+
+func TestEntryVersionsWithoutRepository(t *testing.T) {
+	newTestVault(t, map[string]string{"2024-03-15.md": "# Friday\n"})
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(themisPath))
+
+	_, output, err := handleGetEntryVersions(context.Background(), nil, GetEntryVersionsInput{Date: "2024-03-15"})
+	if err != nil || output.Versions == nil || len(output.Versions) != 0 {
+		t.Errorf("versions = %v, %v, want an empty list", output.Versions, err)
+	}
+}
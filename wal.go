@@ -0,0 +1,251 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// walDir holds one record per mutation in flight. a record is written and synced before the file is touched
+// and deleted once the mutation and its bookkeeping are done, so the directory only ever holds interrupted work.
+const walDir = ".themis/wal"
+
+type walRecord struct {
+	ID      string    `json:"id"`
+	Op      string    `json:"op"`               // write, create or remove
+	Path    string    `json:"path"`             // vault relative
+	SHA256  string    `json:"sha256,omitempty"` // of the content being written
+	Started time.Time `json:"started"`
+}
+
+// walFindings are problems found while replaying interrupted records at startup, reported by diagnose
+var walFindings struct {
+	mu       sync.Mutex
+	checked  bool
+	findings []string
+}
+
+// journal records the mutation, applies it and clears the record again. data is nil for removals.
+func journal(op, path string, data []byte, apply func() error) error {
+	record := walRecord{Op: op, Path: vaultRelative(path), Started: now()}
+	if data != nil {
+		sum := sha256.Sum256(data)
+		record.SHA256 = hex.EncodeToString(sum[:])
+	}
+
+	recordPath, err := writeWALRecord(&record)
+	if err != nil {
+		return fmt.Errorf("failed to journal %s of %s: %w", op, record.Path, err)
+	}
+
+	err = apply()
+	// a failed apply leaves the file as it was, either way there is nothing left to recover
+	if removeErr := os.Remove(recordPath); removeErr != nil {
+		log.Printf("failed to clear journal record %s: %v", recordPath, removeErr)
+	}
+	return err
+}
+
+func writeWALRecord(record *walRecord) (string, error) {
+	dir, err := resolveInVault(walDir)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	record.ID = record.Started.UTC().Format("20060102T150405") + "-" + hex.EncodeToString(buf)
+	data, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, record.ID+".json")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return "", err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(path)
+		return "", err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(path)
+		return "", err
+	}
+	return path, file.Close()
+}
+
+// recoverWAL replays the records of mutations interrupted by a crash: it checks what actually landed on disk,
+// drops stale cache, index and word count state and leftover temp files, and keeps a finding for anything
+// that did not complete
+func recoverWAL() {
+	walFindings.mu.Lock()
+	defer walFindings.mu.Unlock()
+	walFindings.checked = true
+
+	dir, err := resolveInVault(walDir)
+	if err != nil {
+		return
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			walFindings.findings = append(walFindings.findings, fmt.Sprintf("failed to read %s: %v", walDir, err))
+		}
+		return
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+	for _, file := range files {
+		recordPath := filepath.Join(dir, file.Name())
+		if finding := replayWALRecord(recordPath); finding != "" {
+			log.Printf("journal: %s", finding)
+			walFindings.findings = append(walFindings.findings, finding)
+		}
+		os.Remove(recordPath)
+	}
+}
+
+func replayWALRecord(recordPath string) string {
+	record, ok := readWALRecord(recordPath)
+	if !ok {
+		// a record torn by the crash means the mutation never started
+		return ""
+	}
+
+	path, err := resolveInVault(filepath.FromSlash(record.Path))
+	if err != nil {
+		return fmt.Sprintf("record %s points outside the vault: %s", record.ID, record.Path)
+	}
+	cache.invalidate(path)
+	vaultIndex.forget(path)
+	lengths.forget(path)
+	removeTempFiles(path)
+	return checkWALRecord(record, path)
+}
+
+func readWALRecord(recordPath string) (walRecord, bool) {
+	data, err := os.ReadFile(recordPath)
+	var record walRecord
+	if err == nil {
+		err = json.Unmarshal(data, &record)
+	}
+	return record, err == nil
+}
+
+// checkWALRecord compares an interrupted record with what is on disk at path, returning a finding when the mutation
+// did not complete
+func checkWALRecord(record walRecord, path string) string {
+	current, readErr := os.ReadFile(path)
+	switch record.Op {
+	case "write", "create":
+		if readErr != nil {
+			return fmt.Sprintf("%s of %s at %s was interrupted and the file is missing", record.Op, record.Path, record.Started.Format(time.RFC3339))
+		}
+		sum := sha256.Sum256(current)
+		if hex.EncodeToString(sum[:]) != record.SHA256 {
+			return fmt.Sprintf("%s of %s at %s was interrupted before it landed, the file still has other content", record.Op, record.Path, record.Started.Format(time.RFC3339))
+		}
+	case "remove":
+		if readErr == nil {
+			return fmt.Sprintf("removal of %s at %s was interrupted, the file is still there", record.Path, record.Started.Format(time.RFC3339))
+		}
+	default:
+		return fmt.Sprintf("record %s has unknown operation %q", record.ID, record.Op)
+	}
+	return ""
+}
+
+// pendingWAL describes the records left in the journal and the temp files next to their files without touching
+// anything, for the doctor to show what recoverWAL would replay
+func pendingWAL() ([]string, error) {
+	dir, err := resolveInVault(walDir)
+	if err != nil {
+		return nil, err
+	}
+	files, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+	var pending []string
+	for _, file := range files {
+		record, ok := readWALRecord(filepath.Join(dir, file.Name()))
+		if !ok {
+			pending = append(pending, fmt.Sprintf("torn record %s, its mutation never started", file.Name()))
+			continue
+		}
+		path, err := resolveInVault(filepath.FromSlash(record.Path))
+		if err != nil {
+			pending = append(pending, fmt.Sprintf("record %s points outside the vault: %s", record.ID, record.Path))
+			continue
+		}
+
+		finding := checkWALRecord(record, path)
+		if finding == "" {
+			finding = fmt.Sprintf("%s of %s at %s landed but its record was not cleared", record.Op, record.Path, record.Started.Format(time.RFC3339))
+		}
+		if temps := tempFilesFor(path); len(temps) > 0 {
+			finding += fmt.Sprintf(", %d temp files left next to it", len(temps))
+		}
+		pending = append(pending, finding)
+	}
+	return pending, nil
+}
+
+// tempFilesFor lists the temp files writeFileAtomic left next to path
+func tempFilesFor(path string) []string {
+	matches, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*"))
+	return matches
+}
+
+// removeTempFiles deletes temp files writeFileAtomic left next to path
+func removeTempFiles(path string) {
+	for _, match := range tempFilesFor(path) {
+		os.Remove(match)
+	}
+}
+
+// walCheck is the diagnose view of the startup recovery, or of the records still pending when it hasn't run,
+// as under --doctor without --repair
+func walCheck() DiagnosticCheck {
+	walFindings.mu.Lock()
+	defer walFindings.mu.Unlock()
+
+	if !walFindings.checked {
+		pending, err := pendingWAL()
+		switch {
+		case err != nil:
+			return DiagnosticCheck{Name: "journal", Status: "warn", Detail: fmt.Sprintf("failed to read %s: %v", walDir, err)}
+		case len(pending) == 0:
+			return DiagnosticCheck{Name: "journal", Status: "pass", Detail: "no interrupted writes pending"}
+		}
+		return DiagnosticCheck{Name: "journal", Status: "warn", Detail: strings.Join(pending, "; "),
+			Hint: "run --doctor --repair or start the server to replay them, then check the listed files by hand"}
+	}
+	if len(walFindings.findings) == 0 {
+		return DiagnosticCheck{Name: "journal", Status: "pass", Detail: "no interrupted writes found at startup"}
+	}
+	return DiagnosticCheck{Name: "journal", Status: "warn", Detail: strings.Join(walFindings.findings, "; "),
+		Hint: "check the listed files by hand, e.g. with getEntryVersions or resolveConflict"}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// interruptedWrite leaves the vault as a crash in the middle of writeFileAtomic would: a journal record for
+// content that never landed and the temp file it was being written to
+func interruptedWrite(t *testing.T) (record, temp string) {
+	t.Helper()
+	dir := newTestVault(t, map[string]string{
+		"2024-03-15.md":               "# Friday\n",
+		".2024-03-15.md.tmp-1234":     "# Friday\nhalf wri",
+		".themis/wal/20240315T1.json": `{"id":"20240315T1","op":"write","path":"2024-03-15.md","sha256":"00","started":"2024-03-15T21:00:00Z"}`,
+	})
+
+	resetWALFindings(t)
+	return filepath.Join(dir, ".themis", "wal", "20240315T1.json"), filepath.Join(dir, ".2024-03-15.md.tmp-1234")
+}
+
+// resetWALFindings lets recoverWAL run again and puts the previous findings state back when the test ends
+func resetWALFindings(t *testing.T) {
+	t.Helper()
+	walFindings.mu.Lock()
+	old := walFindings.checked
+	walFindings.checked, walFindings.findings = false, nil
+	walFindings.mu.Unlock()
+	t.Cleanup(func() {
+		walFindings.mu.Lock()
+		walFindings.checked, walFindings.findings = old, nil
+		walFindings.mu.Unlock()
+	})
+}
+
+func TestDoctorReportsPendingJournalWithoutRepairing(t *testing.T) {
+	record, temp := interruptedWrite(t)
+
+	var out bytes.Buffer
+	runDoctor(&out, false)
+	report := out.String()
+	if !strings.Contains(report, "write of 2024-03-15.md at 2024-03-15T21:00:00Z was interrupted before it landed") || !strings.Contains(report, "1 temp files left next to it") {
+		t.Errorf("report does not list the pending record:\n%s", report)
+	}
+	if !strings.Contains(report, "--doctor --repair") {
+		t.Errorf("report does not offer the repair:\n%s", report)
+	}
+	for _, path := range []string{record, temp} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("the doctor removed %s: %v", filepath.Base(path), err)
+		}
+	}
+}
+
+func TestDoctorRepair(t *testing.T) {
+	record, temp := interruptedWrite(t)
+
+	var out bytes.Buffer
+	runDoctor(&out, true)
+	if report := out.String(); !strings.Contains(report, "was interrupted before it landed") || strings.Contains(report, "temp files left") {
+		t.Errorf("report = \n%s", report)
+	}
+	for _, path := range []string{record, temp} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s is still there after the repair: %v", filepath.Base(path), err)
+		}
+	}
+}
+
+func TestRecoverWALRefreshesIndex(t *testing.T) {
+	dir := newTestVault(t, map[string]string{"2024-03-15.md": "one two\n"})
+	useTestIndex(t, dir)
+	all := func(time.Time) bool { return true }
+	if _, _, err := vaultIndex.entries(all); err != nil {
+		t.Fatal(err)
+	}
+	lengths.words(filepath.Join(dir, "2024-03-15.md"), fileStamp{}, "one two\n")
+
+	// a write that landed just before the crash, same size and mtime so the stamps can't tell it changed
+	path := filepath.Join(dir, "2024-03-15.md")
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	landed := "onetwo!\n"
+	writeTestFile(t, path, landed)
+	touch(t, path, info.ModTime())
+	sum := sha256.Sum256([]byte(landed))
+	writeTestFile(t, filepath.Join(dir, ".themis", "wal", "20240315T1.json"),
+		`{"id":"20240315T1","op":"write","path":"2024-03-15.md","sha256":"`+hex.EncodeToString(sum[:])+`","started":"2024-03-15T21:00:00Z"}`)
+
+	resetWALFindings(t)
+	recoverWAL()
+
+	lengths.mu.Lock()
+	_, counted := lengths.files[path]
+	lengths.mu.Unlock()
+	if counted {
+		t.Error("the word count read before the crash is still cached")
+	}
+	records, _, err := vaultIndex.entries(all)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].WordCount != 1 {
+		t.Errorf("records after recovery = %+v, want the landed content with 1 word", records)
+	}
+
+	// a restart loads the saved index, which must not bring the stale record back
+	vaultIndex = newEntryIndex(vaultIndex.path)
+	if records, _, err = vaultIndex.entries(all); err != nil || len(records) != 1 || records[0].WordCount != 1 {
+		t.Errorf("records after restart = %+v, %v", records, err)
+	}
+}
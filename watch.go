@@ -0,0 +1,110 @@
+package main
+
+import (
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// THEMIS_WATCH=true keeps the content cache in step with the vault through file system events
+var watchEnabled = os.Getenv("THEMIS_WATCH") == "true"
+
+// vaultWatcher refreshes cached entries when they are written and drops them when removed or renamed
+type vaultWatcher struct {
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+	events  atomic.Int64 // events seen so far, diagnose checks it moves
+}
+
+// activeWatcher is the running watcher, nil unless THEMIS_WATCH is on
+var activeWatcher *vaultWatcher
+
+func newVaultWatcher(root string) (*vaultWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &vaultWatcher{watcher: watcher, done: make(chan struct{})}
+	if err := w.addTree(root); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	go w.run()
+	return w, nil
+}
+
+// close stops the watcher and waits for the event loop to finish
+func (w *vaultWatcher) close() error {
+	err := w.watcher.Close()
+	<-w.done
+	return err
+}
+
+// addTree watches root and every directory below it, fsnotify is not recursive
+func (w *vaultWatcher) addTree(root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			log.Printf("failed to watch %s: %v", path, err)
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != root && strings.HasPrefix(d.Name(), ".") || isExportsDir(path) {
+			return filepath.SkipDir
+		}
+		return w.watcher.Add(path)
+	})
+}
+
+func (w *vaultWatcher) run() {
+	defer close(w.done)
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			w.events.Add(1)
+			w.handle(event)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("vault watcher: %v", err)
+		}
+	}
+}
+
+func (w *vaultWatcher) handle(event fsnotify.Event) {
+	if event.Has(fsnotify.Create) {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			if err := w.addTree(event.Name); err != nil {
+				log.Printf("failed to watch %s: %v", event.Name, err)
+			}
+			return
+		}
+	}
+
+	name := filepath.Base(event.Name)
+	if !strings.HasSuffix(name, ".md") && !strings.HasSuffix(name, encryptedSuffix) || isTempFile(name) {
+		return
+	}
+
+	if activeWebhooks != nil {
+		activeWebhooks.observe(event)
+	}
+
+	cache.invalidate(event.Name)
+	if event.Has(fsnotify.Create) || event.Has(fsnotify.Write) {
+		// read it back so the next tool call is served warm, a failure just leaves it uncached
+		cache.read(event.Name)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// eventually polls cond until it holds or a couple of seconds pass
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting until %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func cached(path string) bool {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	_, ok := cache.items[path]
+	return ok
+}
+
+func TestVaultWatcherKeepsCacheInStep(t *testing.T) {
+	dir := newTestVault(t, map[string]string{"2024-03-14.md": "# Thursday\n"})
+	oldWebhooks := activeWebhooks
+	activeWebhooks = nil
+	defer func() { activeWebhooks = oldWebhooks }()
+
+	watcher, err := newVaultWatcher(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.close()
+
+	// a written entry is read back into the cache
+	entry := filepath.Join(dir, "2024-03-15.md")
+	writeTestFile(t, entry, "# Friday\n")
+	eventually(t, "the new entry is cached", func() bool { return cached(entry) })
+
+	// folders created after startup are watched too
+	nested := filepath.Join(dir, "2024", "04")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "the new folder is watched", func() bool { return slices.Contains(watcher.watcher.WatchList(), nested) })
+	later := filepath.Join(nested, "2024-04-01.md")
+	writeTestFile(t, later, "# April\n")
+	eventually(t, "the entry in a new folder is cached", func() bool { return cached(later) })
+
+	if err := os.Remove(entry); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "the removed entry is dropped", func() bool { return !cached(entry) })
+
+	// temp files and non-entries are never read
+	temp := filepath.Join(dir, ".2024-03-14.md.tmp-1")
+	notes := filepath.Join(dir, "notes.txt")
+	writeTestFile(t, temp, "partial")
+	writeTestFile(t, notes, "notes")
+	time.Sleep(100 * time.Millisecond)
+	if cached(temp) || cached(notes) {
+		t.Error("the watcher cached a temp file or a non-entry")
+	}
+	if watcher.events.Load() == 0 {
+		t.Error("no events counted")
+	}
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	defaultWebhookConcurrency = 2
+	defaultWebhookRetries     = 3
+	webhookQueueSize          = 64
+	webhookTimeout            = 10 * time.Second
+	webhookBackoff            = time.Second
+
+	// webhookDebounce gathers the events of one entry file into a single delivery, a save is often a create,
+	// several writes and a chmod
+	webhookDebounce = 250 * time.Millisecond
+)
+
+var webhookEventNames = []string{"create", "modify", "delete"}
+
+// THEMIS_WEBHOOK_URL receives a POST and THEMIS_WEBHOOK_COMMAND is run through sh with the payload on stdin,
+// for every entry event the watcher sees. either or both may be set.
+var (
+	webhookURL     = strings.TrimSpace(os.Getenv("THEMIS_WEBHOOK_URL"))
+	webhookCommand = strings.TrimSpace(os.Getenv("THEMIS_WEBHOOK_COMMAND"))
+)
+
+// THEMIS_WEBHOOK_CONCURRENCY caps deliveries in flight, THEMIS_WEBHOOK_RETRIES is how often a failed one is retried
+var (
+	webhookConcurrency = getEnvInt("THEMIS_WEBHOOK_CONCURRENCY", defaultWebhookConcurrency)
+	webhookRetries     = getEnvInt("THEMIS_WEBHOOK_RETRIES", defaultWebhookRetries)
+)
+
+// activeWebhooks is the running dispatcher, nil unless a webhook is configured and the watcher is on
+var activeWebhooks *webhookDispatcher
+
+type WebhookPayload struct {
+	Date  string `json:"date"`
+	Path  string `json:"path"`
+	Event string `json:"event"`
+}
+
+type WebhookState struct {
+	Delivered int64 `json:"delivered" jsonschema:"Webhook deliveries that succeeded since startup"`
+	Failed    int64 `json:"failed" jsonschema:"Webhook deliveries that failed after all retries"`
+	Dropped   int64 `json:"dropped" jsonschema:"Events dropped because the delivery queue was full"`
+}
+
+// webhookDispatcher delivers entry events from a bounded queue so a slow endpoint never blocks the watcher,
+// events arriving while the queue is full are dropped
+type webhookDispatcher struct {
+	events    map[string]bool
+	entries   *entryEvents
+	queue     chan WebhookPayload
+	wg        sync.WaitGroup
+	ctx       context.Context
+	cancel    context.CancelFunc
+	delivered atomic.Int64
+	failed    atomic.Int64
+	dropped   atomic.Int64
+}
+
+func newWebhookDispatcher(events map[string]bool) *webhookDispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &webhookDispatcher{
+		events: events,
+		queue:  make(chan WebhookPayload, webhookQueueSize),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	d.entries = newEntryEvents(webhookDebounce, d.notify)
+	for range max(webhookConcurrency, 1) {
+		d.wg.Add(1)
+		go d.run()
+	}
+	return d
+}
+
+// close stops accepting events, gives queued ones until the timeout to go out and waits for the workers
+func (d *webhookDispatcher) close() {
+	d.entries.stop()
+	close(d.queue)
+	timer := time.AfterFunc(webhookTimeout, d.cancel)
+	d.wg.Wait()
+	timer.Stop()
+	d.cancel()
+}
+
+// observe passes a watcher event on to be coalesced, the delivery is queued once the file has been quiet
+func (d *webhookDispatcher) observe(event fsnotify.Event) {
+	d.entries.observe(event)
+}
+
+// notify queues an event for delivery without ever blocking
+func (d *webhookDispatcher) notify(payload WebhookPayload) {
+	if !d.events[payload.Event] {
+		return
+	}
+	select {
+	case d.queue <- payload:
+	default:
+		d.dropped.Add(1)
+		promWebhookDeliveries.WithLabelValues("dropped").Inc()
+		log.Printf("webhook queue full, dropped %s event for %s", payload.Event, payload.Date)
+	}
+}
+
+func (d *webhookDispatcher) state() WebhookState {
+	return WebhookState{Delivered: d.delivered.Load(), Failed: d.failed.Load(), Dropped: d.dropped.Load()}
+}
+
+func (d *webhookDispatcher) run() {
+	defer d.wg.Done()
+	for payload := range d.queue {
+		if err := d.deliver(payload); err != nil {
+			d.failed.Add(1)
+			promWebhookDeliveries.WithLabelValues("failed").Inc()
+			log.Printf("webhook for %s %s failed: %v", payload.Event, payload.Date, err)
+			continue
+		}
+		d.delivered.Add(1)
+		promWebhookDeliveries.WithLabelValues("delivered").Inc()
+	}
+}
+
+// deliver sends payload to every configured target, retrying with a growing backoff
+func (d *webhookDispatcher) deliver(payload WebhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= max(webhookRetries, 0); attempt++ {
+		if attempt > 0 {
+			select {
+			case <-d.ctx.Done():
+				return lastErr
+			case <-time.After(webhookBackoff * time.Duration(1<<(attempt-1))):
+			}
+		}
+
+		if lastErr = d.send(body); lastErr == nil {
+			return nil
+		}
+	}
+	return lastErr
+}
+
+func (d *webhookDispatcher) send(body []byte) error {
+	ctx, cancel := context.WithTimeout(d.ctx, webhookTimeout)
+	defer cancel()
+
+	if webhookURL != "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("%s returned %s", webhookURL, resp.Status)
+		}
+	}
+
+	if webhookCommand != "" {
+		cmd := exec.CommandContext(ctx, "sh", "-c", webhookCommand)
+		cmd.Stdin = bytes.NewReader(body)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+		}
+	}
+	return nil
+}
+
+// helpers
+
+// parseWebhookEvents reads the --webhook-events list, empty means every event
+func parseWebhookEvents(raw string) (map[string]bool, error) {
+	events := map[string]bool{}
+	if strings.TrimSpace(raw) == "" {
+		for _, name := range webhookEventNames {
+			events[name] = true
+		}
+		return events, nil
+	}
+
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		found := false
+		for _, known := range webhookEventNames {
+			if name == known {
+				events[name], found = true, true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("invalid webhook event %q, expected %s", name, strings.Join(webhookEventNames, ", "))
+		}
+	}
+	return events, nil
+}
+
+// entryEvents coalesces watcher events per entry file. whether a burst was a create, modify or delete is
+// decided when it ends, from whether the file existed before it and whether it exists now: the server's own
+// atomic rename over an entry is reported as a create of that path, so the event op alone can't tell.
+type entryEvents struct {
+	mu      sync.Mutex
+	window  time.Duration
+	emit    func(WebhookPayload)
+	known   map[string]bool // entry files that existed when their last burst ended
+	pending map[string]*pendingEntryEvent
+	flushes sync.WaitGroup // one per pending burst, so stop can wait for flushes already under way
+	stopped bool
+}
+
+type pendingEntryEvent struct {
+	date    string
+	existed bool
+	timer   *time.Timer
+}
+
+// newEntryEvents seeds the known files from the tree, so edits to entries that existed at startup are modifies
+func newEntryEvents(window time.Duration, emit func(WebhookPayload)) *entryEvents {
+	e := &entryEvents{window: window, emit: emit, known: map[string]bool{}, pending: map[string]*pendingEntryEvent{}}
+	for path := range entryStamps() {
+		e.known[path] = true
+	}
+	return e
+}
+
+func (e *entryEvents) observe(event fsnotify.Event) {
+	date, ok := entryEventDate(event.Name)
+	if !ok || !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) && !event.Has(fsnotify.Remove) && !event.Has(fsnotify.Rename) {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stopped {
+		return
+	}
+	if p := e.pending[event.Name]; p != nil {
+		p.timer.Reset(e.window)
+		return
+	}
+	p := &pendingEntryEvent{date: date, existed: e.known[event.Name]}
+	e.flushes.Add(1)
+	p.timer = time.AfterFunc(e.window, func() { e.flush(event.Name) })
+	e.pending[event.Name] = p
+}
+
+// flush ends the burst for path, a file created and removed again within it fires nothing
+func (e *entryEvents) flush(path string) {
+	e.mu.Lock()
+	p := e.pending[path]
+	if p == nil {
+		e.mu.Unlock()
+		return
+	}
+	delete(e.pending, path)
+	defer e.flushes.Done()
+	_, err := os.Stat(path)
+	exists := err == nil
+	if exists {
+		e.known[path] = true
+	} else {
+		delete(e.known, path)
+	}
+	e.mu.Unlock()
+
+	payload := WebhookPayload{Date: p.date, Path: path}
+	switch {
+	case p.existed && exists:
+		payload.Event = "modify"
+	case exists:
+		payload.Event = "create"
+	case p.existed:
+		payload.Event = "delete"
+	default:
+		return
+	}
+	e.emit(payload)
+}
+
+// stop flushes every pending burst right away and waits until they are handed to emit, later events are ignored
+func (e *entryEvents) stop() {
+	e.mu.Lock()
+	e.stopped = true
+	var paths []string
+	for path, p := range e.pending {
+		if p.timer.Stop() {
+			paths = append(paths, path)
+		}
+	}
+	e.mu.Unlock()
+
+	for _, path := range paths {
+		e.flush(path)
+	}
+	e.flushes.Wait()
+}
+
+// entryEventDate returns the entry date of a watched path, ok is false for files that are not entries under
+// the entry root
+func entryEventDate(path string) (string, bool) {
+	dateStr := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".age"), ".md")
+	if _, err := time.Parse("2006-01-02", dateStr); err != nil {
+		return "", false
+	}
+	if rel, err := filepath.Rel(entryRoot, path); err != nil || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+	return dateStr, true
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestEntryEventsCoalesce(t *testing.T) {
+	dir := newTestVault(t, map[string]string{"2024-03-15.md": "# Friday\n"})
+	existing := filepath.Join(dir, "2024-03-15.md")
+	created := filepath.Join(dir, "2024-03-16.md")
+	fleeting := filepath.Join(dir, "2024-03-17.md")
+
+	var mu sync.Mutex
+	var got []WebhookPayload
+	events := newEntryEvents(time.Hour, func(payload WebhookPayload) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, payload)
+	})
+
+	// an atomic rename over an existing entry is reported as a create, followed by a chmod
+	events.observe(fsnotify.Event{Name: existing, Op: fsnotify.Create})
+	events.observe(fsnotify.Event{Name: existing, Op: fsnotify.Chmod})
+	events.observe(fsnotify.Event{Name: existing, Op: fsnotify.Write})
+
+	writeTestFile(t, created, "# Saturday\n")
+	events.observe(fsnotify.Event{Name: created, Op: fsnotify.Create})
+	events.observe(fsnotify.Event{Name: created, Op: fsnotify.Write})
+	events.observe(fsnotify.Event{Name: created, Op: fsnotify.Write})
+
+	// created and removed again before the burst ends
+	events.observe(fsnotify.Event{Name: fleeting, Op: fsnotify.Create})
+	events.observe(fsnotify.Event{Name: fleeting, Op: fsnotify.Remove})
+
+	events.observe(fsnotify.Event{Name: filepath.Join(dir, "notes.md"), Op: fsnotify.Write})
+	events.stop()
+
+	byPath := map[string]string{}
+	for _, payload := range got {
+		if _, dup := byPath[payload.Path]; dup {
+			t.Errorf("more than one event for %s: %+v", payload.Path, got)
+		}
+		byPath[payload.Path] = payload.Event
+	}
+	want := map[string]string{existing: "modify", created: "create"}
+	if !reflect.DeepEqual(byPath, want) {
+		t.Errorf("events = %v, want %v", byPath, want)
+	}
+
+	// the next burst for the new entry knows it exists, and a removal is a delete
+	got = nil
+	events = newEntryEvents(time.Hour, func(payload WebhookPayload) { got = append(got, payload) })
+	if err := os.Remove(existing); err != nil {
+		t.Fatal(err)
+	}
+	events.observe(fsnotify.Event{Name: existing, Op: fsnotify.Remove})
+	events.observe(fsnotify.Event{Name: created, Op: fsnotify.Write})
+	events.stop()
+	byPath = map[string]string{}
+	for _, payload := range got {
+		byPath[payload.Path] = payload.Event
+	}
+	want = map[string]string{existing: "delete", created: "modify"}
+	if !reflect.DeepEqual(byPath, want) {
+		t.Errorf("events = %v, want %v", byPath, want)
+	}
+}
+
+func TestWebhookAtomicSaveFiresOneModify(t *testing.T) {
+	dir := newTestVault(t, map[string]string{"2024-03-15.md": "# Friday\n"})
+
+	var mu sync.Mutex
+	var got []WebhookPayload
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload WebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		got = append(got, payload)
+		mu.Unlock()
+	}))
+	defer endpoint.Close()
+
+	oldURL, oldWebhooks := webhookURL, activeWebhooks
+	defer func() { webhookURL, activeWebhooks = oldURL, oldWebhooks }()
+	webhookURL = endpoint.URL
+	events, err := parseWebhookEvents("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	activeWebhooks = newWebhookDispatcher(events)
+	watcher, err := newVaultWatcher(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "2024-03-15.md")
+	if err := writeFileAtomic(path, []byte("# Friday\nwent for a run\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(path, []byte("# Friday\nwent for a run\ncalled mum\n")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * webhookDebounce)
+	watcher.close()
+	activeWebhooks.close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []WebhookPayload{{Date: "2024-03-15", Path: path, Event: "modify"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("deliveries = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var tagNamePattern = regexp.MustCompile(`^[\p{L}\p{N}_/-]+$`)
+
+// writeFileAtomic replaces path by writing a temp file in the same directory and renaming it over the original.
+// the write is journaled so a crash part way through is noticed on the next start.
+func writeFileAtomic(path string, data []byte) error {
+	return journal("write", path, data, func() error {
+		return replaceFile(path, data)
+	})
+}
+
+func replaceFile(path string, data []byte) error {
+	perm := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	cache.invalidate(path)
+	return nil
+}
+
+// addFrontmatterTag appends tag to the frontmatter tags list, creating the list or the frontmatter itself when absent.
+// existing lines are edited in place so the rest of the frontmatter keeps its formatting.
+func addFrontmatterTag(content, tag string) (string, error) {
+	raw, body, ok := splitFrontmatter(content)
+	if !ok {
+		return "---\ntags:\n  - " + tag + "\n---\n" + content, nil
+	}
+
+	lines := strings.Split(strings.TrimSuffix(raw, "\n"), "\n")
+	idx := slices.IndexFunc(lines, func(line string) bool {
+		return strings.HasPrefix(line, "tags:")
+	})
+
+	switch {
+	case idx < 0:
+		lines = append(lines, "tags:", "  - "+tag)
+
+	default:
+		value := strings.TrimSpace(strings.TrimPrefix(lines[idx], "tags:"))
+		switch {
+		case strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]"):
+			items := strings.TrimSpace(value[1 : len(value)-1])
+			if items == "" {
+				lines[idx] = "tags: [" + tag + "]"
+			} else {
+				lines[idx] = "tags: [" + items + ", " + tag + "]"
+			}
+
+		case value == "":
+			// block list, insert after the last item
+			last := idx
+			indent := "  "
+			for i := idx + 1; i < len(lines); i++ {
+				trimmed := strings.TrimLeft(lines[i], " ")
+				if !strings.HasPrefix(trimmed, "- ") && trimmed != "-" {
+					break
+				}
+				indent = lines[i][:len(lines[i])-len(trimmed)]
+				last = i
+			}
+			lines = slices.Insert(lines, last+1, indent+"- "+tag)
+
+		default:
+			existing := extractTags(map[string]any{"tags": strings.Trim(value, `"'`)}, "")
+			lines[idx] = "tags: [" + strings.Join(append(existing, tag), ", ") + "]"
+		}
+	}
+
+	updated := "---\n" + strings.Join(lines, "\n") + "\n---\n" + body
+
+	fm, _, err := parseFrontmatter(updated)
+	if err != nil {
+		return "", fmt.Errorf("frontmatter would become invalid: %w", err)
+	}
+	if !slices.Contains(extractTags(fm, ""), tag) {
+		return "", fmt.Errorf("could not add tag to the existing tags field")
+	}
+
+	return updated, nil
+}